@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"example.com/binance-pivot-monitor/internal/backtest"
+	"example.com/binance-pivot-monitor/internal/binance"
+	"example.com/binance-pivot-monitor/internal/pivot"
+)
+
+// runBacktest rebuilds historical pivots from Binance klines, replays 1m
+// closes against them and prints a JSON report of forward returns.
+//
+//	server backtest -symbols BTCUSDT,ETHUSDT -from 2025-01-01 -to 2025-02-01 -levels R3,S3
+func runBacktest(args []string) {
+	fs := flag.NewFlagSet("backtest", flag.ExitOnError)
+	symbols := fs.String("symbols", "BTCUSDT", "")
+	from := fs.String("from", "", "")
+	to := fs.String("to", "", "")
+	periods := fs.String("periods", "1d,1w", "")
+	method := fs.String("method", "camarilla", "")
+	levels := fs.String("levels", "", "")
+	horizons := fs.String("horizons", "15m,1h,4h", "")
+	cooldownDur := fs.Duration("cooldown", 30*time.Minute, "")
+	restBase := fs.String("binance-rest", "https://fapi.binance.com", "")
+	pivotTZ := fs.String("pivot-tz", "Asia/Shanghai", "")
+	pivotSessionClose := fs.String("pivot-session-close", "08:00", "")
+	pivotWeekStart := fs.String("pivot-week-start", "monday", "")
+	trades := fs.Bool("trades", false, "")
+	out := fs.String("out", "", "")
+	_ = fs.Parse(args)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cfg := backtest.Config{Cooldown: *cooldownDur, Trades: *trades}
+	for _, s := range strings.Split(*symbols, ",") {
+		if s = strings.ToUpper(strings.TrimSpace(s)); s != "" {
+			cfg.Symbols = append(cfg.Symbols, s)
+		}
+	}
+
+	var err error
+	if cfg.From, err = parseReplayTime(*from); err != nil {
+		log.Fatalf("invalid -from: %v", err)
+	}
+	if cfg.To, err = parseReplayTime(*to); err != nil {
+		log.Fatalf("invalid -to: %v", err)
+	}
+	if cfg.To.IsZero() {
+		cfg.To = time.Now().UTC()
+	}
+	if cfg.From.IsZero() {
+		cfg.From = cfg.To.Add(-30 * 24 * time.Hour)
+	}
+	if cfg.Periods, err = pivot.ParsePeriods(*periods); err != nil {
+		log.Fatalf("invalid -periods: %v", err)
+	}
+	m, ok := pivot.ParseMethod(*method)
+	if !ok {
+		log.Fatalf("invalid -method: %q", *method)
+	}
+	cfg.Method = m
+	if cfg.Levels, err = backtest.ParseLevels(*levels); err != nil {
+		log.Fatalf("invalid -levels: %v", err)
+	}
+	if cfg.Horizons, err = backtest.ParseHorizons(*horizons); err != nil {
+		log.Fatalf("invalid -horizons: %v", err)
+	}
+	if cfg.Session, err = pivot.ParseSession(*pivotTZ, *pivotSessionClose, *pivotWeekStart); err != nil {
+		log.Fatalf("invalid pivot session: %v", err)
+	}
+
+	log.Printf("backtest: symbols=%v from=%s to=%s periods=%v method=%s",
+		cfg.Symbols, cfg.From.Format(time.RFC3339), cfg.To.Format(time.RFC3339), cfg.Periods, cfg.Method)
+
+	start := time.Now()
+	rep, err := backtest.Run(ctx, binance.NewRESTClient(*restBase), cfg)
+	if err != nil {
+		log.Fatalf("backtest: %v", err)
+	}
+	for sym, e := range rep.Errors {
+		log.Printf("backtest %s failed: %s", sym, e)
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatalf("backtest output: %v", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(rep); err != nil {
+		log.Fatalf("backtest output: %v", err)
+	}
+
+	log.Printf("backtest done: elapsed=%s", time.Since(start).Round(time.Millisecond))
+}
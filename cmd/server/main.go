@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -10,31 +11,76 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"example.com/binance-pivot-monitor/internal/binance"
+	"example.com/binance-pivot-monitor/internal/confluence"
 	"example.com/binance-pivot-monitor/internal/httpapi"
 	"example.com/binance-pivot-monitor/internal/kline"
 	"example.com/binance-pivot-monitor/internal/monitor"
+	"example.com/binance-pivot-monitor/internal/notify"
 	"example.com/binance-pivot-monitor/internal/pattern"
 	"example.com/binance-pivot-monitor/internal/pivot"
 	"example.com/binance-pivot-monitor/internal/ranking"
+	"example.com/binance-pivot-monitor/internal/recorder"
 	signalpkg "example.com/binance-pivot-monitor/internal/signal"
 	"example.com/binance-pivot-monitor/internal/sse"
+	"example.com/binance-pivot-monitor/internal/subscription"
+	"example.com/binance-pivot-monitor/internal/telegram"
 	"example.com/binance-pivot-monitor/internal/ticker"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		runReplay(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "backtest" {
+		runBacktest(os.Args[2:])
+		return
+	}
+
 	addr := flag.String("addr", ":8080", "")
 	dataDir := flag.String("data-dir", "data", "")
 	corsOrigins := flag.String("cors-origins", "*", "")
 	restBase := flag.String("binance-rest", "https://fapi.binance.com", "")
+	restMaxWeight := flag.Int("binance-rest-weight", binance.DefaultMaxWeight, "")
+	wsBase := flag.String("binance-ws", binance.FStreamWSBaseURL, "")
+	wsMaxFailures := flag.Int("binance-ws-failover", binance.DefaultWSMaxFailures, "")
 	refreshWorkers := flag.Int("refresh-workers", 16, "")
+	listings := flag.Bool("listings", true, "")
+	pivotArchiveDays := flag.Int("pivot-archive-days", 365, "")
+	pivotMethods := flag.String("pivot-methods", "camarilla", "")
+	pivotPeriods := flag.String("pivot-periods", "1d,1w", "")
+	pivotTZ := flag.String("pivot-tz", "Asia/Shanghai", "")
+	pivotSessionClose := flag.String("pivot-session-close", "08:00", "")
+	pivotWeekStart := flag.String("pivot-week-start", "monday", "")
 	monitorHeartbeat := flag.Duration("monitor-heartbeat", 0, "")
+	confirmHold := flag.Duration("confirm-hold", 0, "")
+	confirmKline := flag.Duration("confirm-kline", 0, "")
+	confirmPenetration := flag.Float64("confirm-penetration", 0, "")
+	rejectApproach := flag.Float64("reject-approach", 0, "")
+	rejectTouch := flag.Float64("reject-touch", 0, "")
 	historyMax := flag.Int("history-max", 20000, "")
 	historyFile := flag.String("history-file", "signals/history.jsonl", "")
+	outcomeFile := flag.String("outcome-file", "signals/outcomes.jsonl", "")
+	combinedFile := flag.String("combined-file", "signals/combined.jsonl", "")
 	tickerBatchInterval := flag.Duration("ticker-batch-interval", 500*time.Millisecond, "")
+	webhookConfig := flag.String("webhook-config", "", "")
+	sseReplay := flag.Int("sse-replay", sse.DefaultReplaySize, "")
+	ssePolicy := flag.String("sse-policy", "drop-newest", "")
+	sseMaxDrops := flag.Int("sse-max-drops", sse.DefaultMaxDrops, "")
+	profilesFile := flag.String("profiles-file", "subscriptions/profiles.json", "")
+	confluenceThreshold := flag.Float64("confluence-threshold", confluence.DefaultThreshold, "")
+	confluenceWindow := flag.Duration("confluence-window", confluence.DefaultWindow, "")
+	confluenceWeights := flag.String("confluence-weights", "", "")
+	telegramToken := flag.String("telegram-token", os.Getenv("TELEGRAM_BOT_TOKEN"), "")
+	telegramAPI := flag.String("telegram-api", telegram.DefaultBaseURL, "")
+	telegramChats := flag.String("telegram-chats", "", "")
+	record := flag.Bool("record", false, "")
+	recordDir := flag.String("record-dir", "recordings", "")
 	flag.Parse()
 
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
@@ -44,6 +90,16 @@ func main() {
 	patternEnabled := getEnvBool("PATTERN_ENABLED", true)
 	klineCount := getEnvInt("KLINE_COUNT", 12)
 	klineInterval := getEnvDurationOrMinutes("KLINE_INTERVAL", 15*time.Minute)
+	// KLINE_INTERVALS 指定多个周期（如 5m,15m,1h,4h），第一个为主周期
+	klineIntervals := []time.Duration{klineInterval}
+	if v := os.Getenv("KLINE_INTERVALS"); v != "" {
+		ivs, err := kline.ParseIntervals(v)
+		if err != nil || len(ivs) == 0 {
+			log.Fatalf("invalid KLINE_INTERVALS: %v", err)
+		}
+		klineIntervals = ivs
+		klineInterval = ivs[0]
+	}
 	patternMinConfidence := getEnvInt("PATTERN_MIN_CONFIDENCE", 60) // Requirement 8: default 60
 	patternHistoryFile := os.Getenv("PATTERN_HISTORY_FILE")
 	if patternHistoryFile == "" {
@@ -51,35 +107,87 @@ func main() {
 	}
 	patternCryptoMode := getEnvBool("PATTERN_CRYPTO_MODE", true)
 	patternHistoryMax := getEnvInt("PATTERN_HISTORY_MAX", 1000) // Requirement 6.3: default 1000
+	patternStatsFile := os.Getenv("PATTERN_STATS_FILE")
+	if patternStatsFile == "" {
+		patternStatsFile = "patterns/stats.json"
+	}
+	patternStatsHorizon := getEnvInt("PATTERN_STATS_HORIZON", 3)
+	patternStatsPrior := getEnvInt("PATTERN_STATS_PRIOR", 20)
+	patternStatsMinSamples := getEnvInt("PATTERN_STATS_MIN_SAMPLES", 30)
+	klineBackfill := getEnvBool("KLINE_BACKFILL", true)
+	klineBackfillWorkers := getEnvInt("KLINE_BACKFILL_WORKERS", kline.DefaultBackfillWorkers)
+	klineBackfillRate := getEnvInt("KLINE_BACKFILL_RATE", kline.DefaultBackfillRate)
+	klinePersist := getEnvBool("KLINE_PERSIST", true)
 
 	// Log configuration
 	log.Printf("config: addr=%s data-dir=%s", *addr, *dataDir)
-	log.Printf("config: pattern_enabled=%v kline_count=%d kline_interval=%v", patternEnabled, klineCount, klineInterval)
+
+	// 行情 WS 端点：两个监控共用，连续拨号失败后轮换到下一个
+	wsEndpoints := binance.ParseWSEndpoints(*wsBase)
+	wsEndpoints.MaxFailures = *wsMaxFailures
+	log.Printf("config: binance_ws=%v binance_ws_failover=%d", wsEndpoints.Status().Endpoints, *wsMaxFailures)
+	log.Printf("config: pattern_enabled=%v kline_count=%d kline_intervals=%v", patternEnabled, klineCount, klineIntervals)
 	log.Printf("config: pattern_min_confidence=%d pattern_crypto_mode=%v pattern_history_max=%d", patternMinConfidence, patternCryptoMode, patternHistoryMax)
 	log.Printf("config: pattern_history_file=%s", patternHistoryFile)
+	log.Printf("config: kline_backfill=%v kline_backfill_workers=%d kline_backfill_rate=%d", klineBackfill, klineBackfillWorkers, klineBackfillRate)
+	log.Printf("config: kline_persist=%v", klinePersist)
+	log.Printf("config: pattern_stats_file=%s pattern_stats_horizon=%d pattern_stats_prior=%d pattern_stats_min_samples=%d",
+		patternStatsFile, patternStatsHorizon, patternStatsPrior, patternStatsMinSamples)
+
+	methods, err := pivot.ParseMethods(*pivotMethods)
+	if err != nil {
+		log.Fatalf("invalid -pivot-methods: %v", err)
+	}
+	log.Printf("config: pivot_methods=%v", methods)
+
+	periods, err := pivot.ParsePeriods(*pivotPeriods)
+	if err != nil {
+		log.Fatalf("invalid -pivot-periods: %v", err)
+	}
+	log.Printf("config: pivot_periods=%v", periods)
+
+	session, err := pivot.ParseSession(*pivotTZ, *pivotSessionClose, *pivotWeekStart)
+	if err != nil {
+		log.Fatalf("invalid pivot session: %v", err)
+	}
+	log.Printf("config: pivot_session=%s", session)
 
-	store := pivot.NewStore()
+	store := pivot.NewStore(periods...)
 	rest := binance.NewRESTClient(*restBase)
+	rest.Limiter = binance.NewLimiter(*restMaxWeight)
 	refresher := pivot.NewRefresher(*dataDir, store, rest)
 	refresher.Workers = *refreshWorkers
+	refresher.Methods = methods
+	refresher.Session = session
+	refresher.Archive.MaxAge = time.Duration(*pivotArchiveDays) * 24 * time.Hour
 	refresher.LoadFromDisk()
 
 	go func() {
 		ctxInit, cancel := context.WithTimeout(ctx, 15*time.Minute)
 		defer cancel()
 
-		if snap, _ := store.Snapshot(pivot.PeriodDaily); snap == nil {
-			_ = refresher.Refresh(ctxInit, pivot.PeriodDaily)
-		}
-		if snap, _ := store.Snapshot(pivot.PeriodWeekly); snap == nil {
-			_ = refresher.Refresh(ctxInit, pivot.PeriodWeekly)
+		for _, p := range store.Periods() {
+			if snap, _ := store.Snapshot(p); snap == nil {
+				_ = refresher.Refresh(ctxInit, p)
+			}
 		}
 	}()
 
 	refresher.StartScheduler(ctx)
 
+	// 盘中新上线的合约：按需计算枢轴点并合并进当前快照
+	var listingWatcher *pivot.ListingWatcher
+	if *listings {
+		listingWatcher = pivot.NewListingWatcher(refresher)
+		go listingWatcher.Run(ctx)
+	}
+
 	signalBroker := sse.NewBroker[signalpkg.Signal]()
-	history := signalpkg.NewHistory(*historyMax)
+	periodKeys := make([]string, 0, len(periods))
+	for _, p := range periods {
+		periodKeys = append(periodKeys, string(p))
+	}
+	history := signalpkg.NewHistoryForPeriods(*historyMax, periodKeys)
 	if *historyFile != "" {
 		path := *historyFile
 		if !filepath.IsAbs(path) {
@@ -89,17 +197,84 @@ func main() {
 			log.Fatalf("history persistence init error: %v", err)
 		}
 	}
+	// persistWG tracks goroutines that write to disk on shutdown
+	var persistWG sync.WaitGroup
+
+	outcomes := signalpkg.NewOutcomeTracker(*historyMax)
+	if *outcomeFile != "" {
+		path := *outcomeFile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(*dataDir, path)
+		}
+		if err := outcomes.EnablePersistence(path); err != nil {
+			log.Fatalf("outcome persistence init error: %v", err)
+		}
+		log.Printf("config: outcome_file=%s horizons=%v open=%d", path, outcomes.Horizons(), outcomes.Open())
+
+		// 定期及退出时保存未完成结果的最大有利/不利偏移
+		persistWG.Add(1)
+		go func() {
+			defer persistWG.Done()
+			ticker := time.NewTicker(time.Minute)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					if err := outcomes.Flush(); err != nil {
+						log.Printf("outcome final flush error: %v", err)
+					}
+					return
+				case <-ticker.C:
+					if err := outcomes.Flush(); err != nil {
+						log.Printf("outcome flush error: %v", err)
+					}
+				}
+			}
+		}()
+	}
 	cooldown := signalpkg.NewCooldown(30 * time.Minute)
 
 	// Initialize pattern recognition components (if enabled)
 	var klineStore *kline.Store
+	var klineStores *kline.MultiStore
 	var patternDetector *pattern.Detector
 	var patternHistory *pattern.History
+	var patternStats *pattern.LiveStats
 	var patternBroker *sse.Broker[pattern.Signal]
 	var signalCombiner *signalpkg.Combiner
+	var combinedBroker *sse.Broker[signalpkg.CombinedSignal]
+	var combinedHistory *signalpkg.CombinedHistory
 
 	if patternEnabled {
-		klineStore = kline.NewStore(klineInterval, klineCount)
+		klineStores = kline.NewMultiStore(klineIntervals, klineCount)
+		klineStore = klineStores.Primary()
+		if klinePersist {
+			// 重启后恢复 K 线，预填补齐更早的部分和停机期间缺失的部分
+			klineStores.SetDataDir(*dataDir)
+			if err := klineStores.Load(); err != nil {
+				log.Printf("kline store load warning: %v", err)
+			}
+			persistWG.Add(1)
+			go func() {
+				defer persistWG.Done()
+				ticker := time.NewTicker(5 * time.Minute)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-ctx.Done():
+						// Final persist on shutdown
+						if err := klineStores.Persist(); err != nil {
+							log.Printf("kline store final persist error: %v", err)
+						}
+						return
+					case <-ticker.C:
+						if err := klineStores.Persist(); err != nil {
+							log.Printf("kline store persist error: %v", err)
+						}
+					}
+				}
+			}()
+		}
 		patternDetector = pattern.NewDetector(pattern.DetectorConfig{
 			MinConfidence:      patternMinConfidence,
 			HighEfficiencyOnly: false,
@@ -111,6 +286,24 @@ func main() {
 
 		// Initialize pattern history
 		var err error
+
+		// 枢轴点 + 形态组合信号：持久化并推送
+		combinedPath := *combinedFile
+		if combinedPath != "" && !filepath.IsAbs(combinedPath) {
+			combinedPath = filepath.Join(*dataDir, combinedPath)
+		}
+		combinedHistory, err = signalpkg.NewCombinedHistory(combinedPath, signalpkg.DefaultCombinedHistoryMax)
+		if err != nil {
+			log.Printf("combined history init warning: %v (continuing without persistence)", err)
+			combinedHistory, _ = signalpkg.NewCombinedHistory("", signalpkg.DefaultCombinedHistoryMax)
+		}
+		combinedBroker = sse.NewBroker[signalpkg.CombinedSignal]()
+		signalCombiner.SetOnCombined(func(cs signalpkg.CombinedSignal) {
+			if err := combinedHistory.Add(cs); err != nil {
+				log.Printf("combined history add failed: %v", err)
+			}
+			combinedBroker.Publish(cs)
+		})
 		histPath := patternHistoryFile
 		if !filepath.IsAbs(histPath) {
 			histPath = filepath.Join(*dataDir, histPath)
@@ -121,7 +314,55 @@ func main() {
 			patternHistory, _ = pattern.NewHistory("", 10000)
 		}
 
-		log.Printf("pattern recognition enabled: kline_count=%d interval=%v", klineCount, klineInterval)
+		statsPath := patternStatsFile
+		if !filepath.IsAbs(statsPath) {
+			statsPath = filepath.Join(*dataDir, statsPath)
+		}
+		patternStats = pattern.NewLiveStats(pattern.LiveStatsConfig{
+			Horizon:     patternStatsHorizon,
+			PriorWeight: float64(patternStatsPrior),
+			MinSamples:  patternStatsMinSamples,
+			FilePath:    statsPath,
+			Timeframe:   klineStore.Timeframe(),
+		})
+		if patternStatsHorizon >= klineCount {
+			log.Printf("WARN: PATTERN_STATS_HORIZON=%d is not below KLINE_COUNT=%d, no pattern outcomes can be resolved", patternStatsHorizon, klineCount)
+		}
+		go patternStats.Run(ctx, patternHistory, klineStore, klineInterval)
+
+		log.Printf("pattern recognition enabled: kline_count=%d timeframes=%v", klineCount, klineStores.Timeframes())
+
+		// 启动时用 REST K 线预填历史，避免重启后要等 KLINE_COUNT 个周期才能识别形态
+		if klineBackfill {
+			go func() {
+				ctxSymbols, cancel := context.WithTimeout(ctx, 20*time.Second)
+				symbols, err := rest.ExchangeInfoUSDTPERP(ctxSymbols)
+				cancel()
+				if err != nil {
+					log.Printf("kline backfill skipped: %v", err)
+					return
+				}
+				for _, st := range klineStores.Stores() {
+					backfiller := kline.NewBackfiller(rest, st)
+					backfiller.Workers = klineBackfillWorkers
+					backfiller.Rate = float64(klineBackfillRate)
+					start := time.Now()
+					res, err := backfiller.Run(ctx, symbols)
+					if err != nil {
+						log.Printf("kline backfill %s stopped: %v", st.Timeframe(), err)
+						return
+					}
+					log.Printf("kline backfill %s done: symbols=%d klines=%d failed=%d in %v",
+						st.Timeframe(), res.Symbols, res.Klines, res.Failed, time.Since(start).Round(time.Second))
+				}
+			}()
+		}
+	}
+
+	confirmation := monitor.Confirmation{
+		Hold:           *confirmHold,
+		KlineClose:     *confirmKline,
+		MinPenetration: *confirmPenetration,
 	}
 
 	// Create monitor with full config
@@ -130,19 +371,42 @@ func main() {
 		Broker:          signalBroker,
 		History:         history,
 		Cooldown:        cooldown,
+		PivotMethods:    methods,
+		Confirmation:    confirmation,
+		Rejection:       monitor.Rejection{Approach: *rejectApproach, Touch: *rejectTouch},
+		Outcomes:        outcomes,
 		KlineStore:      klineStore,
+		KlineStores:     klineStores,
 		PatternDetector: patternDetector,
 		PatternHistory:  patternHistory,
 		PatternBroker:   patternBroker,
 		SignalCombiner:  signalCombiner,
 	})
 	mon.HeartbeatEvery = *monitorHeartbeat
+	mon.Endpoints = wsEndpoints
+	mon.Listings = listingWatcher
+	if *record {
+		dir := *recordDir
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(*dataDir, dir)
+		}
+		rec, err := recorder.New(dir)
+		if err != nil {
+			log.Fatalf("recorder init error: %v", err)
+		}
+		defer rec.Close()
+		mon.Recorder = rec
+		log.Printf("recording mark price frames to %s", dir)
+	}
+	log.Printf("config: confirm_hold=%v confirm_kline=%v confirm_penetration=%g%%", *confirmHold, *confirmKline, *confirmPenetration)
+	log.Printf("config: reject_approach=%g%% reject_touch=%g%%", *rejectApproach, *rejectTouch)
 	go mon.Run(ctx)
 
 	// Ticker monitor
 	tickerStore := ticker.NewStore()
 	tickerMon := ticker.NewMonitor(tickerStore)
 	tickerMon.BatchInterval = *tickerBatchInterval
+	tickerMon.Endpoints = wsEndpoints
 	go tickerMon.Run(ctx)
 
 	// Ranking monitor
@@ -158,7 +422,9 @@ func main() {
 		go sampler.Run(ctx)
 
 		// Persist ranking data periodically
+		persistWG.Add(1)
 		go func() {
+			defer persistWG.Done()
 			ticker := time.NewTicker(5 * time.Minute)
 			defer ticker.Stop()
 			for {
@@ -180,16 +446,118 @@ func main() {
 		log.Printf("ranking monitor enabled: sample_interval=5m retention=24h")
 	}
 
+	// 多信号共振评分：日/周线穿越、形态、成交额排名变化、涨跌幅
+	weights, err := confluence.ParseWeights(*confluenceWeights)
+	if err != nil {
+		log.Fatalf("invalid -confluence-weights: %v", err)
+	}
+	scorer := confluence.New(tickerStore, rankingStore)
+	scorer.Weights = weights
+	scorer.Threshold = *confluenceThreshold
+	scorer.Window = *confluenceWindow
+	var recentPatterns []pattern.Signal
+	if patternHistory != nil {
+		recentPatterns = patternHistory.Query(pattern.QueryOptions{Since: time.Now().Add(-*confluenceWindow)})
+	}
+	scorer.Seed(append(history.Query("", signalpkg.PeriodDaily, "", "", "", 4000),
+		history.Query("", signalpkg.PeriodWeekly, "", "", "", 4000)...), recentPatterns)
+	go scorer.Run(ctx, signalBroker, patternBroker, confluence.DefaultInterval)
+	log.Printf("config: confluence_threshold=%g confluence_window=%v confluence_weights=%+v", scorer.Threshold, scorer.Window, scorer.Weights)
+
+	var notifier *notify.Dispatcher
+	if *webhookConfig != "" {
+		cfg, err := notify.LoadConfig(*webhookConfig)
+		if err != nil {
+			log.Fatalf("webhook config error: %v", err)
+		}
+		if cfg.DeadLetterFile == "" {
+			cfg.DeadLetterFile = "notify/deadletter.jsonl"
+		}
+		if !filepath.IsAbs(cfg.DeadLetterFile) {
+			cfg.DeadLetterFile = filepath.Join(*dataDir, cfg.DeadLetterFile)
+		}
+		notifier, err = notify.New(cfg)
+		if err != nil {
+			log.Fatalf("webhook config error: %v", err)
+		}
+		// 退出时未送达的通知写入死信文件
+		persistWG.Add(1)
+		go func() {
+			defer persistWG.Done()
+			notifier.Run(ctx, signalBroker, patternBroker)
+		}()
+		log.Printf("config: webhooks=%d dead_letter_file=%s", len(cfg.Endpoints), cfg.DeadLetterFile)
+	}
+
+	profilesPath := *profilesFile
+	if profilesPath != "" && !filepath.IsAbs(profilesPath) {
+		profilesPath = filepath.Join(*dataDir, profilesPath)
+	}
+	profiles, err := subscription.NewProfileStore(profilesPath)
+	if err != nil {
+		log.Fatalf("profiles load error: %v", err)
+	}
+	log.Printf("config: profiles_file=%s profiles=%d", profilesPath, len(profiles.List()))
+
 	api := httpapi.New(signalBroker, history, httpapi.ParseAllowedOrigins(*corsOrigins))
 	api.PivotStatus = refresher
+	api.Listings = listingWatcher
+	api.Outcomes = outcomes
 	api.PivotStore = store
+	api.PivotArchive = refresher.Archive
+	api.PivotSession = session
 	api.TickerStore = tickerStore
 	api.TickerMonitor = tickerMon
+	api.WSEndpoints = wsEndpoints
+	api.RESTLimiter = rest.Limiter
 	api.PatternBroker = patternBroker
 	api.PatternHistory = patternHistory
+	api.PatternStats = patternStats
 	api.KlineStore = klineStore
+	api.KlineStores = klineStores
 	api.SignalCombiner = signalCombiner
+	api.CombinedBroker = combinedBroker
+	api.CombinedHistory = combinedHistory
 	api.RankingStore = rankingStore
+	api.Confluence = scorer
+	api.Notifier = notifier
+	api.Profiles = profiles
+	policy, err := sse.ParsePolicy(*ssePolicy)
+	if err != nil {
+		log.Fatalf("invalid -sse-policy: %v", err)
+	}
+	api.Events = sse.NewHub(*sseReplay)
+	api.Events.SetPolicy(policy, *sseMaxDrops)
+	// 内部广播器同样使用该策略；其订阅者（hub、webhook、Telegram、共振评分）为固定订阅，只丢弃不断开
+	signalBroker.SetPolicy(policy, *sseMaxDrops)
+	if patternBroker != nil {
+		patternBroker.SetPolicy(policy, *sseMaxDrops)
+	}
+	if combinedBroker != nil {
+		combinedBroker.SetPolicy(policy, *sseMaxDrops)
+	}
+	scorer.Broker().SetPolicy(policy, *sseMaxDrops)
+	if listingWatcher != nil {
+		listingWatcher.Broker().SetPolicy(policy, *sseMaxDrops)
+	}
+	tickerMon.Broker().SetPolicy(policy, *sseMaxDrops)
+	log.Printf("config: sse_replay=%d sse_policy=%s sse_max_drops=%d", *sseReplay, policy, *sseMaxDrops)
+	go api.RunEvents(ctx)
+	api.BacktestSource = rest
+	api.BacktestSession = session
+
+	if *telegramToken != "" {
+		chats, err := parseChatIDs(*telegramChats)
+		if err != nil {
+			log.Fatalf("invalid -telegram-chats: %v", err)
+		}
+		if len(chats) == 0 {
+			log.Fatalf("-telegram-chats is required when a telegram token is set")
+		}
+		bot := telegram.New(telegram.NewClient(*telegramAPI, *telegramToken), chats, api, tickerStore)
+		go bot.Run(ctx, signalBroker, patternBroker)
+		log.Printf("config: telegram_api=%s telegram_chats=%d", *telegramAPI, len(chats))
+	}
 
 	srv := &http.Server{
 		Addr:              *addr,
@@ -208,6 +576,25 @@ func main() {
 	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		log.Fatalf("http server error: %v", err)
 	}
+	// 等待最终落盘完成再退出
+	persistWG.Wait()
+}
+
+// parseChatIDs parses a comma-separated list of chat ids.
+func parseChatIDs(s string) ([]int64, error) {
+	var ids []int64
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("chat id %q: %w", part, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
 }
 
 // getEnvBool reads a boolean from environment variable.
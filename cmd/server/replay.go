@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"example.com/binance-pivot-monitor/internal/kline"
+	"example.com/binance-pivot-monitor/internal/monitor"
+	"example.com/binance-pivot-monitor/internal/pattern"
+	"example.com/binance-pivot-monitor/internal/pivot"
+	"example.com/binance-pivot-monitor/internal/recorder"
+	signalpkg "example.com/binance-pivot-monitor/internal/signal"
+)
+
+// replayEvent is one output line of the replay subcommand.
+type replayEvent struct {
+	Type   string `json:"type"` // pivot or pattern
+	Signal any    `json:"signal"`
+}
+
+// runReplay feeds recorded mark price frames through a Monitor with fixed
+// pivot snapshots and writes every emitted signal as JSONL.
+//
+//	server replay -from 2025-01-06 -to 2025-01-07 -speed 0 -out signals.jsonl
+func runReplay(args []string) {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	dataDir := fs.String("data-dir", "data", "")
+	recordDir := fs.String("record-dir", "recordings", "")
+	from := fs.String("from", "", "")
+	to := fs.String("to", "", "")
+	speed := fs.Float64("speed", 0, "")
+	pivotFiles := fs.String("pivots", "", "")
+	pivotMethods := fs.String("pivot-methods", "", "")
+	cooldownDur := fs.Duration("cooldown", 30*time.Minute, "")
+	confirmHold := fs.Duration("confirm-hold", 0, "")
+	confirmKline := fs.Duration("confirm-kline", 0, "")
+	confirmPenetration := fs.Float64("confirm-penetration", 0, "")
+	rejectApproach := fs.Float64("reject-approach", 0, "")
+	rejectTouch := fs.Float64("reject-touch", 0, "")
+	patterns := fs.Bool("patterns", false, "")
+	klineInterval := fs.Duration("kline-interval", 15*time.Minute, "")
+	klineCount := fs.Int("kline-count", 12, "")
+	patternMinConfidence := fs.Int("pattern-min-confidence", 60, "")
+	out := fs.String("out", "", "")
+	_ = fs.Parse(args)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fromT, err := parseReplayTime(*from)
+	if err != nil {
+		log.Fatalf("invalid -from: %v", err)
+	}
+	toT, err := parseReplayTime(*to)
+	if err != nil {
+		log.Fatalf("invalid -to: %v", err)
+	}
+
+	dir := *recordDir
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(*dataDir, dir)
+	}
+	files, err := recorder.Files(dir, fromT, toT)
+	if err != nil {
+		log.Fatalf("replay list recordings: %v", err)
+	}
+	if len(files) == 0 {
+		log.Fatalf("replay: no recordings in %s for the requested range", dir)
+	}
+
+	store, err := loadReplayPivots(*dataDir, *pivotFiles)
+	if err != nil {
+		log.Fatalf("replay pivots: %v", err)
+	}
+
+	var methods []pivot.Method
+	if *pivotMethods != "" {
+		if methods, err = pivot.ParseMethods(*pivotMethods); err != nil {
+			log.Fatalf("invalid -pivot-methods: %v", err)
+		}
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatalf("replay output: %v", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+	enc := json.NewEncoder(bw)
+
+	cfg := monitor.MonitorConfig{
+		PivotStore:   store,
+		Cooldown:     signalpkg.NewCooldown(*cooldownDur),
+		PivotMethods: methods,
+		Confirmation: monitor.Confirmation{
+			Hold:           *confirmHold,
+			KlineClose:     *confirmKline,
+			MinPenetration: *confirmPenetration,
+		},
+		Rejection: monitor.Rejection{Approach: *rejectApproach, Touch: *rejectTouch},
+	}
+	if *patterns {
+		cfg.KlineStore = kline.NewStore(*klineInterval, *klineCount)
+		cfg.KlineStore.SetSynchronous(true)
+		cfg.PatternDetector = pattern.NewDetector(pattern.DetectorConfig{
+			MinConfidence: *patternMinConfidence,
+			CryptoMode:    true,
+			GapThreshold:  0.001,
+		})
+	}
+	mon := monitor.NewWithConfig(cfg)
+	mon.Source = "replay"
+
+	var signals, patternSignals int
+	mon.OnSignal = func(sig signalpkg.Signal) {
+		signals++
+		_ = enc.Encode(replayEvent{Type: "pivot", Signal: sig})
+	}
+	mon.OnPattern = func(sig pattern.Signal) {
+		patternSignals++
+		_ = enc.Encode(replayEvent{Type: "pattern", Signal: sig})
+	}
+
+	log.Printf("replay: files=%d speed=%g periods=%v", len(files), *speed, store.Periods())
+
+	var frames, events int
+	var first time.Time
+	start := time.Now()
+	for _, path := range files {
+		err := recorder.ReadFile(path, func(fr recorder.Frame) error {
+			if (!fromT.IsZero() && fr.Time.Before(fromT)) || (!toT.IsZero() && !fr.Time.Before(toT)) {
+				return nil
+			}
+			if first.IsZero() {
+				first = fr.Time
+			}
+			if *speed > 0 {
+				due := start.Add(time.Duration(float64(fr.Time.Sub(first)) / *speed))
+				if !sleepUntil(ctx, due) {
+					return ctx.Err()
+				}
+			} else if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			frames++
+			events += len(fr.Events)
+			mon.Feed(fr.Events, fr.Time)
+			return nil
+		})
+		if err != nil {
+			log.Printf("replay %s stopped: %v", path, err)
+			break
+		}
+	}
+
+	log.Printf("replay done: frames=%d events=%d signals=%d patterns=%d elapsed=%s",
+		frames, events, signals, patternSignals, time.Since(start).Round(time.Millisecond))
+}
+
+// loadReplayPivots builds a store from comma-separated snapshot files, or
+// from the daily and weekly snapshots in <data-dir>/pivots if none are given.
+func loadReplayPivots(dataDir, files string) (*pivot.Store, error) {
+	var paths []string
+	for _, p := range strings.Split(files, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			paths = append(paths, p)
+		}
+	}
+	if len(paths) == 0 {
+		paths = []string{
+			filepath.Join(dataDir, "pivots", "daily.json"),
+			filepath.Join(dataDir, "pivots", "weekly.json"),
+		}
+	}
+
+	var snaps []*pivot.Snapshot
+	var periods []pivot.Period
+	for _, path := range paths {
+		snap, err := pivot.LoadSnapshotFile(path)
+		if err != nil {
+			if len(files) == 0 && os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		snaps = append(snaps, snap)
+		periods = append(periods, snap.Period)
+	}
+	if len(snaps) == 0 {
+		return nil, fmt.Errorf("no pivot snapshots found, pass -pivots")
+	}
+
+	store := pivot.NewStore(periods...)
+	for _, snap := range snaps {
+		if err := store.Swap(snap.Period, snap); err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+// parseReplayTime accepts RFC3339, "2006-01-02T15" or "2006-01-02" (UTC).
+func parseReplayTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time %q", s)
+}
+
+func sleepUntil(ctx context.Context, t time.Time) bool {
+	d := time.Until(t)
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -6,6 +6,8 @@ let currentConfig = {
 let currentServerUrl = "";
 let eventSource = null;
 let reconnectTimer = null;
+// Last event id seen, used to replay missed signals after a reconnect
+let lastEventId = "";
 
 function safeRuntimeSendMessage(msg) {
   try {
@@ -61,10 +63,15 @@ function connectSSE(serverUrl) {
     return;
   }
 
+  if (serverUrl !== currentServerUrl) {
+    lastEventId = "";
+  }
   currentServerUrl = serverUrl;
   closeSSE();
 
-  const url = `${serverUrl}/api/sse`;
+  const url = lastEventId
+    ? `${serverUrl}/api/sse?since=${encodeURIComponent(lastEventId)}`
+    : `${serverUrl}/api/sse`;
   sendStatus("reconnecting");
 
   try {
@@ -92,8 +99,15 @@ function connectSSE(serverUrl) {
   });
 
   eventSource.addEventListener("signal", (ev) => {
+    if (ev.lastEventId) {
+      lastEventId = ev.lastEventId;
+    }
     try {
       const sig = JSON.parse(ev.data);
+      // Signals from older servers have no kind; they are all crossings
+      if (!sig.kind) {
+        sig.kind = "cross";
+      }
       safeRuntimeSendMessage({ type: "signal", signal: sig });
     } catch (_) {
     }
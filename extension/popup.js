@@ -145,12 +145,17 @@ function render(signals) {
     tagL.className = "tag";
     tagL.textContent = String(s.level || "");
 
+    const tagK = document.createElement("span");
+    tagK.className = `tag kind ${String(s.kind || "cross")}`;
+    tagK.textContent = String(s.kind || "cross");
+
     const tagD = document.createElement("span");
     tagD.className = `tag dir ${String(s.direction || "")}`;
     tagD.textContent = String(s.direction || "");
 
     tags.appendChild(tagP);
     tags.appendChild(tagL);
+    tags.appendChild(tagK);
     tags.appendChild(tagD);
 
     row.appendChild(sym);
@@ -0,0 +1,415 @@
+// Package backtest replays historical 1m klines against rebuilt pivot levels
+// and measures how level crossings performed afterwards.
+package backtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"example.com/binance-pivot-monitor/internal/binance"
+	"example.com/binance-pivot-monitor/internal/pivot"
+	signalpkg "example.com/binance-pivot-monitor/internal/signal"
+)
+
+// DefaultHorizons are the forward return windows measured for each signal.
+var DefaultHorizons = []time.Duration{15 * time.Minute, time.Hour, 4 * time.Hour}
+
+// KlineSource provides historical klines. *binance.RESTClient implements it.
+type KlineSource interface {
+	KlinesRange(ctx context.Context, symbol, interval string, start, end time.Time) ([]binance.Kline, error)
+}
+
+// Config selects what to backtest.
+type Config struct {
+	Symbols []string
+	From    time.Time
+	To      time.Time
+	// Periods defaults to daily and weekly.
+	Periods []pivot.Period
+	// Method defaults to Camarilla.
+	Method pivot.Method
+	// Levels limits the levels checked; empty means all of pivot.LevelNames.
+	Levels []string
+	// Horizons defaults to DefaultHorizons.
+	Horizons []time.Duration
+	// Cooldown mirrors the live per-level cooldown; defaults to 30m.
+	Cooldown time.Duration
+	Session  pivot.Session
+	// Trades includes every signal in the report when set.
+	Trades bool
+}
+
+// Trade is one historical crossing.
+type Trade struct {
+	Symbol    string             `json:"symbol"`
+	Period    string             `json:"period"`
+	Level     string             `json:"level"`
+	Direction string             `json:"direction"`
+	Time      time.Time          `json:"time"`
+	Price     float64            `json:"price"`
+	Returns   map[string]float64 `json:"returns"` // by horizon, in percent
+}
+
+// HorizonStat summarizes forward returns for one horizon. Returns are in
+// percent; a hit is a move in the signal's direction.
+type HorizonStat struct {
+	Samples   int     `json:"samples"`
+	AvgReturn float64 `json:"avg_return"`
+	// AvgSigned is the average return in the signal's direction.
+	AvgSigned float64 `json:"avg_signed_return"`
+	HitRate   float64 `json:"hit_rate"`
+}
+
+// Stat aggregates trades of one symbol, period, level and direction.
+// Symbol is "ALL" for the cross-symbol summary.
+type Stat struct {
+	Symbol    string                 `json:"symbol"`
+	Period    string                 `json:"period"`
+	Level     string                 `json:"level"`
+	Direction string                 `json:"direction"`
+	Count     int                    `json:"count"`
+	Horizons  map[string]HorizonStat `json:"horizons"`
+}
+
+// Report is the result of a backtest run.
+type Report struct {
+	From     time.Time         `json:"from"`
+	To       time.Time         `json:"to"`
+	Method   pivot.Method      `json:"method"`
+	Periods  []pivot.Period    `json:"periods"`
+	Horizons []string          `json:"horizons"`
+	Symbols  []string          `json:"symbols"`
+	Summary  []Stat            `json:"summary"`
+	Stats    []Stat            `json:"stats"`
+	Trades   []Trade           `json:"trades,omitempty"`
+	Errors   map[string]string `json:"errors,omitempty"`
+}
+
+// Run backtests every symbol. Symbols whose klines cannot be fetched are
+// reported in Report.Errors; Run fails only if every symbol fails.
+func Run(ctx context.Context, src KlineSource, cfg Config) (*Report, error) {
+	cfg = withDefaults(cfg)
+	if len(cfg.Symbols) == 0 {
+		return nil, errors.New("no symbols")
+	}
+	if !cfg.To.After(cfg.From) {
+		return nil, errors.New("to must be after from")
+	}
+
+	rep := &Report{
+		From:    cfg.From,
+		To:      cfg.To,
+		Method:  cfg.Method,
+		Periods: cfg.Periods,
+		Symbols: cfg.Symbols,
+	}
+	for _, h := range cfg.Horizons {
+		rep.Horizons = append(rep.Horizons, horizonName(h))
+	}
+
+	var trades []Trade
+	for _, sym := range cfg.Symbols {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		t, err := runSymbol(ctx, src, cfg, sym)
+		if err != nil {
+			if rep.Errors == nil {
+				rep.Errors = make(map[string]string)
+			}
+			rep.Errors[sym] = err.Error()
+			continue
+		}
+		trades = append(trades, t...)
+	}
+	if len(rep.Errors) == len(cfg.Symbols) {
+		return nil, fmt.Errorf("backtest failed for all symbols: %s", rep.Errors[cfg.Symbols[0]])
+	}
+
+	rep.Stats = aggregate(trades, cfg.Horizons, false)
+	rep.Summary = aggregate(trades, cfg.Horizons, true)
+	if cfg.Trades {
+		rep.Trades = trades
+	}
+	return rep, nil
+}
+
+func withDefaults(cfg Config) Config {
+	if len(cfg.Periods) == 0 {
+		cfg.Periods = []pivot.Period{pivot.PeriodDaily, pivot.PeriodWeekly}
+	}
+	if cfg.Method == "" {
+		cfg.Method = pivot.DefaultMethod
+	}
+	if len(cfg.Levels) == 0 {
+		cfg.Levels = pivot.LevelNames
+	}
+	if len(cfg.Horizons) == 0 {
+		cfg.Horizons = DefaultHorizons
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = 30 * time.Minute
+	}
+	if cfg.Session.Location == nil {
+		cfg.Session = pivot.DefaultSession()
+	}
+	symbols := make([]string, 0, len(cfg.Symbols))
+	for _, s := range cfg.Symbols {
+		if s = strings.ToUpper(strings.TrimSpace(s)); s != "" {
+			symbols = append(symbols, s)
+		}
+	}
+	cfg.Symbols = symbols
+	return cfg
+}
+
+// runSymbol fetches 1m klines covering the prior period of From through
+// To plus the longest horizon, then walks every period.
+func runSymbol(ctx context.Context, src KlineSource, cfg Config, symbol string) ([]Trade, error) {
+	fetchStart := cfg.From
+	for _, p := range cfg.Periods {
+		first := cfg.Session.Start(cfg.From, p)
+		prev := cfg.Session.Start(first.Add(-time.Second), p)
+		if prev.Before(fetchStart) {
+			fetchStart = prev
+		}
+	}
+	maxHorizon := cfg.Horizons[0]
+	for _, h := range cfg.Horizons {
+		if h > maxHorizon {
+			maxHorizon = h
+		}
+	}
+	fetchEnd := cfg.To.Add(maxHorizon)
+	if now := time.Now(); fetchEnd.After(now) {
+		fetchEnd = now
+	}
+
+	klines, err := src.KlinesRange(ctx, symbol, "1m", fetchStart, fetchEnd)
+	if err != nil {
+		return nil, err
+	}
+	if len(klines) == 0 {
+		return nil, errors.New("no klines")
+	}
+
+	var trades []Trade
+	for _, p := range cfg.Periods {
+		trades = append(trades, walkPeriod(klines, cfg, symbol, p)...)
+	}
+	sort.SliceStable(trades, func(i, j int) bool { return trades[i].Time.Before(trades[j].Time) })
+	return trades, nil
+}
+
+// walkPeriod rebuilds the levels of each period from the previous period's
+// klines and checks every 1m close against them.
+func walkPeriod(klines []binance.Kline, cfg Config, symbol string, period pivot.Period) []Trade {
+	cooldown := signalpkg.NewCooldown(cfg.Cooldown)
+	var trades []Trade
+
+	start := cfg.Session.Start(cfg.From, period)
+	for start.Before(cfg.To) {
+		next := cfg.Session.Next(start, period)
+		prevStart := cfg.Session.Start(start.Add(-time.Second), period)
+
+		ohlc, ok := pivot.AggregateOHLC(slice(klines, prevStart, start))
+		if !ok {
+			start = next
+			continue
+		}
+		lv, err := pivot.CalculateWith(cfg.Method, ohlc)
+		if err != nil {
+			start = next
+			continue
+		}
+
+		from, to := start, next
+		if from.Before(cfg.From) {
+			from = cfg.From
+		}
+		if to.After(cfg.To) {
+			to = cfg.To
+		}
+		i := index(klines, from)
+		for ; i < len(klines) && klines[i].OpenTime.Before(to); i++ {
+			if i == 0 {
+				continue
+			}
+			prev, cur := klines[i-1].Close, klines[i].Close
+			closeAt := klines[i].OpenTime.Add(time.Minute)
+			for _, name := range cfg.Levels {
+				price, ok := lv.Price(name)
+				if !ok {
+					continue
+				}
+				dir := pivot.Crossing(prev, cur, price)
+				if dir == "" {
+					continue
+				}
+				if !cooldown.Allow(symbol+"|"+string(period)+"|"+name, closeAt) {
+					continue
+				}
+				trades = append(trades, Trade{
+					Symbol:    symbol,
+					Period:    string(period),
+					Level:     name,
+					Direction: dir,
+					Time:      closeAt,
+					Price:     cur,
+					Returns:   forwardReturns(klines, i, cfg.Horizons),
+				})
+			}
+		}
+		start = next
+	}
+	return trades
+}
+
+// forwardReturns returns the percent change from klines[i] to the close h
+// later, for each horizon with data.
+func forwardReturns(klines []binance.Kline, i int, horizons []time.Duration) map[string]float64 {
+	out := make(map[string]float64, len(horizons))
+	entry := klines[i].Close
+	for _, h := range horizons {
+		j := index(klines, klines[i].OpenTime.Add(h))
+		if j >= len(klines) || !klines[j].OpenTime.Equal(klines[i].OpenTime.Add(h)) {
+			continue
+		}
+		out[horizonName(h)] = (klines[j].Close - entry) / entry * 100
+	}
+	return out
+}
+
+// index returns the first kline opening at or after t.
+func index(klines []binance.Kline, t time.Time) int {
+	return sort.Search(len(klines), func(i int) bool { return !klines[i].OpenTime.Before(t) })
+}
+
+func slice(klines []binance.Kline, from, to time.Time) []binance.Kline {
+	return klines[index(klines, from):index(klines, to)]
+}
+
+func horizonName(d time.Duration) string {
+	switch {
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	default:
+		return d.String()
+	}
+}
+
+func aggregate(trades []Trade, horizons []time.Duration, allSymbols bool) []Stat {
+	type acc struct {
+		stat   Stat
+		sum    map[string]float64
+		signed map[string]float64
+		hits   map[string]int
+	}
+	groups := make(map[string]*acc)
+	var order []string
+
+	for _, t := range trades {
+		sym := t.Symbol
+		if allSymbols {
+			sym = "ALL"
+		}
+		key := sym + "|" + t.Period + "|" + t.Level + "|" + t.Direction
+		a, ok := groups[key]
+		if !ok {
+			a = &acc{
+				stat:   Stat{Symbol: sym, Period: t.Period, Level: t.Level, Direction: t.Direction, Horizons: make(map[string]HorizonStat)},
+				sum:    make(map[string]float64),
+				signed: make(map[string]float64),
+				hits:   make(map[string]int),
+			}
+			groups[key] = a
+			order = append(order, key)
+		}
+		a.stat.Count++
+		for name, r := range t.Returns {
+			signed := r
+			if t.Direction == "down" {
+				signed = -r
+			}
+			hs := a.stat.Horizons[name]
+			hs.Samples++
+			a.stat.Horizons[name] = hs
+			a.sum[name] += r
+			a.signed[name] += signed
+			if signed > 0 {
+				a.hits[name]++
+			}
+		}
+	}
+
+	out := make([]Stat, 0, len(order))
+	for _, key := range order {
+		a := groups[key]
+		for _, h := range horizons {
+			name := horizonName(h)
+			hs, ok := a.stat.Horizons[name]
+			if !ok || hs.Samples == 0 {
+				continue
+			}
+			n := float64(hs.Samples)
+			hs.AvgReturn = a.sum[name] / n
+			hs.AvgSigned = a.signed[name] / n
+			hs.HitRate = float64(a.hits[name]) / n
+			a.stat.Horizons[name] = hs
+		}
+		out = append(out, a.stat)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Symbol != b.Symbol {
+			return a.Symbol < b.Symbol
+		}
+		if a.Period != b.Period {
+			return a.Period < b.Period
+		}
+		if a.Level != b.Level {
+			return a.Level < b.Level
+		}
+		return a.Direction < b.Direction
+	})
+	return out
+}
+
+// ParseLevels parses a comma-separated level list such as "R3,S3".
+func ParseLevels(s string) ([]string, error) {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		name := strings.ToUpper(strings.TrimSpace(part))
+		if name == "" {
+			continue
+		}
+		if _, ok := (pivot.Levels{}).Price(name); !ok {
+			return nil, fmt.Errorf("unknown level %q", part)
+		}
+		out = append(out, name)
+	}
+	return out, nil
+}
+
+// ParseHorizons parses a comma-separated duration list such as "15m,1h,4h".
+func ParseHorizons(s string) ([]time.Duration, error) {
+	var out []time.Duration
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		d, err := time.ParseDuration(part)
+		if err != nil || d < time.Minute || d%time.Minute != 0 {
+			return nil, fmt.Errorf("invalid horizon %q", part)
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
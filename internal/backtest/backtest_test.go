@@ -0,0 +1,155 @@
+package backtest
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"example.com/binance-pivot-monitor/internal/binance"
+	"example.com/binance-pivot-monitor/internal/pivot"
+)
+
+type fakeSource map[string][]binance.Kline
+
+func (f fakeSource) KlinesRange(_ context.Context, symbol, interval string, start, end time.Time) ([]binance.Kline, error) {
+	if interval != "1m" {
+		return nil, errors.New("unexpected interval " + interval)
+	}
+	all, ok := f[symbol]
+	if !ok {
+		return nil, errors.New("unknown symbol")
+	}
+	var out []binance.Kline
+	for _, k := range all {
+		if !k.OpenTime.Before(start) && k.OpenTime.Before(end) {
+			out = append(out, k)
+		}
+	}
+	return out, nil
+}
+
+// synthetic builds 1m klines from start with close = price(minute).
+func synthetic(start time.Time, minutes int, price func(int) float64) []binance.Kline {
+	out := make([]binance.Kline, minutes)
+	for i := range out {
+		p := price(i)
+		out[i] = binance.Kline{OpenTime: start.Add(time.Duration(i) * time.Minute), Open: p, High: p, Low: p, Close: p}
+	}
+	return out
+}
+
+func TestRun_RebuildsLevelsAndMeasuresReturns(t *testing.T) {
+	day1 := time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC)
+	day2 := day1.Add(24 * time.Hour)
+
+	// Day 1: high 110, low 90, close 100 → Camarilla R3 = 105.5.
+	klines := synthetic(day1, 2*24*60, func(i int) float64 {
+		switch {
+		case i < 24*60:
+			switch i {
+			case 10:
+				return 110
+			case 20:
+				return 90
+			}
+			return 100
+		case i < 24*60+60:
+			return 100
+		case i < 24*60+60+60:
+			return 106 // crosses R3 at 01:00 day 2
+		default:
+			return 108
+		}
+	})
+
+	rep, err := Run(context.Background(), fakeSource{"BTCUSDT": klines}, Config{
+		Symbols: []string{"btcusdt"},
+		From:    day2,
+		To:      day2.Add(12 * time.Hour),
+		Periods: []pivot.Period{pivot.PeriodDaily},
+		Levels:  []string{"R3", "S3"},
+		Trades:  true,
+	})
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+
+	if len(rep.Trades) != 1 {
+		t.Fatalf("trades = %+v, want 1", rep.Trades)
+	}
+	tr := rep.Trades[0]
+	if tr.Level != "R3" || tr.Direction != "up" || !tr.Time.Equal(day2.Add(61*time.Minute)) {
+		t.Errorf("trade = %+v, want R3 up at 01:01", tr)
+	}
+	if r := tr.Returns["15m"]; math.Abs(r) > 1e-9 {
+		t.Errorf("15m return = %v, want 0", r)
+	}
+	if r, want := tr.Returns["4h"], (108.0-106)/106*100; math.Abs(r-want) > 1e-9 {
+		t.Errorf("4h return = %v, want %v", r, want)
+	}
+
+	if len(rep.Summary) != 1 || rep.Summary[0].Symbol != "ALL" || rep.Summary[0].Count != 1 {
+		t.Fatalf("summary = %+v", rep.Summary)
+	}
+	hs := rep.Summary[0].Horizons["4h"]
+	if hs.Samples != 1 || hs.HitRate != 1 {
+		t.Errorf("4h stat = %+v, want 1 sample hit", hs)
+	}
+	if hs := rep.Summary[0].Horizons["15m"]; hs.HitRate != 0 {
+		t.Errorf("15m hit rate = %v, want 0 for a flat move", hs.HitRate)
+	}
+}
+
+func TestRun_CooldownAndDownHits(t *testing.T) {
+	day1 := time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC)
+	day2 := day1.Add(24 * time.Hour)
+
+	// Day 2 oscillates around S3 (94.5) every 5 minutes, then falls.
+	klines := synthetic(day1, 2*24*60, func(i int) float64 {
+		if i < 24*60 {
+			switch i {
+			case 10:
+				return 110
+			case 20:
+				return 90
+			}
+			return 100
+		}
+		m := i - 24*60
+		if m < 60 {
+			if (m/5)%2 == 0 {
+				return 95
+			}
+			return 94
+		}
+		return 92
+	})
+
+	rep, err := Run(context.Background(), fakeSource{"ETHUSDT": klines}, Config{
+		Symbols:  []string{"ETHUSDT", "MISSING"},
+		From:     day2,
+		To:       day2.Add(6 * time.Hour),
+		Periods:  []pivot.Period{pivot.PeriodDaily},
+		Levels:   []string{"S3"},
+		Cooldown: 30 * time.Minute,
+		Trades:   true,
+	})
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if rep.Errors["MISSING"] == "" {
+		t.Error("expected an error for MISSING")
+	}
+
+	// One crossing per 30m cooldown window across both directions.
+	if len(rep.Trades) != 2 {
+		t.Fatalf("trades = %d, want 2 with cooldown", len(rep.Trades))
+	}
+	for _, st := range rep.Stats {
+		if st.Direction == "down" && st.Horizons["1h"].HitRate != 1 {
+			t.Errorf("down 1h hit rate = %v, want 1", st.Horizons["1h"].HitRate)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package binance
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// DefaultWSMaxFailures is how many consecutive dial failures make
+// WSEndpoints move on to the next endpoint.
+const DefaultWSMaxFailures = 3
+
+// WSEndpoints is a list of WebSocket base URLs such as
+// "wss://fstream.binance.com/ws". Dials go to the active endpoint; after
+// MaxFailures consecutive dial failures the next one becomes active, wrapping
+// around at the end. It is safe to share between connections.
+type WSEndpoints struct {
+	MaxFailures int
+
+	mu       sync.Mutex
+	urls     []string
+	active   int
+	failures int
+	switches int
+	lastErr  string
+	lastDial time.Time
+}
+
+// WSEndpointStatus reports the state of a WSEndpoints.
+type WSEndpointStatus struct {
+	Active    string    `json:"active"`
+	Endpoints []string  `json:"endpoints"`
+	Failures  int       `json:"failures"` // consecutive failures on the active endpoint
+	Switches  int       `json:"switches"`
+	LastError string    `json:"last_error,omitempty"`
+	LastDial  time.Time `json:"last_dial,omitempty"`
+}
+
+// NewWSEndpoints creates an endpoint list. Empty entries and trailing
+// slashes are dropped; with no usable entry, FStreamWSBaseURL is used.
+func NewWSEndpoints(urls []string) *WSEndpoints {
+	e := &WSEndpoints{MaxFailures: DefaultWSMaxFailures}
+	for _, u := range urls {
+		u = strings.TrimRight(strings.TrimSpace(u), "/")
+		if u != "" {
+			e.urls = append(e.urls, u)
+		}
+	}
+	if len(e.urls) == 0 {
+		e.urls = []string{FStreamWSBaseURL}
+	}
+	return e
+}
+
+// ParseWSEndpoints parses a comma-separated endpoint list.
+func ParseWSEndpoints(s string) *WSEndpoints {
+	return NewWSEndpoints(strings.Split(s, ","))
+}
+
+// Active returns the endpoint the next dial goes to.
+func (e *WSEndpoints) Active() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.urls[e.active]
+}
+
+// Status returns a snapshot of the endpoint state.
+func (e *WSEndpoints) Status() WSEndpointStatus {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return WSEndpointStatus{
+		Active:    e.urls[e.active],
+		Endpoints: append([]string(nil), e.urls...),
+		Failures:  e.failures,
+		Switches:  e.switches,
+		LastError: e.lastErr,
+		LastDial:  e.lastDial,
+	}
+}
+
+// Dial connects to stream (e.g. "/!ticker@arr") on the active endpoint and
+// records the result for failover. It returns the base URL it dialed, which
+// Active may no longer report once another connection has failed over.
+func (e *WSEndpoints) Dial(ctx context.Context, stream string) (*websocket.Conn, string, *http.Response, error) {
+	base := e.Active()
+	d := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: 10 * time.Second,
+	}
+	conn, resp, err := d.DialContext(ctx, base+stream, nil)
+	if ctx.Err() == nil {
+		e.record(base, err)
+	}
+	return conn, base, resp, err
+}
+
+func (e *WSEndpoints) record(base string, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.lastDial = time.Now()
+	if e.urls[e.active] != base {
+		return // 其他连接已切换端点
+	}
+	if err == nil {
+		e.failures = 0
+		e.lastErr = ""
+		return
+	}
+	e.failures++
+	e.lastErr = err.Error()
+	max := e.MaxFailures
+	if max <= 0 {
+		max = DefaultWSMaxFailures
+	}
+	if e.failures >= max && len(e.urls) > 1 {
+		e.active = (e.active + 1) % len(e.urls)
+		e.failures = 0
+		e.switches++
+	}
+}
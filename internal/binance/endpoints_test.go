@@ -0,0 +1,70 @@
+package binance
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestWSEndpoints_Failover(t *testing.T) {
+	var paths []string
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conn.Close()
+	}))
+	defer up.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	downURL := "ws" + strings.TrimPrefix(down.URL, "http")
+	down.Close()
+	upURL := "ws" + strings.TrimPrefix(up.URL, "http") + "/ws"
+
+	e := NewWSEndpoints([]string{downURL, " ", upURL + "/"})
+	e.MaxFailures = 2
+	if got := e.Status().Endpoints; len(got) != 2 || got[1] != upURL {
+		t.Fatalf("endpoints = %v", got)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		_, base, _, err := DialTickerArr(ctx, e)
+		if err == nil {
+			t.Fatal("expected dial to the closed endpoint to fail")
+		}
+		if base != downURL {
+			t.Errorf("dial %d went to %s, want %s", i, base, downURL)
+		}
+	}
+	st := e.Status()
+	if st.Active != upURL || st.Switches != 1 || st.Failures != 0 || st.LastError == "" {
+		t.Fatalf("status after failover = %+v", st)
+	}
+
+	conn, base, _, err := DialMarkPriceArr1s(ctx, e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if base != upURL {
+		t.Errorf("dialed %s, want %s", base, upURL)
+	}
+	conn.Close()
+	if len(paths) != 1 || paths[0] != "/ws/!markPrice@arr@1s" {
+		t.Errorf("paths = %v", paths)
+	}
+	if st := e.Status(); st.LastError != "" || st.Active != upURL {
+		t.Errorf("status after success = %+v", st)
+	}
+}
+
+func TestParseWSEndpoints_Default(t *testing.T) {
+	if got := ParseWSEndpoints("").Active(); got != FStreamWSBaseURL {
+		t.Errorf("active = %q", got)
+	}
+}
@@ -0,0 +1,194 @@
+package binance
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Binance futures allows 2400 request weight per minute per IP. The limiter
+// keeps some headroom for other clients on the same IP.
+const (
+	WeightLimitPerMinute = 2400
+	DefaultMaxWeight     = 2000
+)
+
+// HeaderUsedWeight reports the IP's used weight in the current minute.
+const HeaderUsedWeight = "X-Mbx-Used-Weight-1m"
+
+// BanError is returned while the IP is banned (HTTP 418). No request is sent
+// until the ban expires.
+type BanError struct {
+	Until time.Time
+}
+
+func (e *BanError) Error() string {
+	return fmt.Sprintf("binance: IP banned until %s", e.Until.Format(time.RFC3339))
+}
+
+// Limiter tracks request weight per clock minute, shared by every call of a
+// RESTClient. Requests wait when the minute's budget is spent, pause after a
+// 429 for Retry-After, and fail fast during a 418 ban.
+type Limiter struct {
+	MaxWeight int
+
+	mu          sync.Mutex
+	now         func() time.Time
+	window      time.Time // start of the current minute
+	used        int
+	pausedUntil time.Time
+	bannedUntil time.Time
+
+	requests     int64
+	retries      int64
+	throttled    int64
+	rateLimited  int64
+	serverErrors int64
+	bans         int64
+}
+
+// LimiterStats is a snapshot of a Limiter's state and counters.
+type LimiterStats struct {
+	UsedWeight   int        `json:"used_weight"`
+	MaxWeight    int        `json:"max_weight"`
+	Requests     int64      `json:"requests"`
+	Retries      int64      `json:"retries"`
+	Throttled    int64      `json:"throttled"`     // waits for the next minute's budget
+	RateLimited  int64      `json:"rate_limited"`  // 429 responses
+	ServerErrors int64      `json:"server_errors"` // 5xx responses
+	Bans         int64      `json:"bans"`          // 418 responses
+	PausedUntil  *time.Time `json:"paused_until,omitempty"`
+	BannedUntil  *time.Time `json:"banned_until,omitempty"`
+}
+
+// NewLimiter creates a limiter; maxWeight <= 0 uses DefaultMaxWeight.
+func NewLimiter(maxWeight int) *Limiter {
+	if maxWeight <= 0 {
+		maxWeight = DefaultMaxWeight
+	}
+	return &Limiter{MaxWeight: maxWeight, now: time.Now}
+}
+
+// Wait blocks until weight fits into the current minute's budget. It returns
+// a *BanError during a ban, or the context's error.
+func (l *Limiter) Wait(ctx context.Context, weight int) error {
+	for {
+		l.mu.Lock()
+		now := l.now()
+		if now.Before(l.bannedUntil) {
+			until := l.bannedUntil
+			l.mu.Unlock()
+			return &BanError{Until: until}
+		}
+		var wait time.Duration
+		if now.Before(l.pausedUntil) {
+			wait = l.pausedUntil.Sub(now)
+		} else {
+			l.rollLocked(now)
+			if l.used+weight <= l.MaxWeight || l.used == 0 {
+				l.used += weight
+				l.requests++
+				l.mu.Unlock()
+				return nil
+			}
+			l.throttled++
+			wait = l.window.Add(time.Minute).Sub(now)
+		}
+		l.mu.Unlock()
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+func (l *Limiter) rollLocked(now time.Time) {
+	if w := now.Truncate(time.Minute); !w.Equal(l.window) {
+		l.window = w
+		l.used = 0
+	}
+}
+
+// Observe records a response: the server's used weight, and 429/418 pauses.
+func (l *Limiter) Observe(resp *http.Response) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.rollLocked(now)
+	if n, err := strconv.Atoi(resp.Header.Get(HeaderUsedWeight)); err == nil && n > l.used {
+		l.used = n // 同一 IP 上其他客户端也在消耗权重
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTeapot:
+		l.bans++
+		l.bannedUntil = now.Add(retryAfter(resp, 2*time.Minute))
+	case resp.StatusCode == http.StatusTooManyRequests:
+		l.rateLimited++
+		// 未给出 Retry-After 时等到下一分钟
+		l.pausedUntil = now.Add(retryAfter(resp, l.window.Add(time.Minute).Sub(now)))
+	case resp.StatusCode >= 500:
+		l.serverErrors++
+	}
+}
+
+func (l *Limiter) retried() {
+	l.mu.Lock()
+	l.retries++
+	l.mu.Unlock()
+}
+
+// Stats returns a snapshot of the limiter.
+func (l *Limiter) Stats() LimiterStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.rollLocked(now)
+	st := LimiterStats{
+		UsedWeight:   l.used,
+		MaxWeight:    l.MaxWeight,
+		Requests:     l.requests,
+		Retries:      l.retries,
+		Throttled:    l.throttled,
+		RateLimited:  l.rateLimited,
+		ServerErrors: l.serverErrors,
+		Bans:         l.bans,
+	}
+	if now.Before(l.pausedUntil) {
+		t := l.pausedUntil
+		st.PausedUntil = &t
+	}
+	if now.Before(l.bannedUntil) {
+		t := l.bannedUntil
+		st.BannedUntil = &t
+	}
+	return st
+}
+
+// retryAfter parses the Retry-After header in seconds.
+func retryAfter(resp *http.Response, def time.Duration) time.Duration {
+	if n, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && n > 0 {
+		return time.Duration(n) * time.Second
+	}
+	return def
+}
+
+// klinesWeight is the request weight of /fapi/v1/klines for a page size.
+func klinesWeight(limit int) int {
+	switch {
+	case limit < 100:
+		return 1
+	case limit < 500:
+		return 2
+	case limit <= 1000:
+		return 5
+	}
+	return 10
+}
@@ -0,0 +1,127 @@
+package binance
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRESTClient_RetriesServerErrorsAnd429(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.Header().Set(HeaderUsedWeight, "42")
+			_, _ = w.Write([]byte(`{"symbols":[{"symbol":"BTCUSDT","status":"TRADING","contractType":"PERPETUAL","quoteAsset":"USDT"}]}`))
+		}
+	}))
+	defer srv.Close()
+
+	c := NewRESTClient(srv.URL)
+	c.RetryBackoff = time.Millisecond
+	start := time.Now()
+	symbols, err := c.ExchangeInfoUSDTPERP(context.Background())
+	if err != nil || len(symbols) != 1 {
+		t.Fatalf("symbols = %v err = %v", symbols, err)
+	}
+	if time.Since(start) < time.Second {
+		t.Error("429 Retry-After was not honoured")
+	}
+	st := c.Limiter.Stats()
+	if st.Requests != 3 || st.Retries != 2 || st.ServerErrors != 1 || st.RateLimited != 1 || st.UsedWeight < 42 {
+		t.Errorf("stats = %+v", st)
+	}
+}
+
+func TestRESTClient_GivesUpAfterMaxRetries(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	c := NewRESTClient(srv.URL)
+	c.RetryBackoff = time.Millisecond
+	c.MaxRetries = 2
+	if _, err := c.RecentKlines(context.Background(), "BTCUSDT", "5m", 10); err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+}
+
+func TestRESTClient_BanStopsRequests(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Retry-After", "600")
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer srv.Close()
+
+	c := NewRESTClient(srv.URL)
+	for i := 0; i < 3; i++ {
+		_, _, _, err := c.PrevKline(context.Background(), "BTCUSDT", "1d")
+		var ban *BanError
+		if !errors.As(err, &ban) || time.Until(ban.Until) < 9*time.Minute {
+			t.Fatalf("err = %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1 (no requests during a ban)", calls)
+	}
+	if st := c.Limiter.Stats(); st.Bans != 1 || st.BannedUntil == nil {
+		t.Errorf("stats = %+v", st)
+	}
+}
+
+func TestLimiter_WaitsForNextMinute(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 0, 59, 900e6, time.UTC)
+	l := NewLimiter(10)
+	l.now = func() time.Time { return now }
+	ctx := context.Background()
+	if err := l.Wait(ctx, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- l.Wait(ctx, 1) }()
+	select {
+	case err := <-done:
+		t.Fatalf("wait returned early: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	l.mu.Lock()
+	now = now.Add(time.Second)
+	l.mu.Unlock()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("wait did not resume in the next minute")
+	}
+	if st := l.Stats(); st.Throttled == 0 || st.UsedWeight != 1 {
+		t.Errorf("stats = %+v", st)
+	}
+}
+
+func TestKlinesWeight(t *testing.T) {
+	for limit, want := range map[int]int{2: 1, 99: 1, 100: 2, 500: 5, 1000: 5, 1500: 10} {
+		if got := klinesWeight(limit); got != want {
+			t.Errorf("klinesWeight(%d) = %d, want %d", limit, got, want)
+		}
+	}
+}
@@ -5,14 +5,24 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"strconv"
 	"time"
 )
 
+// DefaultMaxRetries is how many times a request is retried after a 429 or
+// 5xx response.
+const DefaultMaxRetries = 3
+
 type RESTClient struct {
 	BaseURL string
 	HTTP    *http.Client
+	// Limiter is shared by every call; nil disables weight tracking.
+	Limiter    *Limiter
+	MaxRetries int
+	// RetryBackoff is the first 5xx retry delay, doubled on each retry.
+	RetryBackoff time.Duration
 }
 
 func NewRESTClient(baseURL string) *RESTClient {
@@ -21,6 +31,68 @@ func NewRESTClient(baseURL string) *RESTClient {
 		HTTP: &http.Client{
 			Timeout: 15 * time.Second,
 		},
+		Limiter:      NewLimiter(DefaultMaxWeight),
+		MaxRetries:   DefaultMaxRetries,
+		RetryBackoff: 500 * time.Millisecond,
+	}
+}
+
+// get sends a GET of the given request weight. 429 and 5xx responses are
+// retried, waiting out Retry-After; the last response is returned as is once
+// retries are exhausted. A 418 ban returns a *BanError.
+func (c *RESTClient) get(ctx context.Context, url string, weight int) (*http.Response, error) {
+	backoff := c.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		if c.Limiter != nil {
+			if err := c.Limiter.Wait(ctx, weight); err != nil {
+				return nil, err
+			}
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.HTTP.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if c.Limiter != nil {
+			c.Limiter.Observe(resp)
+		}
+
+		if resp.StatusCode == http.StatusTeapot {
+			resp.Body.Close()
+			until := time.Now().Add(retryAfter(resp, 2*time.Minute))
+			log.Printf("binance rest: IP banned (418) until %s", until.Format(time.RFC3339))
+			return nil, &BanError{Until: until}
+		}
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		if !retry || attempt >= c.MaxRetries {
+			return resp, nil
+		}
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+		if c.Limiter != nil {
+			c.Limiter.retried()
+		}
+
+		// 429 的等待由 Limiter 负责，5xx 指数退避
+		wait := time.Duration(0)
+		if resp.StatusCode >= 500 {
+			wait = backoff
+			backoff *= 2
+		} else if c.Limiter == nil {
+			wait = retryAfter(resp, time.Second)
+		}
+		if wait > 0 {
+			t := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return nil, ctx.Err()
+			case <-t.C:
+			}
+		}
 	}
 }
 
@@ -35,12 +107,7 @@ type exchangeInfoResp struct {
 
 func (c *RESTClient) ExchangeInfoUSDTPERP(ctx context.Context) ([]string, error) {
 	url := c.BaseURL + "/fapi/v1/exchangeInfo"
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := c.HTTP.Do(req)
+	resp, err := c.get(ctx, url, 1)
 	if err != nil {
 		return nil, err
 	}
@@ -73,61 +140,164 @@ func (c *RESTClient) ExchangeInfoUSDTPERP(ctx context.Context) ([]string, error)
 }
 
 func (c *RESTClient) PrevKline(ctx context.Context, symbol, interval string) (high, low, close float64, err error) {
-	url := fmt.Sprintf("%s/fapi/v1/klines?symbol=%s&interval=%s&limit=2", c.BaseURL, symbol, interval)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		return 0, 0, 0, err
-	}
+	_, high, low, close, err = c.PrevOHLC(ctx, symbol, interval)
+	return high, low, close, err
+}
 
-	resp, err := c.HTTP.Do(req)
+// PrevOHLC returns the open, high, low and close of the last completed kline.
+func (c *RESTClient) PrevOHLC(ctx context.Context, symbol, interval string) (open, high, low, close float64, err error) {
+	url := fmt.Sprintf("%s/fapi/v1/klines?symbol=%s&interval=%s&limit=2", c.BaseURL, symbol, interval)
+	resp, err := c.get(ctx, url, klinesWeight(2))
 	if err != nil {
-		return 0, 0, 0, err
+		return 0, 0, 0, 0, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		b, _ := io.ReadAll(resp.Body)
-		return 0, 0, 0, fmt.Errorf("klines %s %s status=%d body=%s", symbol, interval, resp.StatusCode, string(b))
+		return 0, 0, 0, 0, fmt.Errorf("klines %s %s status=%d body=%s", symbol, interval, resp.StatusCode, string(b))
 	}
 
 	var raw [][]any
 	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
-		return 0, 0, 0, err
+		return 0, 0, 0, 0, err
 	}
 	if len(raw) < 2 {
-		return 0, 0, 0, fmt.Errorf("klines %s %s: not enough data", symbol, interval)
+		return 0, 0, 0, 0, fmt.Errorf("klines %s %s: not enough data", symbol, interval)
 	}
 
 	k := raw[len(raw)-2]
 	if len(k) < 5 {
-		return 0, 0, 0, fmt.Errorf("klines %s %s: invalid kline", symbol, interval)
+		return 0, 0, 0, 0, fmt.Errorf("klines %s %s: invalid kline", symbol, interval)
 	}
 
-	highStr, ok := k[2].(string)
-	if !ok {
-		return 0, 0, 0, fmt.Errorf("klines %s %s: high not string", symbol, interval)
+	var vals [4]float64
+	for i, name := range []string{"open", "high", "low", "close"} {
+		str, ok := k[i+1].(string)
+		if !ok {
+			return 0, 0, 0, 0, fmt.Errorf("klines %s %s: %s not string", symbol, interval, name)
+		}
+		vals[i], err = strconv.ParseFloat(str, 64)
+		if err != nil {
+			return 0, 0, 0, 0, err
+		}
 	}
-	lowStr, ok := k[3].(string)
-	if !ok {
-		return 0, 0, 0, fmt.Errorf("klines %s %s: low not string", symbol, interval)
+
+	return vals[0], vals[1], vals[2], vals[3], nil
+}
+
+// Kline is one futures candle returned by /fapi/v1/klines.
+type Kline struct {
+	OpenTime  time.Time
+	Open      float64
+	High      float64
+	Low       float64
+	Close     float64
+	Volume    float64
+	CloseTime time.Time
+}
+
+// klinesPageLimit is the maximum page size accepted by /fapi/v1/klines.
+const klinesPageLimit = 1500
+
+// KlinesRange returns the klines whose open time is in [start, end), paging
+// through the endpoint as needed.
+func (c *RESTClient) KlinesRange(ctx context.Context, symbol, interval string, start, end time.Time) ([]Kline, error) {
+	var out []Kline
+	from := start
+	for from.Before(end) {
+		url := fmt.Sprintf("%s/fapi/v1/klines?symbol=%s&interval=%s&startTime=%d&endTime=%d&limit=%d",
+			c.BaseURL, symbol, interval, from.UnixMilli(), end.UnixMilli()-1, klinesPageLimit)
+		page, err := c.fetchKlines(ctx, url, klinesWeight(klinesPageLimit), symbol, interval)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range page {
+			if !k.OpenTime.Before(end) {
+				break
+			}
+			out = append(out, k)
+		}
+		if len(page) < klinesPageLimit {
+			break
+		}
+		from = page[len(page)-1].OpenTime.Add(time.Millisecond)
 	}
-	closeStr, ok := k[4].(string)
-	if !ok {
-		return 0, 0, 0, fmt.Errorf("klines %s %s: close not string", symbol, interval)
+	return out, nil
+}
+
+// RecentKlines returns the last limit klines, oldest first. The last one is
+// usually still forming.
+func (c *RESTClient) RecentKlines(ctx context.Context, symbol, interval string, limit int) ([]Kline, error) {
+	if limit <= 0 || limit > klinesPageLimit {
+		limit = klinesPageLimit
 	}
+	url := fmt.Sprintf("%s/fapi/v1/klines?symbol=%s&interval=%s&limit=%d", c.BaseURL, symbol, interval, limit)
+	return c.fetchKlines(ctx, url, klinesWeight(limit), symbol, interval)
+}
 
-	high, err = strconv.ParseFloat(highStr, 64)
+func (c *RESTClient) fetchKlines(ctx context.Context, url string, weight int, symbol, interval string) ([]Kline, error) {
+	resp, err := c.get(ctx, url, weight)
 	if err != nil {
-		return 0, 0, 0, err
+		return nil, err
 	}
-	low, err = strconv.ParseFloat(lowStr, 64)
-	if err != nil {
-		return 0, 0, 0, err
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("klines %s %s status=%d body=%s", symbol, interval, resp.StatusCode, string(b))
 	}
-	close, err = strconv.ParseFloat(closeStr, 64)
-	if err != nil {
-		return 0, 0, 0, err
+
+	var raw [][]any
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	out := make([]Kline, 0, len(raw))
+	for _, k := range raw {
+		kl, err := parseKline(k)
+		if err != nil {
+			return nil, fmt.Errorf("klines %s %s: %w", symbol, interval, err)
+		}
+		out = append(out, kl)
+	}
+	return out, nil
+}
+
+// parseKline decodes [openTime, open, high, low, close, volume, closeTime, ...].
+func parseKline(k []any) (Kline, error) {
+	if len(k) < 7 {
+		return Kline{}, fmt.Errorf("invalid kline")
+	}
+	openMs, ok := k[0].(float64)
+	if !ok {
+		return Kline{}, fmt.Errorf("open time not number")
+	}
+	closeMs, ok := k[6].(float64)
+	if !ok {
+		return Kline{}, fmt.Errorf("close time not number")
+	}
+
+	var vals [5]float64
+	for i, name := range []string{"open", "high", "low", "close", "volume"} {
+		str, ok := k[i+1].(string)
+		if !ok {
+			return Kline{}, fmt.Errorf("%s not string", name)
+		}
+		v, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return Kline{}, err
+		}
+		vals[i] = v
 	}
 
-	return high, low, close, nil
+	return Kline{
+		OpenTime:  time.UnixMilli(int64(openMs)).UTC(),
+		Open:      vals[0],
+		High:      vals[1],
+		Low:       vals[2],
+		Close:     vals[3],
+		Volume:    vals[4],
+		CloseTime: time.UnixMilli(int64(closeMs)).UTC(),
+	}, nil
 }
@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
-	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -59,11 +58,11 @@ func (e *MarkPriceEvent) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
-func DialMarkPriceArr1s(ctx context.Context) (*websocket.Conn, *http.Response, error) {
-	d := websocket.Dialer{
-		Proxy:            http.ProxyFromEnvironment,
-		HandshakeTimeout: 10 * time.Second,
+// DialMarkPriceArr1s dials the all-market mark price stream and returns the
+// base URL dialed. A nil endpoints uses FStreamWSBaseURL.
+func DialMarkPriceArr1s(ctx context.Context, endpoints *WSEndpoints) (*websocket.Conn, string, *http.Response, error) {
+	if endpoints == nil {
+		endpoints = NewWSEndpoints(nil)
 	}
-	url := FStreamWSBaseURL + "/!markPrice@arr@1s"
-	return d.DialContext(ctx, url, nil)
+	return endpoints.Dial(ctx, "/!markPrice@arr@1s")
 }
@@ -5,7 +5,6 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
-	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -92,12 +91,10 @@ func (e *TickerEvent) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
-// DialTickerArr 订阅所有交易对的24小时行情
-func DialTickerArr(ctx context.Context) (*websocket.Conn, *http.Response, error) {
-	d := websocket.Dialer{
-		Proxy:            http.ProxyFromEnvironment,
-		HandshakeTimeout: 10 * time.Second,
+// DialTickerArr 订阅所有交易对的24小时行情，并返回实际连接的端点；endpoints 为 nil 时使用 FStreamWSBaseURL
+func DialTickerArr(ctx context.Context, endpoints *WSEndpoints) (*websocket.Conn, string, *http.Response, error) {
+	if endpoints == nil {
+		endpoints = NewWSEndpoints(nil)
 	}
-	url := FStreamWSBaseURL + "/!ticker@arr"
-	return d.DialContext(ctx, url, nil)
+	return endpoints.Dial(ctx, "/!ticker@arr")
 }
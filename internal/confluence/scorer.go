@@ -0,0 +1,611 @@
+// Package confluence scores each symbol by how far independent inputs agree
+// on a direction: recent daily and weekly pivot crossings, candlestick
+// patterns, 24h volume rank changes and 24h price change.
+package confluence
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"example.com/binance-pivot-monitor/internal/pattern"
+	"example.com/binance-pivot-monitor/internal/ranking"
+	signalpkg "example.com/binance-pivot-monitor/internal/signal"
+	"example.com/binance-pivot-monitor/internal/sse"
+	"example.com/binance-pivot-monitor/internal/ticker"
+)
+
+const (
+	// DefaultWindow is how long crossings and patterns count towards a score.
+	DefaultWindow = 24 * time.Hour
+	// DefaultThreshold is the absolute score that triggers an alert.
+	DefaultThreshold = 50
+	// DefaultInterval is how often all symbols are rescored for alerts.
+	DefaultInterval = time.Minute
+
+	// A 24h change of PriceScale percent, or a volume rank gain of RankScale
+	// places, counts as a full-strength component.
+	PriceScale = 10.0
+	RankScale  = 20.0
+)
+
+// Bias values.
+const (
+	BiasBullish = "bullish"
+	BiasBearish = "bearish"
+	BiasNeutral = "neutral"
+)
+
+// Component names.
+const (
+	ComponentDaily   = "daily"
+	ComponentWeekly  = "weekly"
+	ComponentPattern = "pattern"
+	ComponentVolume  = "volume_rank"
+	ComponentPrice   = "price"
+)
+
+// Weights sets how much each component counts. Zero drops a component.
+type Weights struct {
+	Daily   float64 `json:"daily"`
+	Weekly  float64 `json:"weekly"`
+	Pattern float64 `json:"pattern"`
+	Volume  float64 `json:"volume_rank"`
+	Price   float64 `json:"price"`
+}
+
+// DefaultWeights weighs weekly levels above daily ones and lets pivots and
+// patterns dominate the ticker-derived components.
+func DefaultWeights() Weights {
+	return Weights{Daily: 1, Weekly: 2, Pattern: 1.5, Volume: 1, Price: 1}
+}
+
+// ParseWeights reads weights from "daily=1,weekly=2,pattern=1.5,volume_rank=1,price=1".
+// Components not listed keep their default weight.
+func ParseWeights(s string) (Weights, error) {
+	w := DefaultWeights()
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return Weights{}, fmt.Errorf("weight %q: want name=value", part)
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || f < 0 {
+			return Weights{}, fmt.Errorf("weight %q: want a number >= 0", part)
+		}
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case ComponentDaily:
+			w.Daily = f
+		case ComponentWeekly:
+			w.Weekly = f
+		case ComponentPattern:
+			w.Pattern = f
+		case ComponentVolume:
+			w.Volume = f
+		case ComponentPrice:
+			w.Price = f
+		default:
+			return Weights{}, fmt.Errorf("unknown component %q", name)
+		}
+	}
+	if w.total() <= 0 {
+		return Weights{}, fmt.Errorf("at least one weight must be > 0")
+	}
+	return w, nil
+}
+
+func (w Weights) total() float64 {
+	return w.Daily + w.Weekly + w.Pattern + w.Volume + w.Price
+}
+
+// Component is one input to a score. Value is in [-1, 1], positive is
+// bullish; Points is its share of the final score.
+type Component struct {
+	Name   string  `json:"name"`
+	Value  float64 `json:"value"`
+	Weight float64 `json:"weight"`
+	Points float64 `json:"points"`
+	Detail string  `json:"detail,omitempty"`
+}
+
+// Score is a symbol's confluence score in [-100, 100] with the components
+// that produced it.
+type Score struct {
+	Symbol     string      `json:"symbol"`
+	Score      float64     `json:"score"`
+	Bias       string      `json:"bias"`
+	Components []Component `json:"components"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+}
+
+// Alert is published when a score reaches the threshold on either side.
+type Alert struct {
+	Score
+	Previous  float64 `json:"previous"`
+	Threshold float64 `json:"threshold"`
+}
+
+// Query filters and ranks scores. Empty fields match everything.
+type Query struct {
+	Bias     string  // bullish or bearish
+	MinScore float64 // minimum absolute score
+	Limit    int
+}
+
+// Scorer keeps recent pivot and pattern signals per symbol and scores them
+// together with ticker and ranking data.
+type Scorer struct {
+	Weights   Weights
+	Window    time.Duration
+	Threshold float64
+
+	tickers  *ticker.Store
+	rankings *ranking.Store
+	broker   *sse.Broker[Alert]
+	now      func() time.Time
+
+	mu       sync.Mutex
+	signals  map[string][]signalpkg.Signal
+	patterns map[string][]pattern.Signal
+	state    map[string]int     // -1, 0, 1: which side of the threshold
+	last     map[string]float64 // last checked score
+}
+
+// New creates a scorer. tickers and rankings may be nil, which zeroes the
+// price and volume rank components.
+func New(tickers *ticker.Store, rankings *ranking.Store) *Scorer {
+	return &Scorer{
+		Weights:   DefaultWeights(),
+		Window:    DefaultWindow,
+		Threshold: DefaultThreshold,
+		tickers:   tickers,
+		rankings:  rankings,
+		broker:    sse.NewBroker[Alert](),
+		now:       time.Now,
+		signals:   make(map[string][]signalpkg.Signal),
+		patterns:  make(map[string][]pattern.Signal),
+		state:     make(map[string]int),
+		last:      make(map[string]float64),
+	}
+}
+
+// Broker returns the broker alerts are published on.
+func (s *Scorer) Broker() *sse.Broker[Alert] {
+	return s.broker
+}
+
+// Seed loads past signals, e.g. from history at startup. Scores reached by
+// seeding do not raise alerts.
+func (s *Scorer) Seed(signals []signalpkg.Signal, patterns []pattern.Signal) {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sig := range signals {
+		s.addSignalLocked(sig, now)
+	}
+	for _, sig := range patterns {
+		s.addPatternLocked(sig, now)
+	}
+	cur, prev := s.snapshots()
+	for _, sym := range s.trackedLocked() {
+		sc := s.scoreLocked(sym, now, cur, prev)
+		s.state[sym] = s.side(sc.Score)
+		s.last[sym] = sc.Score
+	}
+}
+
+// AddSignal records a pivot signal and rescores its symbol. Only daily and
+// weekly crossings are used.
+func (s *Scorer) AddSignal(sig signalpkg.Signal) {
+	now := s.now()
+	s.mu.Lock()
+	ok := s.addSignalLocked(sig, now)
+	var alerts []Alert
+	if ok {
+		alerts = s.checkLocked([]string{sig.Symbol}, now)
+	}
+	s.mu.Unlock()
+	s.publish(alerts)
+}
+
+// AddPattern records a pattern signal and rescores its symbol.
+func (s *Scorer) AddPattern(sig pattern.Signal) {
+	now := s.now()
+	s.mu.Lock()
+	ok := s.addPatternLocked(sig, now)
+	var alerts []Alert
+	if ok {
+		alerts = s.checkLocked([]string{sig.Symbol}, now)
+	}
+	s.mu.Unlock()
+	s.publish(alerts)
+}
+
+func (s *Scorer) addSignalLocked(sig signalpkg.Signal, now time.Time) bool {
+	if sig.Period != signalpkg.PeriodDaily && sig.Period != signalpkg.PeriodWeekly {
+		return false
+	}
+	if sig.Kind != "" && sig.Kind != signalpkg.KindCross {
+		return false
+	}
+	if now.Sub(sig.TriggeredAt) > s.Window {
+		return false
+	}
+	s.signals[sig.Symbol] = append(s.signals[sig.Symbol], sig)
+	return true
+}
+
+func (s *Scorer) addPatternLocked(sig pattern.Signal, now time.Time) bool {
+	if sig.Direction == pattern.DirectionNeutral || now.Sub(sig.DetectedAt) > s.Window {
+		return false
+	}
+	s.patterns[sig.Symbol] = append(s.patterns[sig.Symbol], sig)
+	return true
+}
+
+// Check rescores every known symbol and publishes threshold crossings.
+func (s *Scorer) Check() {
+	now := s.now()
+	s.mu.Lock()
+	s.pruneLocked(now)
+	alerts := s.checkLocked(s.symbolsLocked(), now)
+	s.mu.Unlock()
+	s.publish(alerts)
+}
+
+// checkLocked scores symbols and returns an alert for each one that moved
+// onto a side of the threshold it was not on before.
+func (s *Scorer) checkLocked(symbols []string, now time.Time) []Alert {
+	cur, prev := s.snapshots()
+	var alerts []Alert
+	for _, sym := range symbols {
+		sc := s.scoreLocked(sym, now, cur, prev)
+		side := s.side(sc.Score)
+		if side != 0 && side != s.state[sym] {
+			alerts = append(alerts, Alert{Score: sc, Previous: s.last[sym], Threshold: s.Threshold})
+		}
+		if side == 0 && sc.Score == 0 {
+			delete(s.state, sym)
+			delete(s.last, sym)
+			continue
+		}
+		s.state[sym] = side
+		s.last[sym] = sc.Score
+	}
+	return alerts
+}
+
+func (s *Scorer) side(score float64) int {
+	if s.Threshold <= 0 {
+		return 0
+	}
+	switch {
+	case score >= s.Threshold:
+		return 1
+	case score <= -s.Threshold:
+		return -1
+	}
+	return 0
+}
+
+func (s *Scorer) publish(alerts []Alert) {
+	for _, a := range alerts {
+		s.broker.Publish(a)
+	}
+}
+
+// Score returns the current score for one symbol.
+func (s *Scorer) Score(symbol string) Score {
+	symbol = strings.ToUpper(symbol)
+	now := s.now()
+	cur, prev := s.snapshots()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.scoreLocked(symbol, now, cur, prev)
+}
+
+// Scores returns matching scores ranked by absolute score, strongest first.
+// Symbols scoring zero are left out.
+func (s *Scorer) Scores(q Query) []Score {
+	now := s.now()
+	cur, prev := s.snapshots()
+	s.mu.Lock()
+	s.pruneLocked(now)
+	var out []Score
+	for _, sym := range s.symbolsLocked() {
+		sc := s.scoreLocked(sym, now, cur, prev)
+		if sc.Score == 0 || math.Abs(sc.Score) < q.MinScore {
+			continue
+		}
+		if q.Bias != "" && sc.Bias != q.Bias {
+			continue
+		}
+		out = append(out, sc)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		ai, aj := math.Abs(out[i].Score), math.Abs(out[j].Score)
+		if ai != aj {
+			return ai > aj
+		}
+		return out[i].Symbol < out[j].Symbol
+	})
+	if q.Limit > 0 && len(out) > q.Limit {
+		out = out[:q.Limit]
+	}
+	return out
+}
+
+// Run feeds signals and patterns from the brokers into the scorer and
+// rescores all symbols every interval until ctx is done.
+func (s *Scorer) Run(ctx context.Context, signals *sse.Broker[signalpkg.Signal], patterns *sse.Broker[pattern.Signal], interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	var sigCh chan signalpkg.Signal
+	var patCh chan pattern.Signal
+	if signals != nil {
+		sigCh = signals.SubscribePinned("confluence", 256)
+		defer signals.Unsubscribe(sigCh)
+	}
+	if patterns != nil {
+		patCh = patterns.SubscribePinned("confluence", 256)
+		defer patterns.Unsubscribe(patCh)
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig, ok := <-sigCh:
+			if !ok {
+				sigCh = nil
+				continue
+			}
+			s.AddSignal(sig)
+		case sig, ok := <-patCh:
+			if !ok {
+				patCh = nil
+				continue
+			}
+			s.AddPattern(sig)
+		case <-t.C:
+			s.Check()
+		}
+	}
+}
+
+func (s *Scorer) snapshots() (cur, prev *ranking.Snapshot) {
+	if s.rankings == nil {
+		return nil, nil
+	}
+	return s.rankings.Latest(), s.rankings.Previous()
+}
+
+// pruneLocked drops signals older than the window.
+func (s *Scorer) pruneLocked(now time.Time) {
+	for sym, list := range s.signals {
+		kept := list[:0]
+		for _, sig := range list {
+			if now.Sub(sig.TriggeredAt) <= s.Window {
+				kept = append(kept, sig)
+			}
+		}
+		if len(kept) == 0 {
+			delete(s.signals, sym)
+		} else {
+			s.signals[sym] = kept
+		}
+	}
+	for sym, list := range s.patterns {
+		kept := list[:0]
+		for _, sig := range list {
+			if now.Sub(sig.DetectedAt) <= s.Window {
+				kept = append(kept, sig)
+			}
+		}
+		if len(kept) == 0 {
+			delete(s.patterns, sym)
+		} else {
+			s.patterns[sym] = kept
+		}
+	}
+}
+
+// trackedLocked returns symbols with recent signals or patterns, or with a
+// score above the threshold that may need to drop back.
+func (s *Scorer) trackedLocked() []string {
+	seen := make(map[string]bool)
+	for sym := range s.signals {
+		seen[sym] = true
+	}
+	for sym := range s.patterns {
+		seen[sym] = true
+	}
+	for sym := range s.last {
+		seen[sym] = true
+	}
+	out := make([]string, 0, len(seen))
+	for sym := range seen {
+		out = append(out, sym)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// symbolsLocked returns tracked symbols plus every symbol with a ticker.
+func (s *Scorer) symbolsLocked() []string {
+	out := s.trackedLocked()
+	if s.tickers == nil {
+		return out
+	}
+	seen := make(map[string]bool, len(out))
+	for _, sym := range out {
+		seen[sym] = true
+	}
+	for sym := range s.tickers.GetAll() {
+		if !seen[sym] {
+			out = append(out, sym)
+		}
+	}
+	return out
+}
+
+func (s *Scorer) scoreLocked(symbol string, now time.Time, cur, prev *ranking.Snapshot) Score {
+	var price float64
+	var hasPrice bool
+	if s.tickers != nil {
+		if t, ok := s.tickers.Get(symbol); ok {
+			price, hasPrice = t.PricePercent, true
+		}
+	}
+
+	daily, dailyDetail := s.pivotComponent(symbol, signalpkg.PeriodDaily, now)
+	weekly, weeklyDetail := s.pivotComponent(symbol, signalpkg.PeriodWeekly, now)
+	pat, patDetail := s.patternComponent(symbol, now)
+	vol, volDetail := volumeComponent(symbol, price, cur, prev)
+	var priceDetail string
+	if hasPrice {
+		priceDetail = fmt.Sprintf("24h %+.2f%%", price)
+	}
+
+	comps := []Component{
+		{Name: ComponentDaily, Value: daily, Weight: s.Weights.Daily, Detail: dailyDetail},
+		{Name: ComponentWeekly, Value: weekly, Weight: s.Weights.Weekly, Detail: weeklyDetail},
+		{Name: ComponentPattern, Value: pat, Weight: s.Weights.Pattern, Detail: patDetail},
+		{Name: ComponentVolume, Value: vol, Weight: s.Weights.Volume, Detail: volDetail},
+		{Name: ComponentPrice, Value: clamp(price / PriceScale), Weight: s.Weights.Price, Detail: priceDetail},
+	}
+
+	sc := Score{Symbol: symbol, Bias: BiasNeutral, UpdatedAt: now}
+	total := s.Weights.total()
+	for i := range comps {
+		if total > 0 {
+			points := comps[i].Value * comps[i].Weight / total * 100
+			sc.Score += points
+			comps[i].Points = round2(points)
+		}
+		comps[i].Value = round2(comps[i].Value)
+	}
+	sc.Score = round2(sc.Score)
+	sc.Components = comps
+	switch {
+	case sc.Score > 0:
+		sc.Bias = BiasBullish
+	case sc.Score < 0:
+		sc.Bias = BiasBearish
+	}
+	return sc
+}
+
+// decay weighs an event linearly from 1 when new down to 0 at the window's
+// end.
+func (s *Scorer) decay(at, now time.Time) float64 {
+	if s.Window <= 0 {
+		return 1
+	}
+	d := 1 - now.Sub(at).Seconds()/s.Window.Seconds()
+	if d < 0 {
+		return 0
+	}
+	return math.Min(d, 1)
+}
+
+// pivotComponent sums recent crossings of one period: +1 for up, -1 for
+// down, each decayed by age.
+func (s *Scorer) pivotComponent(symbol, period string, now time.Time) (float64, string) {
+	var sum float64
+	var parts []string
+	list := s.signals[symbol]
+	for i := len(list) - 1; i >= 0; i-- {
+		sig := list[i]
+		if sig.Period != period {
+			continue
+		}
+		d := s.decay(sig.TriggeredAt, now)
+		if d == 0 {
+			continue
+		}
+		sum += directionSign(sig.Direction) * d
+		if len(parts) < 3 {
+			parts = append(parts, fmt.Sprintf("%s %s %s ago", sig.Level, sig.Direction, ago(now.Sub(sig.TriggeredAt))))
+		}
+	}
+	return clamp(sum), strings.Join(parts, ", ")
+}
+
+// patternComponent sums recent patterns by direction and confidence.
+func (s *Scorer) patternComponent(symbol string, now time.Time) (float64, string) {
+	var sum float64
+	var parts []string
+	list := s.patterns[symbol]
+	for i := len(list) - 1; i >= 0; i-- {
+		sig := list[i]
+		d := s.decay(sig.DetectedAt, now)
+		if d == 0 {
+			continue
+		}
+		sum += directionSign(string(sig.Direction)) * float64(sig.Confidence) / 100 * d
+		if len(parts) < 3 {
+			parts = append(parts, fmt.Sprintf("%s %s %d%% %s ago", sig.Pattern, sig.Direction, sig.Confidence, ago(now.Sub(sig.DetectedAt))))
+		}
+	}
+	return clamp(sum), strings.Join(parts, ", ")
+}
+
+// volumeComponent scores a change in 24h volume rank between the last two
+// ranking snapshots. Volume flowing in confirms the current price move, so
+// the sign follows the 24h price change.
+func volumeComponent(symbol string, pricePercent float64, cur, prev *ranking.Snapshot) (float64, string) {
+	if cur == nil || prev == nil {
+		return 0, ""
+	}
+	c, ok1 := cur.Items[symbol]
+	p, ok2 := prev.Items[symbol]
+	if !ok1 || !ok2 {
+		return 0, ""
+	}
+	change := p.VolumeRank - c.VolumeRank // 正数表示排名上升
+	detail := fmt.Sprintf("volume rank %d -> %d", p.VolumeRank, c.VolumeRank)
+	if change <= 0 || pricePercent == 0 {
+		return 0, detail
+	}
+	return clamp(float64(change) / RankScale * math.Copysign(1, pricePercent)), detail
+}
+
+func directionSign(dir string) float64 {
+	switch strings.ToLower(dir) {
+	case "up", string(pattern.DirectionBullish):
+		return 1
+	case "down", string(pattern.DirectionBearish):
+		return -1
+	}
+	return 0
+}
+
+func ago(d time.Duration) string {
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%dh", int(d.Hours()))
+}
+
+func clamp(v float64) float64 {
+	return math.Max(-1, math.Min(1, v))
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
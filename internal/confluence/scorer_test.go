@@ -0,0 +1,131 @@
+package confluence
+
+import (
+	"testing"
+	"time"
+
+	"example.com/binance-pivot-monitor/internal/pattern"
+	"example.com/binance-pivot-monitor/internal/ranking"
+	signalpkg "example.com/binance-pivot-monitor/internal/signal"
+	"example.com/binance-pivot-monitor/internal/ticker"
+)
+
+func newTestScorer(t *testing.T, now time.Time) (*Scorer, *ticker.Store, *ranking.Store) {
+	t.Helper()
+	tickers := ticker.NewStore()
+	rankings := ranking.NewStore(t.TempDir(), 48*time.Hour)
+	s := New(tickers, rankings)
+	s.now = func() time.Time { return now }
+	return s, tickers, rankings
+}
+
+func TestScore_Components(t *testing.T) {
+	now := time.Now()
+	s, tickers, rankings := newTestScorer(t, now)
+
+	tickers.Update("BTCUSDT", 100000, 5, 1000, 1e9)
+	rankings.Add(&ranking.Snapshot{Timestamp: now.Add(-10 * time.Minute), Items: map[string]*ranking.SnapshotItem{
+		"BTCUSDT": {Symbol: "BTCUSDT", VolumeRank: 30},
+	}})
+	rankings.Add(&ranking.Snapshot{Timestamp: now.Add(-5 * time.Minute), Items: map[string]*ranking.SnapshotItem{
+		"BTCUSDT": {Symbol: "BTCUSDT", VolumeRank: 10},
+	}})
+
+	s.Seed([]signalpkg.Signal{
+		{Symbol: "BTCUSDT", Period: "1w", Level: "R1", Direction: "up", TriggeredAt: now},
+		{Symbol: "BTCUSDT", Period: "1d", Level: "R3", Direction: "up", TriggeredAt: now.Add(-12 * time.Hour)},
+		{Symbol: "BTCUSDT", Period: "1d", Level: "S1", Direction: "down", TriggeredAt: now.Add(-30 * time.Hour)}, // outside window
+		{Symbol: "BTCUSDT", Period: "4h", Level: "S1", Direction: "down", TriggeredAt: now},                      // not daily/weekly
+	}, []pattern.Signal{
+		{Symbol: "BTCUSDT", Pattern: pattern.PatternHammer, Direction: pattern.DirectionBullish, Confidence: 80, DetectedAt: now},
+	})
+
+	// (daily 0.5*1 + weekly 1*2 + pattern 0.8*1.5 + volume 1*1 + price 0.5*1) / 6.5
+	sc := s.Score("btcusdt")
+	if sc.Score != 80 || sc.Bias != BiasBullish {
+		t.Fatalf("score = %v %s, want 80 bullish: %+v", sc.Score, sc.Bias, sc.Components)
+	}
+	want := map[string]float64{ComponentDaily: 0.5, ComponentWeekly: 1, ComponentPattern: 0.8, ComponentVolume: 1, ComponentPrice: 0.5}
+	for _, c := range sc.Components {
+		if c.Value != want[c.Name] {
+			t.Errorf("%s = %v, want %v", c.Name, c.Value, want[c.Name])
+		}
+		if c.Name == ComponentWeekly && c.Detail != "R1 up 0m ago" {
+			t.Errorf("weekly detail = %q", c.Detail)
+		}
+	}
+
+	// 同样的成交额排名上升，价格下跌时视为看跌
+	tickers.Update("BTCUSDT", 90000, -5, 1000, 1e9)
+	for _, c := range s.Score("BTCUSDT").Components {
+		if c.Name == ComponentVolume && c.Value != -1 {
+			t.Errorf("volume with falling price = %v, want -1", c.Value)
+		}
+	}
+}
+
+func TestScorer_ThresholdAlerts(t *testing.T) {
+	now := time.Now()
+	s, _, _ := newTestScorer(t, now)
+	alerts := s.Broker().Subscribe(8)
+
+	s.AddSignal(signalpkg.Signal{Symbol: "ETHUSDT", Period: "1w", Level: "S2", Direction: "down", TriggeredAt: now})
+	select {
+	case a := <-alerts:
+		t.Fatalf("unexpected alert below threshold: %+v", a)
+	default:
+	}
+
+	s.AddPattern(pattern.Signal{Symbol: "ETHUSDT", Pattern: pattern.PatternShootingStar, Direction: pattern.DirectionBearish, Confidence: 100, DetectedAt: now})
+	select {
+	case a := <-alerts:
+		if a.Symbol != "ETHUSDT" || a.Bias != BiasBearish || a.Score.Score > -50 || a.Previous != -30.77 {
+			t.Errorf("alert = %+v", a)
+		}
+	default:
+		t.Fatal("expected an alert after crossing the threshold")
+	}
+
+	// 已在阈值之外，再次加强不重复告警
+	s.AddSignal(signalpkg.Signal{Symbol: "ETHUSDT", Period: "1d", Level: "S1", Direction: "down", TriggeredAt: now})
+	select {
+	case a := <-alerts:
+		t.Errorf("repeated alert: %+v", a)
+	default:
+	}
+}
+
+func TestScorer_Scores(t *testing.T) {
+	now := time.Now()
+	s, tickers, _ := newTestScorer(t, now)
+	tickers.Update("BTCUSDT", 1, 2, 1, 1)
+	tickers.Update("ETHUSDT", 1, -8, 1, 1)
+	tickers.Update("XRPUSDT", 1, 0, 1, 1)
+	s.AddSignal(signalpkg.Signal{Symbol: "SOLUSDT", Period: "1d", Level: "R1", Direction: "up", TriggeredAt: now})
+
+	got := s.Scores(Query{})
+	if len(got) != 3 || got[0].Symbol != "SOLUSDT" || got[1].Symbol != "ETHUSDT" || got[2].Symbol != "BTCUSDT" {
+		t.Fatalf("ranked = %+v", got)
+	}
+	if got := s.Scores(Query{Bias: BiasBullish, Limit: 1}); len(got) != 1 || got[0].Symbol != "SOLUSDT" {
+		t.Errorf("bullish = %+v", got)
+	}
+	if got := s.Scores(Query{MinScore: 15}); len(got) != 1 || got[0].Symbol != "SOLUSDT" {
+		t.Errorf("min score = %+v", got)
+	}
+}
+
+func TestParseWeights(t *testing.T) {
+	w, err := ParseWeights("weekly=3, price=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Weekly != 3 || w.Price != 0 || w.Daily != 1 {
+		t.Errorf("weights = %+v", w)
+	}
+	for _, bad := range []string{"weekly", "weekly=x", "daily=-1", "macd=1", "daily=0,weekly=0,pattern=0,volume_rank=0,price=0"} {
+		if _, err := ParseWeights(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"example.com/binance-pivot-monitor/internal/backtest"
+	"example.com/binance-pivot-monitor/internal/pivot"
+)
+
+const (
+	backtestMaxSymbols = 5
+	backtestMaxRange   = 31 * 24 * time.Hour
+	backtestTimeout    = 3 * time.Minute
+)
+
+// parseDateParam parses RFC3339 or YYYY-MM-DD (UTC).
+func parseDateParam(s string) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), true
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+func writeJSONError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+// handleBacktest runs a backtest over historical klines.
+// GET /api/backtest?symbols=BTCUSDT,ETHUSDT&from=2025-01-01&to=2025-01-08
+//
+//	&periods=1d,1w&method=camarilla&levels=R3,S3&horizons=15m,1h,4h&trades=true
+//
+// from defaults to 7 days before to, to defaults to now. At most 5 symbols
+// and 31 days per request; one backtest runs at a time.
+func (s *Server) handleBacktest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.BacktestSource == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "backtest not available")
+		return
+	}
+
+	q := r.URL.Query()
+	cfg := backtest.Config{Session: s.BacktestSession, Trades: q.Get("trades") == "true"}
+
+	for _, sym := range strings.Split(q.Get("symbols")+","+q.Get("symbol"), ",") {
+		if sym = strings.ToUpper(strings.TrimSpace(sym)); sym != "" {
+			cfg.Symbols = append(cfg.Symbols, sym)
+		}
+	}
+	if len(cfg.Symbols) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "symbols parameter required")
+		return
+	}
+	if len(cfg.Symbols) > backtestMaxSymbols {
+		writeJSONError(w, http.StatusBadRequest, "too many symbols (max 5)")
+		return
+	}
+
+	cfg.To = time.Now().UTC()
+	if v := q.Get("to"); v != "" {
+		t, ok := parseDateParam(v)
+		if !ok {
+			writeJSONError(w, http.StatusBadRequest, "invalid to parameter")
+			return
+		}
+		cfg.To = t
+	}
+	cfg.From = cfg.To.Add(-7 * 24 * time.Hour)
+	if v := q.Get("from"); v != "" {
+		t, ok := parseDateParam(v)
+		if !ok {
+			writeJSONError(w, http.StatusBadRequest, "invalid from parameter")
+			return
+		}
+		cfg.From = t
+	}
+	if !cfg.To.After(cfg.From) || cfg.To.Sub(cfg.From) > backtestMaxRange {
+		writeJSONError(w, http.StatusBadRequest, "invalid range (from < to, max 31 days)")
+		return
+	}
+
+	var err error
+	if v := q.Get("periods"); v != "" {
+		if cfg.Periods, err = pivot.ParsePeriods(v); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if v := q.Get("method"); v != "" {
+		m, ok := pivot.ParseMethod(v)
+		if !ok {
+			writeJSONError(w, http.StatusBadRequest, "unknown pivot method")
+			return
+		}
+		cfg.Method = m
+	}
+	if cfg.Levels, err = backtest.ParseLevels(q.Get("levels")); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if cfg.Horizons, err = backtest.ParseHorizons(q.Get("horizons")); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !s.backtestMu.TryLock() {
+		writeJSONError(w, http.StatusTooManyRequests, "backtest already running")
+		return
+	}
+	defer s.backtestMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(r.Context(), backtestTimeout)
+	defer cancel()
+
+	rep, err := backtest.Run(ctx, s.BacktestSource, cfg)
+	if err != nil {
+		writeJSONError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(rep)
+}
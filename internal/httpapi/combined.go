@@ -0,0 +1,75 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	signalpkg "example.com/binance-pivot-monitor/internal/signal"
+)
+
+// handleCombined returns combined pivot+pattern signals, newest first.
+// GET /api/combined?symbol=BTCUSDT&correlation=strong,moderate&min_correlation=moderate
+//
+//	&direction=up&since=2025-01-06T00:00:00Z&limit=100
+func (s *Server) handleCombined(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.CombinedHistory == nil {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte("[]"))
+		return
+	}
+
+	q := r.URL.Query()
+	opts := signalpkg.CombinedQuery{
+		Symbol:    strings.ToUpper(q.Get("symbol")),
+		Direction: q.Get("direction"),
+		Limit:     100,
+	}
+	if v, err := strconv.Atoi(q.Get("limit")); err == nil && v > 0 {
+		opts.Limit = v
+	}
+	if v := q.Get("correlation"); v != "" {
+		for _, part := range strings.Split(v, ",") {
+			c, err := signalpkg.ParseCorrelation(part)
+			if err != nil {
+				writeJSONError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			opts.Correlations = append(opts.Correlations, c)
+		}
+	}
+	if v := q.Get("min_correlation"); v != "" {
+		c, err := signalpkg.ParseCorrelation(v)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		opts.MinCorrelation = c
+	}
+	if v := q.Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid since, want RFC3339")
+			return
+		}
+		opts.Since = t
+	}
+
+	res := s.CombinedHistory.Query(opts)
+	if res == nil {
+		res = []signalpkg.CombinedSignal{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,67 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"example.com/binance-pivot-monitor/internal/confluence"
+)
+
+// handleConfluence returns per-symbol confluence scores ranked by absolute
+// score, or a single symbol's score with ?symbol=.
+// GET /api/confluence?bias=bullish&min_score=30&limit=50
+func (s *Server) handleConfluence(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.Confluence == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "confluence scoring not enabled")
+		return
+	}
+
+	q := r.URL.Query()
+	if sym := strings.ToUpper(strings.TrimSpace(q.Get("symbol"))); sym != "" {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(s.Confluence.Score(sym))
+		return
+	}
+
+	opts := confluence.Query{Limit: 50}
+	switch bias := strings.ToLower(q.Get("bias")); bias {
+	case "", confluence.BiasBullish, confluence.BiasBearish:
+		opts.Bias = bias
+	default:
+		writeJSONError(w, http.StatusBadRequest, "invalid bias, want bullish or bearish")
+		return
+	}
+	if v := q.Get("min_score"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 || f > 100 {
+			writeJSONError(w, http.StatusBadRequest, "invalid min_score, want 0-100")
+			return
+		}
+		opts.MinScore = f
+	}
+	if v, err := strconv.Atoi(q.Get("limit")); err == nil && v > 0 {
+		opts.Limit = v
+	}
+
+	res := s.Confluence.Scores(opts)
+	if res == nil {
+		res = []confluence.Score{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"threshold": s.Confluence.Threshold,
+		"weights":   s.Confluence.Weights,
+		"items":     res,
+	})
+}
@@ -0,0 +1,187 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"example.com/binance-pivot-monitor/internal/confluence"
+	"example.com/binance-pivot-monitor/internal/pattern"
+	"example.com/binance-pivot-monitor/internal/pivot"
+	signalpkg "example.com/binance-pivot-monitor/internal/signal"
+	"example.com/binance-pivot-monitor/internal/sse"
+	"example.com/binance-pivot-monitor/internal/subscription"
+	"example.com/binance-pivot-monitor/internal/ticker"
+)
+
+// RunEvents forwards signals, patterns, combined signals, confluence alerts,
+// new listings and ticker batches into s.Events until ctx is done. Everything except ticker batches is kept
+// for replay.
+func (s *Server) RunEvents(ctx context.Context) {
+	if s.Events == nil {
+		return
+	}
+
+	var signalCh chan signalpkg.Signal
+	if s.SignalBroker != nil {
+		signalCh = s.SignalBroker.SubscribePinned("sse-hub", 256)
+		defer s.SignalBroker.Unsubscribe(signalCh)
+	}
+	var patternCh chan pattern.Signal
+	if s.PatternBroker != nil {
+		patternCh = s.PatternBroker.SubscribePinned("sse-hub", 256)
+		defer s.PatternBroker.Unsubscribe(patternCh)
+	}
+	var combinedCh chan signalpkg.CombinedSignal
+	if s.CombinedBroker != nil {
+		combinedCh = s.CombinedBroker.SubscribePinned("sse-hub", 256)
+		defer s.CombinedBroker.Unsubscribe(combinedCh)
+	}
+	var confluenceCh chan confluence.Alert
+	if s.Confluence != nil {
+		confluenceCh = s.Confluence.Broker().SubscribePinned("sse-hub", 256)
+		defer s.Confluence.Broker().Unsubscribe(confluenceCh)
+	}
+	var listingCh chan pivot.Listing
+	if s.Listings != nil {
+		listingCh = s.Listings.Broker().SubscribePinned("sse-hub", 64)
+		defer s.Listings.Broker().Unsubscribe(listingCh)
+	}
+	var tickerCh chan ticker.TickerBatch
+	if s.TickerMonitor != nil {
+		tickerCh = s.TickerMonitor.Broker().SubscribePinned("sse-hub", 64)
+		defer s.TickerMonitor.Unsubscribe(tickerCh)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig, ok := <-signalCh:
+			if !ok {
+				signalCh = nil
+				continue
+			}
+			_, _ = s.Events.Publish("signal", sig, true)
+		case pat, ok := <-patternCh:
+			if !ok {
+				patternCh = nil
+				continue
+			}
+			_, _ = s.Events.Publish("pattern", pat, true)
+		case cs, ok := <-combinedCh:
+			if !ok {
+				combinedCh = nil
+				continue
+			}
+			_, _ = s.Events.Publish("combined", cs, true)
+		case a, ok := <-confluenceCh:
+			if !ok {
+				confluenceCh = nil
+				continue
+			}
+			_, _ = s.Events.Publish("confluence", a, true)
+		case l, ok := <-listingCh:
+			if !ok {
+				listingCh = nil
+				continue
+			}
+			_, _ = s.Events.Publish("listing", l, true)
+		case batch, ok := <-tickerCh:
+			if !ok {
+				tickerCh = nil
+				continue
+			}
+			_, _ = s.Events.Publish("ticker", batch, false)
+		}
+	}
+}
+
+// handleSSEStats returns delivery and drop counters for every broker, so a
+// dashboard that is losing events shows up as a subscriber with drops.
+// GET /api/sse/stats
+func (s *Server) handleSSEStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	resp := make(map[string]sse.Stats)
+	if s.Events != nil {
+		resp["clients"] = s.Events.Stats()
+	}
+	if s.SignalBroker != nil {
+		resp["signals"] = s.SignalBroker.Stats()
+	}
+	if s.PatternBroker != nil {
+		resp["patterns"] = s.PatternBroker.Stats()
+	}
+	if s.CombinedBroker != nil {
+		resp["combined"] = s.CombinedBroker.Stats()
+	}
+	if s.Confluence != nil {
+		resp["confluence"] = s.Confluence.Broker().Stats()
+	}
+	if s.Listings != nil {
+		resp["listings"] = s.Listings.Broker().Stats()
+	}
+	if s.TickerMonitor != nil {
+		resp["tickers"] = s.TickerMonitor.Broker().Stats()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// parseSince reads the last seen event ID from ?since= or the Last-Event-ID
+// header. 0 means no replay.
+func parseSince(r *http.Request) (uint64, error) {
+	v := r.URL.Query().Get("since")
+	if v == "" {
+		v = r.Header.Get("Last-Event-ID")
+	}
+	if v == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, errors.New("invalid Last-Event-ID")
+	}
+	return id, nil
+}
+
+// filterEvent applies the subscription filter to ev and returns the payload
+// to send. Ticker batches are narrowed to the matching symbols.
+func filterEvent(ev sse.Event, filter *subscription.Matcher) ([]byte, bool) {
+	switch v := ev.Value.(type) {
+	case signalpkg.Signal:
+		return ev.Data, filter.Signal(v)
+	case pattern.Signal:
+		return ev.Data, filter.Pattern(v)
+	case signalpkg.CombinedSignal:
+		return ev.Data, filter.Combined(v)
+	case confluence.Alert:
+		return ev.Data, filter.Confluence(v)
+	case pivot.Listing:
+		return ev.Data, filter.Listing(v)
+	case ticker.TickerBatch:
+		batch, ok := filter.Tickers(v)
+		if !ok {
+			return nil, false
+		}
+		if len(batch.Tickers) == len(v.Tickers) {
+			return ev.Data, true
+		}
+		b, err := json.Marshal(batch)
+		if err != nil {
+			return nil, false
+		}
+		return b, true
+	}
+	return ev.Data, true
+}
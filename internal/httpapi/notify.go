@@ -0,0 +1,58 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"example.com/binance-pivot-monitor/internal/notify"
+)
+
+// handleNotify returns webhook delivery counters and the most recent dead
+// letters.
+// GET /api/notify?limit=50
+func (s *Server) handleNotify(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.Notifier == nil {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"enabled":false}`))
+		return
+	}
+
+	limit := 50
+	if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v >= 0 {
+		limit = v
+	}
+
+	dls := s.Notifier.DeadLetters()
+	total := len(dls)
+	if len(dls) > limit {
+		dls = dls[len(dls)-limit:]
+	}
+	// 死信中的 URL 可能带有令牌，不对外返回
+	for i := range dls {
+		dls[i].URL = ""
+	}
+
+	resp := struct {
+		Enabled         bool                   `json:"enabled"`
+		Endpoints       []notify.EndpointStats `json:"endpoints"`
+		DeadLetterCount int                    `json:"dead_letter_count"`
+		DeadLetters     []notify.DeadLetter    `json:"dead_letters"`
+	}{
+		Enabled:         true,
+		Endpoints:       s.Notifier.Stats(),
+		DeadLetterCount: total,
+		DeadLetters:     dls,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
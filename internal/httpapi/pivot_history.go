@@ -0,0 +1,189 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"example.com/binance-pivot-monitor/internal/pivot"
+)
+
+// PivotSessionLevels is one symbol's levels for one session.
+type PivotSessionLevels struct {
+	Period       pivot.Period `json:"period"`
+	Symbol       string       `json:"symbol"`
+	Method       pivot.Method `json:"method"`
+	SessionStart *time.Time   `json:"session_start,omitempty"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+	Levels       pivot.Levels `json:"levels"`
+}
+
+// PivotLevelChange is how one level moved between two sessions.
+type PivotLevelChange struct {
+	Level     string  `json:"level"`
+	From      float64 `json:"from"`
+	To        float64 `json:"to"`
+	Change    float64 `json:"change"`
+	ChangePct float64 `json:"change_pct"`
+}
+
+// PivotDiffResponse compares a symbol's levels between two sessions.
+type PivotDiffResponse struct {
+	Period  pivot.Period       `json:"period"`
+	Symbol  string             `json:"symbol"`
+	Method  pivot.Method       `json:"method"`
+	From    PivotSessionLevels `json:"from"`
+	To      PivotSessionLevels `json:"to"`
+	Changes []PivotLevelChange `json:"changes"`
+}
+
+// handlePivotHistory serves archived levels and session diffs.
+// GET /api/pivots/{period}/{symbol}?date=2025-01-06&method=classic
+// GET /api/pivots/{period}/{symbol}/diff?from=2025-01-05&to=2025-01-06
+func (s *Server) handlePivotHistory(w http.ResponseWriter, r *http.Request, parts []string) {
+	period, err := pivot.ParsePeriod(parts[0])
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "unknown pivot period")
+		return
+	}
+	symbol := strings.ToUpper(strings.TrimSpace(parts[1]))
+	if symbol == "" {
+		writeJSONError(w, http.StatusBadRequest, "symbol parameter required")
+		return
+	}
+	diff := len(parts) == 3
+	if diff && parts[2] != "diff" {
+		writeJSONError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	q := r.URL.Query()
+	var method pivot.Method
+	if v := q.Get("method"); v != "" {
+		m, ok := pivot.ParseMethod(v)
+		if !ok {
+			writeJSONError(w, http.StatusBadRequest, "unknown pivot method")
+			return
+		}
+		method = m
+	}
+
+	if !diff {
+		snap, status, msg := s.pivotSnapshotAt(period, q.Get("date"))
+		if snap == nil {
+			writeJSONError(w, status, msg)
+			return
+		}
+		lv, ok := sessionLevels(snap, symbol, method)
+		if !ok {
+			writeJSONError(w, http.StatusNotFound, "no pivot data found for symbol")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(lv)
+		return
+	}
+
+	to, status, msg := s.pivotSnapshotAt(period, q.Get("to"))
+	if to == nil {
+		writeJSONError(w, status, msg)
+		return
+	}
+	var from *pivot.Snapshot
+	if v := q.Get("from"); v != "" {
+		from, status, msg = s.pivotSnapshotAt(period, v)
+	} else if s.PivotArchive == nil || to.SessionStart.IsZero() {
+		status, msg = http.StatusNotFound, "no previous session archived"
+	} else {
+		// 默认与上一个会话比较
+		from, err = s.PivotArchive.Before(period, to.SessionStart)
+		if err != nil {
+			from, status, msg = nil, http.StatusNotFound, "no previous session archived"
+		}
+	}
+	if from == nil {
+		writeJSONError(w, status, msg)
+		return
+	}
+
+	fromLv, ok := sessionLevels(from, symbol, method)
+	toLv, ok2 := sessionLevels(to, symbol, method)
+	if !ok || !ok2 {
+		writeJSONError(w, http.StatusNotFound, "symbol missing from one of the sessions")
+		return
+	}
+	resp := PivotDiffResponse{Period: period, Symbol: symbol, Method: toLv.Method, From: fromLv, To: toLv}
+	for _, name := range pivot.LevelNames {
+		a, okA := fromLv.Levels.Price(name)
+		b, okB := toLv.Levels.Price(name)
+		// 方法未定义的点位为 0，两边都没有时不列出
+		if (!okA || a == 0) && (!okB || b == 0) {
+			continue
+		}
+		c := PivotLevelChange{Level: name, From: a, To: b, Change: b - a}
+		if a != 0 {
+			c.ChangePct = (b - a) / a * 100
+		}
+		resp.Changes = append(resp.Changes, c)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// pivotSnapshotAt returns the live snapshot for an empty date, or the
+// archived snapshot in effect at date.
+func (s *Server) pivotSnapshotAt(period pivot.Period, date string) (*pivot.Snapshot, int, string) {
+	if date == "" {
+		if s.PivotStore == nil {
+			return nil, http.StatusServiceUnavailable, "pivot store not available"
+		}
+		snap, err := s.PivotStore.Snapshot(period)
+		if err != nil || snap == nil {
+			return nil, http.StatusNotFound, "no pivot data for period"
+		}
+		return snap, 0, ""
+	}
+	if s.PivotArchive == nil {
+		return nil, http.StatusServiceUnavailable, "pivot archive not available"
+	}
+	session := s.PivotSession
+	if session.Location == nil {
+		session = pivot.DefaultSession()
+	}
+	t, err := session.ParseDate(date)
+	if err != nil {
+		return nil, http.StatusBadRequest, err.Error()
+	}
+	snap, err := s.PivotArchive.At(period, t)
+	if errors.Is(err, pivot.ErrNotArchived) {
+		return nil, http.StatusNotFound, "no archived pivots for date"
+	}
+	if err != nil {
+		return nil, http.StatusInternalServerError, err.Error()
+	}
+	return snap, 0, ""
+}
+
+func sessionLevels(snap *pivot.Snapshot, symbol string, method pivot.Method) (PivotSessionLevels, bool) {
+	lv, ok := snap.Levels(method, symbol)
+	if !ok {
+		return PivotSessionLevels{}, false
+	}
+	if method == "" {
+		method = snap.PrimaryMethod()
+	}
+	out := PivotSessionLevels{
+		Period:    snap.Period,
+		Symbol:    symbol,
+		Method:    method,
+		UpdatedAt: snap.UpdatedAt,
+		Levels:    lv,
+	}
+	if !snap.SessionStart.IsZero() {
+		t := snap.SessionStart
+		out.SessionStart = &t
+	}
+	return out, true
+}
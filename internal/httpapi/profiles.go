@@ -0,0 +1,120 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"example.com/binance-pivot-monitor/internal/subscription"
+)
+
+const maxProfileBody = 64 << 10
+
+// handleProfiles manages saved subscription profiles.
+// GET    /api/profiles
+// GET    /api/profiles/{name}
+// PUT    /api/profiles/{name}   body: subscription filter JSON
+// DELETE /api/profiles/{name}
+func (s *Server) handleProfiles(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if s.Profiles == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "profiles are not enabled")
+		return
+	}
+
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/profiles"), "/")
+	if name == "" {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(s.Profiles.List())
+		return
+	}
+	if !subscription.ValidName(name) {
+		writeJSONError(w, http.StatusBadRequest, "invalid profile name")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		p, ok := s.Profiles.Get(name)
+		if !ok {
+			writeJSONError(w, http.StatusNotFound, "profile not found")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(p)
+
+	case http.MethodPut:
+		var f subscription.Filter
+		dec := json.NewDecoder(io.LimitReader(r.Body, maxProfileBody))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&f); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid filter: "+err.Error())
+			return
+		}
+		p, err := s.Profiles.Put(name, f)
+		if errors.Is(err, subscription.ErrTooManyProfiles) {
+			writeJSONError(w, http.StatusInsufficientStorage, err.Error())
+			return
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(p)
+
+	case http.MethodDelete:
+		ok, err := s.Profiles.Delete(name)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if !ok {
+			writeJSONError(w, http.StatusNotFound, "profile not found")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// subscriptionFilter builds the stream filter for a request: the saved
+// profile named by ?profile=, overridden by any filter query parameters.
+func (s *Server) subscriptionFilter(r *http.Request) (subscription.Filter, int, error) {
+	q := r.URL.Query()
+	f, err := subscription.ParseQuery(q)
+	if err != nil {
+		return subscription.Filter{}, http.StatusBadRequest, err
+	}
+	if name := q.Get("profile"); name != "" {
+		if s.Profiles == nil {
+			return subscription.Filter{}, http.StatusServiceUnavailable, errors.New("profiles are not enabled")
+		}
+		p, ok := s.Profiles.Get(name)
+		if !ok {
+			return subscription.Filter{}, http.StatusNotFound, errors.New("profile not found")
+		}
+		f = p.Filter.Override(f)
+	}
+	return f, 0, nil
+}
+
+// compileFilter compiles f with the ticker store's volume ranks.
+func (s *Server) compileFilter(f subscription.Filter) *subscription.Matcher {
+	var rank subscription.RankFunc
+	if s.TickerStore != nil {
+		rank = s.TickerStore.VolumeRank
+	}
+	return f.Compile(rank)
+}
@@ -7,16 +7,23 @@ import (
 	"io/fs"
 	"net/http"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"example.com/binance-pivot-monitor/internal/backtest"
+	"example.com/binance-pivot-monitor/internal/binance"
+	"example.com/binance-pivot-monitor/internal/confluence"
 	"example.com/binance-pivot-monitor/internal/kline"
+	"example.com/binance-pivot-monitor/internal/notify"
 	"example.com/binance-pivot-monitor/internal/pattern"
 	"example.com/binance-pivot-monitor/internal/pivot"
 	"example.com/binance-pivot-monitor/internal/ranking"
 	signalpkg "example.com/binance-pivot-monitor/internal/signal"
 	"example.com/binance-pivot-monitor/internal/sse"
+	"example.com/binance-pivot-monitor/internal/subscription"
 	"example.com/binance-pivot-monitor/internal/ticker"
 )
 
@@ -26,24 +33,53 @@ var staticFS embed.FS
 type Server struct {
 	SignalBroker   *sse.Broker[signalpkg.Signal]
 	History        *signalpkg.History
+	Outcomes       *signalpkg.OutcomeTracker
 	AllowedOrigins []string
 	PivotStatus    PivotStatusProvider
 	PivotStore     *pivot.Store
+	PivotArchive   *pivot.Archive
+	PivotSession   pivot.Session
+	Listings       *pivot.ListingWatcher
 	TickerStore    *ticker.Store
 	TickerMonitor  *ticker.Monitor
+	WSEndpoints    *binance.WSEndpoints
+	RESTLimiter    *binance.Limiter
 
 	// Pattern recognition
 	PatternBroker  *sse.Broker[pattern.Signal]
 	PatternHistory *pattern.History
+	PatternStats   *pattern.LiveStats
 	KlineStore     *kline.Store
+	KlineStores    *kline.MultiStore // all timeframes; KlineStore is its primary
 	SignalCombiner *signalpkg.Combiner
 
+	// Combined pivot+pattern signals
+	CombinedBroker  *sse.Broker[signalpkg.CombinedSignal]
+	CombinedHistory *signalpkg.CombinedHistory
+
 	// Ranking monitor
 	RankingStore *ranking.Store
+
+	// Per-symbol confluence scores
+	Confluence *confluence.Scorer
+
+	// Webhook notifications
+	Notifier *notify.Dispatcher
+
+	// Saved stream subscription profiles
+	Profiles *subscription.ProfileStore
+
+	// Events numbers and fans out all SSE events; see RunEvents.
+	Events *sse.Hub
+
+	// Backtesting
+	BacktestSource  backtest.KlineSource
+	BacktestSession pivot.Session
+	backtestMu      sync.Mutex
 }
 
 func New(signalBroker *sse.Broker[signalpkg.Signal], history *signalpkg.History, allowedOrigins []string) *Server {
-	return &Server{SignalBroker: signalBroker, History: history, AllowedOrigins: allowedOrigins}
+	return &Server{SignalBroker: signalBroker, History: history, AllowedOrigins: allowedOrigins, Events: sse.NewHub(sse.DefaultReplaySize)}
 }
 
 type PivotStatusProvider interface {
@@ -55,14 +91,23 @@ func (s *Server) Handler() http.Handler {
 	mux.HandleFunc("/", s.handleDashboard)
 	mux.HandleFunc("/healthz", s.handleHealth)
 	mux.HandleFunc("/api/sse", s.handleSSE)
+	mux.HandleFunc("/api/sse/stats", s.handleSSEStats)
+	mux.HandleFunc("/api/ws", s.handleWS)
+	mux.HandleFunc("/api/combined", s.handleCombined)
+	mux.HandleFunc("/api/confluence", s.handleConfluence)
 	mux.HandleFunc("/api/history", s.handleHistory)
 	mux.HandleFunc("/api/pivot-status", s.handlePivotStatus)
 	mux.HandleFunc("/api/pivots/", s.handlePivots)
 	mux.HandleFunc("/api/tickers", s.handleTickers)
 	mux.HandleFunc("/api/patterns", s.handlePatterns)
+	mux.HandleFunc("/api/patterns/stats", s.handlePatternStats)
 	mux.HandleFunc("/api/klines", s.handleKlines)
 	mux.HandleFunc("/api/klines/stats", s.handleKlineStats)
 	mux.HandleFunc("/api/runtime", s.handleRuntime)
+	mux.HandleFunc("/api/backtest", s.handleBacktest)
+	mux.HandleFunc("/api/notify", s.handleNotify)
+	mux.HandleFunc("/api/profiles", s.handleProfiles)
+	mux.HandleFunc("/api/profiles/", s.handleProfiles)
 
 	// Ranking API
 	mux.HandleFunc("/api/ranking/current", s.handleRankingCurrent)
@@ -109,7 +154,7 @@ func (s *Server) handleTickers(w http.ResponseWriter, r *http.Request) {
 }
 
 // handlePatterns returns pattern signal history.
-// GET /api/patterns?limit=100&symbol=BTCUSDT&pattern=hammer&direction=bullish
+// GET /api/patterns?limit=100&symbol=BTCUSDT&pattern=hammer&direction=bullish&timeframe=1h
 func (s *Server) handlePatterns(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodOptions {
 		w.WriteHeader(http.StatusNoContent)
@@ -143,6 +188,7 @@ func (s *Server) handlePatterns(w http.ResponseWriter, r *http.Request) {
 		Symbol:    symbol,
 		Pattern:   pattern.PatternType(patternType),
 		Direction: pattern.Direction(direction),
+		Timeframe: q.Get("timeframe"),
 		Limit:     limit,
 	}
 
@@ -151,8 +197,117 @@ func (s *Server) handlePatterns(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(res)
 }
 
+// PatternStatsEntry compares the static and live statistics of one pattern.
+type PatternStatsEntry struct {
+	Pattern   pattern.PatternType `json:"pattern"`
+	PatternCN string              `json:"pattern_cn"`
+	Static    StaticPatternStats  `json:"static"`
+	Live      *pattern.LiveStat   `json:"live,omitempty"`
+}
+
+// StaticPatternStats is the JSON form of pattern.PatternStats.
+type StaticPatternStats struct {
+	UpPercent      int    `json:"up_percent"`
+	DownPercent    int    `json:"down_percent"`
+	EfficiencyRank string `json:"efficiency_rank"`
+	StatsSource    string `json:"stats_source"`
+	IsEstimated    bool   `json:"is_estimated"`
+}
+
+// handlePatternStats returns static and live pattern statistics side by side.
+// GET /api/patterns/stats
+func (s *Server) handlePatternStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	live := map[pattern.PatternType]pattern.LiveStat{}
+	var updatedAt time.Time
+	var cfg pattern.LiveStatsConfig
+	if s.PatternStats != nil {
+		stats, at := s.PatternStats.Stats()
+		for _, st := range stats {
+			live[st.Pattern] = st
+		}
+		updatedAt = at
+		cfg = s.PatternStats.Config()
+	}
+
+	entries := make([]PatternStatsEntry, 0, len(pattern.PatternStatsMap))
+	for pt, st := range pattern.PatternStatsMap {
+		e := PatternStatsEntry{
+			Pattern:   pt,
+			PatternCN: pattern.PatternNames[pt],
+			Static: StaticPatternStats{
+				UpPercent:      st.UpPercent,
+				DownPercent:    st.DownPercent,
+				EfficiencyRank: st.EfficiencyRank,
+				StatsSource:    st.StatsSource,
+				IsEstimated:    st.IsEstimated,
+			},
+		}
+		if ls, ok := live[pt]; ok {
+			e.Live = &ls
+		}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		ni, nj := 0, 0
+		if entries[i].Live != nil {
+			ni = entries[i].Live.Samples
+		}
+		if entries[j].Live != nil {
+			nj = entries[j].Live.Samples
+		}
+		if ni != nj {
+			return ni > nj
+		}
+		return entries[i].Pattern < entries[j].Pattern
+	})
+
+	resp := struct {
+		Enabled     bool                `json:"enabled"`
+		UpdatedAt   time.Time           `json:"updated_at"`
+		Horizon     int                 `json:"horizon_klines"`
+		PriorWeight float64             `json:"prior_weight"`
+		MinSamples  int                 `json:"min_samples"`
+		Patterns    []PatternStatsEntry `json:"patterns"`
+	}{
+		Enabled:     s.PatternStats != nil,
+		UpdatedAt:   updatedAt,
+		Horizon:     cfg.Horizon,
+		PriorWeight: cfg.PriorWeight,
+		MinSamples:  cfg.MinSamples,
+		Patterns:    entries,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// klineStore returns the store for a timeframe; empty means the primary one.
+func (s *Server) klineStore(timeframe string) (*kline.Store, bool) {
+	if s.KlineStores != nil {
+		return s.KlineStores.Get(timeframe)
+	}
+	if s.KlineStore == nil {
+		return nil, false
+	}
+	if timeframe != "" {
+		d, err := kline.ParseInterval(timeframe)
+		if err != nil || d != s.KlineStore.Interval() {
+			return nil, false
+		}
+	}
+	return s.KlineStore, true
+}
+
 // handleKlines returns kline data for a symbol (for debugging).
-// GET /api/klines?symbol=BTCUSDT
+// GET /api/klines?symbol=BTCUSDT&timeframe=1h
 func (s *Server) handleKlines(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodOptions {
 		w.WriteHeader(http.StatusNoContent)
@@ -177,7 +332,13 @@ func (s *Server) handleKlines(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	klines, ok := s.KlineStore.GetAllKlines(symbol)
+	store, ok := s.klineStore(q.Get("timeframe"))
+	if !ok {
+		writeJSONError(w, http.StatusBadRequest, "unknown timeframe")
+		return
+	}
+
+	klines, ok := store.GetAllKlines(symbol)
 	if !ok {
 		w.Header().Set("Content-Type", "application/json")
 		_, _ = w.Write([]byte("[]"))
@@ -189,7 +350,7 @@ func (s *Server) handleKlines(w http.ResponseWriter, r *http.Request) {
 }
 
 // handleKlineStats returns statistics about kline data in memory.
-// GET /api/klines/stats
+// GET /api/klines/stats?timeframe=1h
 func (s *Server) handleKlineStats(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodOptions {
 		w.WriteHeader(http.StatusNoContent)
@@ -206,7 +367,15 @@ func (s *Server) handleKlineStats(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	stats := s.KlineStore.Stats()
+	store, ok := s.klineStore(r.URL.Query().Get("timeframe"))
+	if !ok {
+		writeJSONError(w, http.StatusBadRequest, "unknown timeframe")
+		return
+	}
+	stats := store.Stats()
+	if s.KlineStores != nil {
+		stats.Timeframes = s.KlineStores.Timeframes()
+	}
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(stats)
 }
@@ -224,6 +393,9 @@ type RuntimeStats struct {
 	Uptime         string  `json:"uptime"`
 	SSESubscribers int     `json:"sse_subscribers"`
 	Version        string  `json:"version"`
+
+	WSEndpoint *binance.WSEndpointStatus `json:"ws_endpoint,omitempty"`
+	REST       *binance.LimiterStats     `json:"rest,omitempty"`
 }
 
 // Version can be set at build time via -ldflags
@@ -268,6 +440,14 @@ func (s *Server) handleRuntime(w http.ResponseWriter, r *http.Request) {
 	if s.SignalBroker != nil {
 		stats.SSESubscribers = s.SignalBroker.SubscriberCount()
 	}
+	if s.WSEndpoints != nil {
+		st := s.WSEndpoints.Status()
+		stats.WSEndpoint = &st
+	}
+	if s.RESTLimiter != nil {
+		st := s.RESTLimiter.Stats()
+		stats.REST = &st
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(stats)
@@ -295,13 +475,18 @@ func (s *Server) handlePivotStatus(w http.ResponseWriter, r *http.Request) {
 
 // PivotResponse is the response for /api/pivots/{symbol}
 type PivotResponse struct {
-	Symbol string        `json:"symbol"`
-	Daily  *pivot.Levels `json:"daily,omitempty"`
-	Weekly *pivot.Levels `json:"weekly,omitempty"`
+	Symbol  string         `json:"symbol"`
+	Method  pivot.Method   `json:"method,omitempty"`
+	Methods []pivot.Method `json:"methods,omitempty"` // methods available in the daily snapshot
+	Daily   *pivot.Levels  `json:"daily,omitempty"`
+	Weekly  *pivot.Levels  `json:"weekly,omitempty"`
+	// Periods holds the levels of every registered period, keyed by period (1d, 1w, 1M, 4h, ...)
+	Periods map[pivot.Period]pivot.Levels `json:"periods,omitempty"`
 }
 
 // handlePivots returns pivot levels for a specific symbol.
-// GET /api/pivots/{symbol}?period=1d|1w (optional, returns both if omitted)
+// GET /api/pivots/{symbol}?period=1d|1w|1M|4h|... (optional, returns all registered periods if omitted)&method=camarilla|classic|... (optional, primary method if omitted)
+// GET /api/pivots/{period}/{symbol} and /api/pivots/{period}/{symbol}/diff are served by handlePivotHistory.
 func (s *Server) handlePivots(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodOptions {
 		w.WriteHeader(http.StatusNoContent)
@@ -320,6 +505,10 @@ func (s *Server) handlePivots(w http.ResponseWriter, r *http.Request) {
 
 	// Extract symbol from path: /api/pivots/{symbol}
 	path := strings.TrimPrefix(r.URL.Path, "/api/pivots/")
+	if parts := strings.Split(path, "/"); (len(parts) == 2 || len(parts) == 3) && parts[1] != "" {
+		s.handlePivotHistory(w, r, parts)
+		return
+	}
 	symbol := strings.ToUpper(strings.TrimSpace(path))
 	if symbol == "" {
 		w.WriteHeader(http.StatusBadRequest)
@@ -329,26 +518,34 @@ func (s *Server) handlePivots(w http.ResponseWriter, r *http.Request) {
 	}
 
 	q := r.URL.Query()
-	period := strings.ToLower(q.Get("period"))
-
-	resp := PivotResponse{Symbol: symbol}
-
-	// Get daily levels
-	if period == "" || period == "1d" || period == "daily" {
-		if levels, ok := s.PivotStore.GetLevels(pivot.PeriodDaily, symbol); ok {
-			resp.Daily = &levels
+	var period pivot.Period
+	if v := q.Get("period"); v != "" {
+		p, err := pivot.ParsePeriod(v)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"error":"unknown pivot period"}`))
+			return
 		}
+		period = p
 	}
 
-	// Get weekly levels
-	if period == "" || period == "1w" || period == "weekly" {
-		if levels, ok := s.PivotStore.GetLevels(pivot.PeriodWeekly, symbol); ok {
-			resp.Weekly = &levels
+	var method pivot.Method
+	if v := q.Get("method"); v != "" {
+		m, ok := pivot.ParseMethod(v)
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"error":"unknown pivot method"}`))
+			return
 		}
+		method = m
 	}
 
+	resp, ok := s.QueryPivots(symbol, period, method)
+
 	// Return 404 if no data found
-	if resp.Daily == nil && resp.Weekly == nil {
+	if !ok {
 		w.WriteHeader(http.StatusNotFound)
 		w.Header().Set("Content-Type", "application/json")
 		_, _ = w.Write([]byte(`{"error":"no pivot data found for symbol"}`))
@@ -359,6 +556,43 @@ func (s *Server) handlePivots(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(resp)
 }
 
+// QueryPivots returns the levels of symbol for one period, or every
+// registered period if period is empty. An empty method selects the primary
+// method. It reports false if no period has levels for the symbol.
+func (s *Server) QueryPivots(symbol string, period pivot.Period, method pivot.Method) (PivotResponse, bool) {
+	resp := PivotResponse{Symbol: symbol, Method: method}
+	if s.PivotStore == nil {
+		return resp, false
+	}
+	if snap, _ := s.PivotStore.Snapshot(pivot.PeriodDaily); snap != nil {
+		resp.Methods = snap.AvailableMethods()
+		if resp.Method == "" {
+			resp.Method = snap.PrimaryMethod()
+		}
+	}
+
+	for _, p := range s.PivotStore.Periods() {
+		if period != "" && p != period {
+			continue
+		}
+		levels, ok := s.PivotStore.GetMethodLevels(p, method, symbol)
+		if !ok {
+			continue
+		}
+		if resp.Periods == nil {
+			resp.Periods = make(map[pivot.Period]pivot.Levels)
+		}
+		resp.Periods[p] = levels
+		switch p {
+		case pivot.PeriodDaily:
+			resp.Daily = &levels
+		case pivot.PeriodWeekly:
+			resp.Weekly = &levels
+		}
+	}
+	return resp, len(resp.Periods) > 0
+}
+
 func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	_, _ = w.Write([]byte(`{"ok":true}`))
@@ -417,66 +651,97 @@ func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	res := s.History.Query(symbol, period, level, direction, source, limit)
+	res := s.QueryHistory(HistoryQuery{
+		Symbol:    symbol,
+		Period:    period,
+		Level:     level,
+		Direction: direction,
+		Source:    source,
+		Limit:     limit,
+	})
 
-	// Enrich signals with related pattern information from PatternHistory
-	if s.PatternHistory != nil {
-		type EnrichedSignal struct {
-			signalpkg.Signal
-			RelatedPattern *RelatedPatternInfo `json:"related_pattern,omitempty"`
-		}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
 
-		enriched := make([]EnrichedSignal, len(res))
-		for i, sig := range res {
-			enriched[i] = EnrichedSignal{Signal: sig}
+// HistoryQuery filters signal history; see signal.History.Query.
+type HistoryQuery struct {
+	Symbol    string
+	Period    string
+	Level     string // comma-separated
+	Direction string
+	Source    string
+	Limit     int
+}
+
+// EnrichedSignal is a history entry with its related pattern and outcome.
+type EnrichedSignal struct {
+	signalpkg.Signal
+	RelatedPattern *RelatedPatternInfo `json:"related_pattern,omitempty"`
+	Outcome        *signalpkg.Outcome  `json:"outcome,omitempty"`
+}
 
-			// Find related patterns for this symbol within 60 minutes (before or after signal)
-			patterns := s.PatternHistory.QueryBySymbolAndTime(sig.Symbol, sig.TriggeredAt, 60*time.Minute)
-			if len(patterns) > 0 {
-				pat := patterns[0] // Use the closest pattern
+// QueryHistory returns matching signals, newest first, enriched with related
+// pattern information and forward outcomes when those are available.
+func (s *Server) QueryHistory(q HistoryQuery) []EnrichedSignal {
+	if s.History == nil {
+		return nil
+	}
+	res := s.History.Query(q.Symbol, q.Period, q.Level, q.Direction, q.Source, q.Limit)
 
-				// Determine correlation strength
-				correlation := "moderate"
-				if pat.Direction == pattern.DirectionNeutral {
-					correlation = "moderate"
+	enriched := make([]EnrichedSignal, len(res))
+	for i, sig := range res {
+		enriched[i] = EnrichedSignal{Signal: sig}
+
+		if s.Outcomes != nil {
+			if o, ok := s.Outcomes.Get(sig.ID); ok {
+				enriched[i].Outcome = &o
+			}
+		}
+		if s.PatternHistory == nil {
+			continue
+		}
+
+		// Find related patterns for this symbol within 60 minutes (before or after signal)
+		patterns := s.PatternHistory.QueryBySymbolAndTime(sig.Symbol, sig.TriggeredAt, 60*time.Minute)
+		if len(patterns) > 0 {
+			pat := patterns[0] // Use the closest pattern
+
+			// Determine correlation strength
+			correlation := "moderate"
+			if pat.Direction == pattern.DirectionNeutral {
+				correlation = "moderate"
+			} else {
+				pivotUp := sig.Direction == "up"
+				patternBullish := pat.Direction == pattern.DirectionBullish
+				if (pivotUp && patternBullish) || (!pivotUp && !patternBullish) {
+					correlation = "strong"
 				} else {
-					pivotUp := sig.Direction == "up"
-					patternBullish := pat.Direction == pattern.DirectionBullish
-					if (pivotUp && patternBullish) || (!pivotUp && !patternBullish) {
-						correlation = "strong"
-					} else {
-						correlation = "weak"
-					}
+					correlation = "weak"
 				}
+			}
 
-				// Calculate time difference
-				timeDiff := sig.TriggeredAt.Sub(pat.DetectedAt)
-				timeDiffStr := formatTimeDiff(timeDiff)
-
-				enriched[i].RelatedPattern = &RelatedPatternInfo{
-					ID:             pat.ID,
-					Pattern:        string(pat.Pattern),
-					PatternCN:      pat.PatternCN,
-					Direction:      string(pat.Direction),
-					Confidence:     pat.Confidence,
-					UpPercent:      pat.UpPercent,
-					DownPercent:    pat.DownPercent,
-					EfficiencyRank: pat.EfficiencyRank,
-					Correlation:    correlation,
-					DetectedAt:     pat.DetectedAt,
-					Count:          len(patterns),
-					TimeDiff:       timeDiffStr,
-				}
+			// Calculate time difference
+			timeDiff := sig.TriggeredAt.Sub(pat.DetectedAt)
+			timeDiffStr := formatTimeDiff(timeDiff)
+
+			enriched[i].RelatedPattern = &RelatedPatternInfo{
+				ID:             pat.ID,
+				Pattern:        string(pat.Pattern),
+				PatternCN:      pat.PatternCN,
+				Direction:      string(pat.Direction),
+				Confidence:     pat.Confidence,
+				UpPercent:      pat.UpPercent,
+				DownPercent:    pat.DownPercent,
+				EfficiencyRank: pat.EfficiencyRank,
+				Correlation:    correlation,
+				DetectedAt:     pat.DetectedAt,
+				Count:          len(patterns),
+				TimeDiff:       timeDiffStr,
 			}
 		}
-
-		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(enriched)
-		return
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(res)
+	return enriched
 }
 
 // RelatedPatternInfo contains pattern information for enriched signals.
@@ -549,7 +814,7 @@ func (s *Server) handleSSE(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if s.SignalBroker == nil {
+	if s.SignalBroker == nil || s.Events == nil {
 		w.WriteHeader(http.StatusServiceUnavailable)
 		return
 	}
@@ -560,30 +825,49 @@ func (s *Server) handleSSE(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// 服务端订阅过滤：?profile=name 与查询参数，在写入前过滤
+	f, status, err := s.subscriptionFilter(r)
+	if err != nil {
+		writeJSONError(w, status, err.Error())
+		return
+	}
+	filter := s.compileFilter(f)
+
+	// 断线重连：EventSource 自动带 Last-Event-ID，也可用 ?since= 指定
+	since, err := parseSince(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
 	w.Header().Set("X-Accel-Buffering", "no")
 
-	// 订阅信号
-	signalCh := s.SignalBroker.Subscribe(256)
-	defer s.SignalBroker.Unsubscribe(signalCh)
+	// 先订阅再读取回放缓冲，避免两者之间的事件丢失
+	eventCh := s.Events.SubscribeAs("sse "+r.RemoteAddr, 256)
+	defer s.Events.Unsubscribe(eventCh)
 
-	// 订阅 ticker（如果可用）
-	var tickerCh chan ticker.TickerBatch
-	if s.TickerMonitor != nil {
-		tickerCh = s.TickerMonitor.Subscribe(64)
-		defer s.TickerMonitor.Unsubscribe(tickerCh)
-	}
+	_, _ = fmt.Fprintf(w, ": connected %s\n\n", time.Now().UTC().Format(time.RFC3339))
 
-	// 订阅 pattern 信号（如果可用）
-	var patternCh chan pattern.Signal
-	if s.PatternBroker != nil {
-		patternCh = s.PatternBroker.Subscribe(256)
-		defer s.PatternBroker.Unsubscribe(patternCh)
+	replayed := since
+	if since > s.Events.LastID() {
+		// ID 比最新事件还大（伪造、来自其他实例或时钟回拨）：无法回放，
+		// 按缺口处理并从实时事件开始，否则在 ID 追上之前收不到任何事件
+		_, _ = fmt.Fprintf(w, "event: gap\ndata: {\"since\":%d}\n\n", since)
+		replayed = 0
+	} else if since > 0 {
+		events, complete := s.Events.Since(since)
+		if !complete {
+			// 缺口之前的信号已不在缓冲中，客户端应通过 /api/history 补齐
+			_, _ = fmt.Fprintf(w, "event: gap\ndata: {\"since\":%d}\n\n", since)
+		}
+		for _, ev := range events {
+			writeEvent(w, ev, filter)
+			replayed = ev.ID
+		}
 	}
-
-	_, _ = fmt.Fprintf(w, ": connected %s\n\n", time.Now().UTC().Format(time.RFC3339))
 	flusher.Flush()
 
 	keepAlive := time.NewTicker(15 * time.Second)
@@ -598,47 +882,32 @@ func (s *Server) handleSSE(w http.ResponseWriter, r *http.Request) {
 			_, _ = fmt.Fprint(w, ": ping\n\n")
 			flusher.Flush()
 
-		case sig, ok := <-signalCh:
+		case ev, ok := <-eventCh:
 			if !ok {
 				return
 			}
-			b, err := json.Marshal(sig)
-			if err != nil {
-				continue
-			}
-			_, _ = fmt.Fprintf(w, "event: signal\n")
-			_, _ = fmt.Fprintf(w, "data: %s\n\n", strings.ReplaceAll(string(b), "\n", ""))
-			flusher.Flush()
-
-		case batch, ok := <-tickerCh:
-			if !ok {
-				tickerCh = nil
+			if ev.ID <= replayed {
 				continue
 			}
-			b, err := json.Marshal(batch)
-			if err != nil {
-				continue
+			if writeEvent(w, ev, filter) {
+				flusher.Flush()
 			}
-			_, _ = fmt.Fprintf(w, "event: ticker\n")
-			_, _ = fmt.Fprintf(w, "data: %s\n\n", strings.ReplaceAll(string(b), "\n", ""))
-			flusher.Flush()
-
-		case pat, ok := <-patternCh:
-			if !ok {
-				patternCh = nil
-				continue
-			}
-			b, err := json.Marshal(pat)
-			if err != nil {
-				continue
-			}
-			_, _ = fmt.Fprintf(w, "event: pattern\n")
-			_, _ = fmt.Fprintf(w, "data: %s\n\n", strings.ReplaceAll(string(b), "\n", ""))
-			flusher.Flush()
 		}
 	}
 }
 
+// writeEvent writes ev if it passes the filter and reports whether anything
+// was written.
+func writeEvent(w http.ResponseWriter, ev sse.Event, filter *subscription.Matcher) bool {
+	data, ok := filterEvent(ev, filter)
+	if !ok {
+		return false
+	}
+	_, _ = fmt.Fprintf(w, "id: %d\nevent: %s\n", ev.ID, ev.Name)
+	_, _ = fmt.Fprintf(w, "data: %s\n\n", strings.ReplaceAll(string(data), "\n", ""))
+	return true
+}
+
 func ParseAllowedOrigins(v string) []string {
 	v = strings.TrimSpace(v)
 	if v == "" {
@@ -687,7 +956,7 @@ func (s *Server) cors(next http.Handler) http.Handler {
 		if allowOrigin != "" {
 			w.Header().Set("Access-Control-Allow-Origin", allowOrigin)
 			w.Header().Add("Vary", "Origin")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, DELETE, OPTIONS")
 			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 		}
 
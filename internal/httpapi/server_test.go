@@ -0,0 +1,98 @@
+package httpapi
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sseStream reads an /api/sse response line by line.
+type sseStream struct {
+	t    *testing.T
+	scan *bufio.Scanner
+}
+
+func (w *wsTest) openSSE(header http.Header, query string) *sseStream {
+	w.t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	w.t.Cleanup(cancel)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, w.srv.URL+"/api/sse"+query, nil)
+	if err != nil {
+		w.t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		w.t.Fatal(err)
+	}
+	w.t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK {
+		w.t.Fatalf("status = %d", resp.StatusCode)
+	}
+	st := &sseStream{t: w.t, scan: bufio.NewScanner(resp.Body)}
+	// The comment is written after the hub subscription.
+	if line := st.next(); !strings.HasPrefix(line, ": connected") {
+		w.t.Fatalf("first line = %q", line)
+	}
+	return st
+}
+
+// next returns the next non-empty line.
+func (st *sseStream) next() string {
+	st.t.Helper()
+	for st.scan.Scan() {
+		if line := st.scan.Text(); line != "" {
+			return line
+		}
+	}
+	st.t.Fatalf("stream ended: %v", st.scan.Err())
+	return ""
+}
+
+func TestSSE_SinceAheadStartsLive(t *testing.T) {
+	w := newWSTest(t)
+	last := w.publish("BTCUSDT")
+	ahead := last + 100
+
+	for name, open := range map[string]func() *sseStream{
+		"query":         func() *sseStream { return w.openSSE(nil, fmt.Sprintf("?since=%d", ahead)) },
+		"Last-Event-ID": func() *sseStream { return w.openSSE(http.Header{"Last-Event-ID": {fmt.Sprint(ahead)}}, "") },
+	} {
+		st := open()
+		if line := st.next(); line != "event: gap" {
+			t.Fatalf("%s: line = %q, want a gap event", name, line)
+		}
+		if line := st.next(); line != fmt.Sprintf(`data: {"since":%d}`, ahead) {
+			t.Errorf("%s: gap data = %q", name, line)
+		}
+
+		// Live events below the bogus ID are still delivered.
+		next := w.publish("BTCUSDT")
+		if line := st.next(); line != fmt.Sprintf("id: %d", next) {
+			t.Errorf("%s: line = %q, want id %d", name, line, next)
+		}
+	}
+}
+
+func TestSSE_SinceReplay(t *testing.T) {
+	w := newWSTest(t)
+	first := w.publish("BTCUSDT")
+	second := w.publish("BTCUSDT")
+
+	st := w.openSSE(nil, fmt.Sprintf("?since=%d", first))
+	if line := st.next(); line != fmt.Sprintf("id: %d", second) {
+		t.Fatalf("line = %q, want replayed id %d", line, second)
+	}
+	st.next() // event:
+	st.next() // data:
+	third := w.publish("BTCUSDT")
+	if line := st.next(); line != fmt.Sprintf("id: %d", third) {
+		t.Errorf("line = %q, want live id %d", line, third)
+	}
+}
@@ -117,6 +117,8 @@
             label_neutral: "中性",
             label_bullish: "看涨",
             label_bearish: "看跌",
+            kind_cross: "穿越",
+            kind_reject: "拒绝",
             label_new: "新",
             label_trades_unit: "笔成交",
             label_current_price: "当前价格",
@@ -251,6 +253,8 @@
             label_neutral: "Neutral",
             label_bullish: "Bullish",
             label_bearish: "Bearish",
+            kind_cross: "Cross",
+            kind_reject: "Reject",
             label_new: "NEW",
             label_trades_unit: "trades",
             label_current_price: "Current Price",
@@ -749,6 +753,11 @@
         return direction || "-";
     }
 
+    // 信号类型：穿越或触及拒绝，旧信号没有 kind 字段时视为穿越
+    function kindLabel(kind) {
+        return kind === "reject" ? t("kind_reject") : t("kind_cross");
+    }
+
     function rankingTypeLabel(type) {
         return type === "trades" ? t("type_trades") : t("type_volume");
     }
@@ -800,6 +809,7 @@
                     <div class="tags">
                         <span class="tag">${signal.period}</span>
                         <span class="tag">${signal.level}</span>
+                        <span class="tag kind ${signal.kind || "cross"}">${kindLabel(signal.kind)}</span>
                         <span class="tag ${signal.direction}">${directionLabel(signal.direction)}</span>
                         ${patternBadgeHtml}
                     </div>
@@ -846,6 +856,7 @@
                     <div class="sym">${pattern.symbol}</div>
                     <div class="tags">
                         <span class="tag">${pattern.pattern_cn || pattern.pattern}</span>
+                        ${pattern.timeframe ? `<span class="tag">${pattern.timeframe}</span>` : ''}
                         <span class="tag" style="background:${dirColor};color:#fff">${dirArrow}</span>
                         <span class="tag rate" style="background:${effColor};color:#fff">${confidence}%</span>
                     </div>
@@ -2510,6 +2521,7 @@
     let sseReconnectTimer = null;
     let sseReconnectDelay = 1000;
     const SSE_MAX_DELAY = 30000;
+    let sseLastEventId = ""; // 重连时通过 ?since= 补回断线期间的信号
 
     function connectSSE() {
         // 清除之前的重连定时器
@@ -2520,7 +2532,7 @@
 
         let es;
         try {
-            es = new EventSource("/api/sse");
+            es = new EventSource(sseLastEventId ? `/api/sse?since=${encodeURIComponent(sseLastEventId)}` : "/api/sse");
         } catch (_) {
             setStatus("disconnected");
             scheduleReconnect();
@@ -2547,6 +2559,7 @@
 
         // 新信号
         es.addEventListener("signal", e => {
+            if (e.lastEventId) sseLastEventId = e.lastEventId;
             try {
                 const signal = JSON.parse(e.data);
 
@@ -2585,6 +2598,7 @@
 
         // 形态信号
         es.addEventListener("pattern", e => {
+            if (e.lastEventId) sseLastEventId = e.lastEventId;
             try {
                 const pattern = JSON.parse(e.data);
 
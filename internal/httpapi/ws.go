@@ -0,0 +1,310 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"example.com/binance-pivot-monitor/internal/subscription"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsWriteWait  = 10 * time.Second
+	wsPongWait   = 60 * time.Second
+	wsPingPeriod = 25 * time.Second
+	wsMaxMessage = 4096
+)
+
+// wsControl is a client control message:
+//
+//	{"op":"subscribe","topics":["signal"],"symbols":["BTCUSDT"]}
+//	{"op":"unsubscribe","symbols":["BTCUSDT"]}
+//	{"op":"ping"}
+type wsControl struct {
+	Op      string   `json:"op"`
+	Topics  []string `json:"topics,omitempty"`
+	Symbols []string `json:"symbols,omitempty"`
+}
+
+// wsMessage is a server message. Stream events carry type signal, pattern,
+// combined, confluence or ticker with the same data as the SSE event.
+type wsMessage struct {
+	Type       string          `json:"type"`
+	ID         uint64          `json:"id,omitempty"`
+	Data       json.RawMessage `json:"data,omitempty"`
+	Topics     []string        `json:"topics,omitempty"`
+	Symbols    []string        `json:"symbols,omitempty"`
+	AllSymbols bool            `json:"all_symbols,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+var wsTopics = []string{subscription.EventSignal, subscription.EventPattern, subscription.EventTicker, subscription.EventCombined, subscription.EventConfluence, subscription.EventListing}
+
+// wsState is a connection's runtime subscription. A nil symbol set means
+// all symbols, an empty one none.
+type wsState struct {
+	base    subscription.Filter
+	topics  map[string]bool
+	symbols map[string]bool
+}
+
+func newWSState(f subscription.Filter) *wsState {
+	st := &wsState{base: f, topics: make(map[string]bool)}
+	topics := f.Events
+	if len(topics) == 0 {
+		topics = wsTopics
+	}
+	for _, t := range topics {
+		st.topics[strings.ToLower(t)] = true
+	}
+	if len(f.Symbols) > 0 {
+		st.symbols = make(map[string]bool)
+		for _, sym := range f.Symbols {
+			st.symbols[strings.ToUpper(sym)] = true
+		}
+	}
+	return st
+}
+
+func (st *wsState) filter() subscription.Filter {
+	f := st.base
+	f.Events = keys(st.topics)
+	f.Symbols = keys(st.symbols)
+	if len(f.Events) == 0 || (st.symbols != nil && len(st.symbols) == 0) {
+		// 用一个不存在的事件名，使过滤器不匹配任何事件
+		f.Events = []string{"none"}
+	}
+	return f
+}
+
+// apply updates the subscription and returns an error message for invalid
+// requests.
+func (st *wsState) apply(c wsControl) string {
+	for _, t := range c.Topics {
+		t = strings.ToLower(t)
+		switch t {
+		case subscription.EventSignal, subscription.EventPattern, subscription.EventTicker, subscription.EventCombined, subscription.EventConfluence, subscription.EventListing:
+		default:
+			return "unknown topic " + t
+		}
+	}
+	switch c.Op {
+	case "subscribe":
+		for _, t := range c.Topics {
+			st.topics[strings.ToLower(t)] = true
+		}
+		if len(c.Symbols) > 0 && st.symbols == nil {
+			st.symbols = make(map[string]bool)
+		}
+		for _, sym := range c.Symbols {
+			st.symbols[strings.ToUpper(sym)] = true
+		}
+	case "unsubscribe":
+		if len(c.Symbols) > 0 && st.symbols == nil {
+			return "subscribed to all symbols; subscribe to specific symbols first"
+		}
+		for _, t := range c.Topics {
+			delete(st.topics, strings.ToLower(t))
+		}
+		for _, sym := range c.Symbols {
+			delete(st.symbols, strings.ToUpper(sym))
+		}
+	default:
+		return "unknown op " + c.Op
+	}
+	return ""
+}
+
+func keys(m map[string]bool) []string {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (s *Server) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	allowed := s.AllowedOrigins
+	if len(allowed) == 0 {
+		allowed = []string{"*"}
+	}
+	for _, o := range allowed {
+		if o == "*" || o == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// handleWS streams the same events as /api/sse over a WebSocket and accepts
+// subscribe/unsubscribe control messages. Initial filters, ?profile= and
+// ?since= work as for /api/sse.
+// GET /api/ws?symbols=BTCUSDT&events=signal,pattern
+func (s *Server) handleWS(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if s.Events == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	f, status, err := s.subscriptionFilter(r)
+	if err != nil {
+		writeJSONError(w, status, err.Error())
+		return
+	}
+	since, err := parseSince(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// 超前的 since 会让之后的实时事件被当作已回放而跳过
+	if since > s.Events.LastID() {
+		writeJSONError(w, http.StatusBadRequest, "since is ahead of the latest event id")
+		return
+	}
+
+	upgrader := websocket.Upgrader{CheckOrigin: s.checkOrigin}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade 已写入错误响应
+		return
+	}
+	defer conn.Close()
+
+	eventCh := s.Events.SubscribeAs("ws "+r.RemoteAddr, 256)
+	defer s.Events.Unsubscribe(eventCh)
+
+	// 读协程只解析控制消息，所有写操作都在本协程中完成
+	controls := make(chan wsControl, 16)
+	readErr := make(chan struct{})
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(readErr)
+		conn.SetReadLimit(wsMaxMessage)
+		_ = conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		})
+		for {
+			_, b, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var c wsControl
+			if err := json.Unmarshal(b, &c); err != nil {
+				c = wsControl{Op: "invalid"}
+			}
+			_ = conn.SetReadDeadline(time.Now().Add(wsPongWait))
+			select {
+			case controls <- c:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	send := func(m wsMessage) bool {
+		_ = conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+		return conn.WriteJSON(m) == nil
+	}
+
+	state := newWSState(f)
+	filter := s.compileFilter(state.filter())
+	ack := func(op string) bool {
+		return send(wsMessage{Type: op, Topics: keys(state.topics), Symbols: keys(state.symbols), AllSymbols: state.symbols == nil})
+	}
+	if !ack("subscribed") {
+		return
+	}
+
+	replayed := since
+	if since > 0 {
+		events, complete := s.Events.Since(since)
+		if !complete && !send(wsMessage{Type: "gap", ID: since}) {
+			return
+		}
+		for _, ev := range events {
+			if data, ok := filterEvent(ev, filter); ok {
+				if !send(wsMessage{Type: ev.Name, ID: ev.ID, Data: data}) {
+					return
+				}
+			}
+			replayed = ev.ID
+		}
+	}
+
+	ping := time.NewTicker(wsPingPeriod)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-readErr:
+			return
+
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				return
+			}
+
+		case c := <-controls:
+			if c.Op == "ping" {
+				if !send(wsMessage{Type: "pong"}) {
+					return
+				}
+				continue
+			}
+			if c.Op == "invalid" {
+				if !send(wsMessage{Type: "error", Error: "invalid JSON"}) {
+					return
+				}
+				continue
+			}
+			if msg := state.apply(c); msg != "" {
+				if !send(wsMessage{Type: "error", Error: msg}) {
+					return
+				}
+				continue
+			}
+			filter = s.compileFilter(state.filter())
+			if !ack(c.Op + "d") {
+				return
+			}
+
+		case ev, ok := <-eventCh:
+			if !ok {
+				// 消费过慢被 broker 断开
+				_ = conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "slow consumer"),
+					time.Now().Add(wsWriteWait))
+				return
+			}
+			if ev.ID <= replayed {
+				continue
+			}
+			data, ok := filterEvent(ev, filter)
+			if !ok {
+				continue
+			}
+			if !send(wsMessage{Type: ev.Name, ID: ev.ID, Data: data}) {
+				return
+			}
+		}
+	}
+}
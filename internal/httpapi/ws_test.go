@@ -0,0 +1,233 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	signalpkg "example.com/binance-pivot-monitor/internal/signal"
+	"example.com/binance-pivot-monitor/internal/sse"
+	"github.com/gorilla/websocket"
+)
+
+type wsTest struct {
+	t       *testing.T
+	s       *Server
+	signals *sse.Broker[signalpkg.Signal]
+	srv     *httptest.Server
+}
+
+func newWSTest(t *testing.T) *wsTest {
+	t.Helper()
+	signals := sse.NewBroker[signalpkg.Signal]()
+	s := New(signals, signalpkg.NewHistory(100), nil)
+	s.Events = sse.NewHub(64)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.RunEvents(ctx)
+		close(done)
+	}()
+	for signals.SubscriberCount() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	srv := httptest.NewServer(s.Handler())
+	t.Cleanup(func() {
+		srv.Close()
+		cancel()
+		<-done
+	})
+	return &wsTest{t: t, s: s, signals: signals, srv: srv}
+}
+
+// publish sends a signal through the broker and waits until the hub has
+// numbered it.
+func (w *wsTest) publish(symbol string) uint64 {
+	w.t.Helper()
+	before := w.s.Events.LastID()
+	w.signals.Publish(signalpkg.Signal{ID: symbol, Symbol: symbol, Period: "1d", Level: "R4", Direction: "up"})
+	deadline := time.Now().Add(5 * time.Second)
+	for w.s.Events.LastID() == before {
+		if time.Now().After(deadline) {
+			w.t.Fatal("signal never reached the hub")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return w.s.Events.LastID()
+}
+
+func (w *wsTest) dial(query string) (*websocket.Conn, *http.Response, error) {
+	url := "ws" + strings.TrimPrefix(w.srv.URL, "http") + "/api/ws" + query
+	return websocket.DefaultDialer.Dial(url, nil)
+}
+
+func (w *wsTest) mustDial(query string) *websocket.Conn {
+	w.t.Helper()
+	conn, _, err := w.dial(query)
+	if err != nil {
+		w.t.Fatalf("dial %s: %v", query, err)
+	}
+	w.t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func read(t *testing.T, conn *websocket.Conn) wsMessage {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var m wsMessage
+	if err := conn.ReadJSON(&m); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return m
+}
+
+func control(t *testing.T, conn *websocket.Conn, v any) wsMessage {
+	t.Helper()
+	if err := conn.WriteJSON(v); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	return read(t, conn)
+}
+
+// signalSymbol decodes the symbol of a signal message.
+func signalSymbol(t *testing.T, m wsMessage) string {
+	t.Helper()
+	if m.Type != "signal" {
+		t.Fatalf("message = %+v, want a signal", m)
+	}
+	var sig signalpkg.Signal
+	if err := json.Unmarshal(m.Data, &sig); err != nil {
+		t.Fatal(err)
+	}
+	return sig.Symbol
+}
+
+func TestWS_SubscribeUnsubscribe(t *testing.T) {
+	w := newWSTest(t)
+	conn := w.mustDial("?symbols=BTCUSDT&events=signal")
+
+	m := read(t, conn)
+	if m.Type != "subscribed" || strings.Join(m.Topics, ",") != "signal" || strings.Join(m.Symbols, ",") != "BTCUSDT" || m.AllSymbols {
+		t.Fatalf("initial ack = %+v", m)
+	}
+
+	// ETHUSDT is filtered out, so the next message is the BTCUSDT signal.
+	w.publish("ETHUSDT")
+	w.publish("BTCUSDT")
+	if sym := signalSymbol(t, read(t, conn)); sym != "BTCUSDT" {
+		t.Errorf("received %s, want BTCUSDT", sym)
+	}
+
+	m = control(t, conn, wsControl{Op: "subscribe", Symbols: []string{"ethusdt"}})
+	if m.Type != "subscribed" || strings.Join(m.Symbols, ",") != "BTCUSDT,ETHUSDT" {
+		t.Fatalf("subscribe ack = %+v", m)
+	}
+	w.publish("ETHUSDT")
+	if sym := signalSymbol(t, read(t, conn)); sym != "ETHUSDT" {
+		t.Errorf("received %s, want ETHUSDT", sym)
+	}
+
+	m = control(t, conn, wsControl{Op: "unsubscribe", Symbols: []string{"BTCUSDT"}})
+	if m.Type != "unsubscribed" || strings.Join(m.Symbols, ",") != "ETHUSDT" {
+		t.Fatalf("unsubscribe ack = %+v", m)
+	}
+	w.publish("BTCUSDT")
+	w.publish("ETHUSDT")
+	if sym := signalSymbol(t, read(t, conn)); sym != "ETHUSDT" {
+		t.Errorf("received %s after unsubscribing BTCUSDT, want ETHUSDT", sym)
+	}
+
+	m = control(t, conn, wsControl{Op: "unsubscribe", Topics: []string{"signal"}})
+	if m.Type != "unsubscribed" || len(m.Topics) != 0 {
+		t.Fatalf("unsubscribe topic ack = %+v", m)
+	}
+	w.publish("ETHUSDT")
+	if m := control(t, conn, wsControl{Op: "ping"}); m.Type != "pong" {
+		t.Errorf("got %+v after unsubscribing all topics, want only pong", m)
+	}
+}
+
+func TestWS_Errors(t *testing.T) {
+	w := newWSTest(t)
+	conn := w.mustDial("")
+	if m := read(t, conn); m.Type != "subscribed" || !m.AllSymbols {
+		t.Fatalf("initial ack = %+v", m)
+	}
+
+	cases := []struct {
+		msg  any
+		want string
+	}{
+		{wsControl{Op: "bogus"}, "unknown op bogus"},
+		{wsControl{Op: "subscribe", Topics: []string{"klines"}}, "unknown topic klines"},
+		{wsControl{Op: "unsubscribe", Symbols: []string{"BTCUSDT"}}, "subscribed to all symbols; subscribe to specific symbols first"},
+		{"not a control message", "invalid JSON"},
+	}
+	for _, c := range cases {
+		m := control(t, conn, c.msg)
+		if m.Type != "error" || m.Error != c.want {
+			t.Errorf("%v: reply = %+v, want error %q", c.msg, m, c.want)
+		}
+	}
+
+	// 出错后订阅不变，连接仍可用
+	w.publish("BTCUSDT")
+	if sym := signalSymbol(t, read(t, conn)); sym != "BTCUSDT" {
+		t.Errorf("received %s, want BTCUSDT", sym)
+	}
+}
+
+func TestWS_SinceReplay(t *testing.T) {
+	w := newWSTest(t)
+	first := w.publish("BTCUSDT")
+	second := w.publish("ETHUSDT")
+	third := w.publish("BTCUSDT")
+
+	conn := w.mustDial("?symbols=BTCUSDT&since=" + strconv.FormatUint(first, 10))
+	if m := read(t, conn); m.Type != "subscribed" {
+		t.Fatalf("initial ack = %+v", m)
+	}
+	// second is ETHUSDT and filtered out, so only third is replayed.
+	m := read(t, conn)
+	if signalSymbol(t, m) != "BTCUSDT" || m.ID != third {
+		t.Errorf("replayed %+v, want id %d (skipping %d)", m, third, second)
+	}
+
+	fourth := w.publish("BTCUSDT")
+	if m := read(t, conn); m.ID != fourth {
+		t.Errorf("live message id = %d, want %d", m.ID, fourth)
+	}
+	// Nothing else is queued: no replayed event is sent twice.
+	if m := control(t, conn, wsControl{Op: "ping"}); m.Type != "pong" {
+		t.Errorf("got %+v, want pong", m)
+	}
+}
+
+func TestWS_SinceAheadRejected(t *testing.T) {
+	w := newWSTest(t)
+	last := w.publish("BTCUSDT")
+
+	_, resp, err := w.dial("?since=" + strconv.FormatUint(last+100, 10))
+	if err == nil {
+		t.Fatal("dial with a future since succeeded")
+	}
+	if resp == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("response = %v, want 400", resp)
+	}
+
+	// The latest ID itself is fine: nothing to replay.
+	conn := w.mustDial("?since=" + strconv.FormatUint(last, 10))
+	if m := read(t, conn); m.Type != "subscribed" {
+		t.Fatalf("initial ack = %+v", m)
+	}
+	next := w.publish("BTCUSDT")
+	if m := read(t, conn); m.ID != next {
+		t.Errorf("live message id = %d, want %d", m.ID, next)
+	}
+}
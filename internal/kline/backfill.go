@@ -0,0 +1,179 @@
+package kline
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"example.com/binance-pivot-monitor/internal/binance"
+)
+
+// KlineSource fetches recent klines from the exchange.
+type KlineSource interface {
+	RecentKlines(ctx context.Context, symbol, interval string, limit int) ([]binance.Kline, error)
+}
+
+// Backfill defaults. Each /fapi/v1/klines call with limit < 100 costs weight
+// 1, so 10 requests/s stays far below the 2400 weight/min IP limit.
+const (
+	DefaultBackfillWorkers = 4
+	DefaultBackfillRate    = 10
+)
+
+// binanceIntervals are the kline intervals Binance serves, largest first.
+var binanceIntervals = []struct {
+	name string
+	d    time.Duration
+}{
+	{"1d", 24 * time.Hour},
+	{"12h", 12 * time.Hour},
+	{"8h", 8 * time.Hour},
+	{"6h", 6 * time.Hour},
+	{"4h", 4 * time.Hour},
+	{"2h", 2 * time.Hour},
+	{"1h", time.Hour},
+	{"30m", 30 * time.Minute},
+	{"15m", 15 * time.Minute},
+	{"5m", 5 * time.Minute},
+	{"3m", 3 * time.Minute},
+	{"1m", time.Minute},
+}
+
+// sourceInterval picks the largest Binance interval that tiles d, and how
+// many of its klines make one kline of d.
+func sourceInterval(d time.Duration) (string, int, bool) {
+	for _, iv := range binanceIntervals {
+		if d >= iv.d && d%iv.d == 0 {
+			return iv.name, int(d / iv.d), true
+		}
+	}
+	return "", 0, false
+}
+
+// Backfiller pre-populates a Store from the exchange's REST API.
+type Backfiller struct {
+	Source  KlineSource
+	Store   *Store
+	Workers int
+	// Rate caps REST requests per second across all workers; 0 disables it.
+	Rate float64
+}
+
+// NewBackfiller creates a backfiller with default workers and rate.
+func NewBackfiller(source KlineSource, store *Store) *Backfiller {
+	return &Backfiller{
+		Source:  source,
+		Store:   store,
+		Workers: DefaultBackfillWorkers,
+		Rate:    DefaultBackfillRate,
+	}
+}
+
+// BackfillResult summarizes a backfill run.
+type BackfillResult struct {
+	Symbols int
+	Klines  int
+	Failed  int
+}
+
+// Run fetches enough klines for every symbol to fill the store's history and
+// seeds them. Per-symbol errors are logged and counted, not returned.
+func (b *Backfiller) Run(ctx context.Context, symbols []string) (BackfillResult, error) {
+	interval := b.Store.Interval()
+	name, factor, ok := sourceInterval(interval)
+	if !ok {
+		return BackfillResult{}, fmt.Errorf("kline interval %v cannot be built from binance klines", interval)
+	}
+	// 多取一根：最后一根通常尚未收盘
+	limit := (b.Store.MaxCount() + 1) * factor
+
+	workers := b.Workers
+	if workers <= 0 {
+		workers = DefaultBackfillWorkers
+	}
+	var throttle <-chan time.Time
+	if b.Rate > 0 {
+		t := time.NewTicker(time.Duration(float64(time.Second) / b.Rate))
+		defer t.Stop()
+		throttle = t.C
+	}
+
+	var (
+		mu  sync.Mutex
+		res BackfillResult
+		wg  sync.WaitGroup
+	)
+	jobs := make(chan string)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for sym := range jobs {
+				raw, err := b.Source.RecentKlines(ctx, sym, name, limit)
+				if err != nil {
+					if ctx.Err() == nil {
+						log.Printf("kline backfill %s failed: %v", sym, err)
+					}
+					mu.Lock()
+					res.Failed++
+					mu.Unlock()
+					continue
+				}
+				n := b.Store.Seed(sym, aggregate(raw, interval), time.Now())
+				mu.Lock()
+				res.Symbols++
+				res.Klines += n
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for _, sym := range symbols {
+		if throttle != nil {
+			select {
+			case <-ctx.Done():
+				break feed
+			case <-throttle:
+			}
+		}
+		select {
+		case <-ctx.Done():
+			break feed
+		case jobs <- sym:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return res, ctx.Err()
+}
+
+// aggregate converts exchange klines to store klines, merging consecutive
+// ones that fall into the same interval. Incomplete leading groups are
+// dropped; the last group may be incomplete, as it is the forming kline.
+func aggregate(raw []binance.Kline, interval time.Duration) []Kline {
+	var out []Kline
+	for _, k := range raw {
+		open := getKlineOpenTime(k.OpenTime, interval)
+		if n := len(out); n > 0 && out[n-1].OpenTime.Equal(open) {
+			last := &out[n-1]
+			last.High = max(last.High, k.High)
+			last.Low = min(last.Low, k.Low)
+			last.Close = k.Close
+			continue
+		}
+		if !open.Equal(k.OpenTime) {
+			continue // 组的开头不在本批数据内
+		}
+		out = append(out, Kline{
+			Open:     k.Open,
+			High:     k.High,
+			Low:      k.Low,
+			Close:    k.Close,
+			OpenTime: open,
+		})
+	}
+	return out
+}
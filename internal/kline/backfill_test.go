@@ -0,0 +1,128 @@
+package kline
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/binance-pivot-monitor/internal/binance"
+)
+
+type fakeSource struct {
+	mu     sync.Mutex
+	klines map[string][]binance.Kline
+	calls  []string
+}
+
+func (f *fakeSource) RecentKlines(ctx context.Context, symbol, interval string, limit int) ([]binance.Kline, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, symbol+" "+interval)
+	ks, ok := f.klines[symbol]
+	if !ok {
+		return nil, errors.New("unknown symbol")
+	}
+	if len(ks) > limit {
+		ks = ks[len(ks)-limit:]
+	}
+	return ks, nil
+}
+
+// series returns n consecutive klines of step d ending with the one that
+// contains now.
+func series(now time.Time, d time.Duration, n int) []binance.Kline {
+	last := now.Truncate(d)
+	out := make([]binance.Kline, n)
+	for i := range out {
+		open := last.Add(-time.Duration(n-1-i) * d)
+		p := float64(100 + i)
+		out[i] = binance.Kline{OpenTime: open, Open: p, High: p + 2, Low: p - 1, Close: p + 1, CloseTime: open.Add(d - time.Millisecond)}
+	}
+	return out
+}
+
+func TestStore_Seed(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 7, 0, 0, time.UTC)
+	store := NewStore(5*time.Minute, 3)
+
+	// 实时数据已开始构建当前 K 线
+	store.Update("BTCUSDT", 110, now.Add(-time.Minute))
+	closes := 0
+	store.SetOnClose(func(string, []Kline) { closes++ })
+
+	added := store.Seed("BTCUSDT", aggregate(series(now, 5*time.Minute, 5), 5*time.Minute), now)
+	if added != 3 {
+		t.Fatalf("added = %d, want 3 (max count)", added)
+	}
+	history, _ := store.GetKlines("BTCUSDT")
+	if len(history) != 3 || !history[2].IsClosed || !history[2].OpenTime.Equal(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Fatalf("history = %+v", history)
+	}
+	if !history[2].CloseTime.Equal(time.Date(2024, 1, 1, 10, 5, 0, 0, time.UTC)) {
+		t.Errorf("close time = %v", history[2].CloseTime)
+	}
+
+	cur, _ := store.GetCurrentKline("BTCUSDT")
+	if cur.Open != 104 || cur.High != 110 || cur.Low != 103 || cur.Close != 110 {
+		t.Errorf("current = %+v, want REST open/low merged with live high/close", cur)
+	}
+	if closes != 0 {
+		t.Errorf("seed fired %d close callbacks", closes)
+	}
+
+	// 重复预填不会产生重复 K 线
+	if added := store.Seed("BTCUSDT", aggregate(series(now, 5*time.Minute, 5), 5*time.Minute), now); added != 0 {
+		t.Errorf("second seed added %d", added)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 20, 0, 0, time.UTC)
+	// 10:00 .. 10:20 in 5m klines, starting mid-group at 09:55
+	raw := series(now, 5*time.Minute, 6)
+	got := aggregate(raw, 15*time.Minute)
+	if len(got) != 2 {
+		t.Fatalf("got %d klines: %+v", len(got), got)
+	}
+	first := got[0]
+	if !first.OpenTime.Equal(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)) || first.Open != 101 || first.Close != 104 || first.High != 105 || first.Low != 100 {
+		t.Errorf("first = %+v", first)
+	}
+	if !got[1].OpenTime.Equal(time.Date(2024, 1, 1, 10, 15, 0, 0, time.UTC)) || got[1].Close != 106 {
+		t.Errorf("forming = %+v", got[1])
+	}
+}
+
+func TestBackfiller_Run(t *testing.T) {
+	now := time.Now()
+	src := &fakeSource{klines: map[string][]binance.Kline{
+		"BTCUSDT": series(now, 5*time.Minute, 40),
+		"ETHUSDT": series(now, 5*time.Minute, 40),
+	}}
+	store := NewStore(10*time.Minute, 4)
+	b := NewBackfiller(src, store)
+	b.Rate = 0
+
+	res, err := b.Run(context.Background(), []string{"BTCUSDT", "ETHUSDT", "XRPUSDT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Symbols != 2 || res.Failed != 1 || res.Klines != 8 {
+		t.Errorf("result = %+v", res)
+	}
+	if n := store.KlineCount("ETHUSDT"); n != 4 {
+		t.Errorf("ETHUSDT klines = %d, want 4", n)
+	}
+	if len(src.calls) != 3 || src.calls[0][len(src.calls[0])-2:] != "5m" {
+		t.Errorf("calls = %v", src.calls)
+	}
+
+	if _, _, ok := sourceInterval(90 * time.Second); ok {
+		t.Error("90s should not map to a binance interval")
+	}
+	if name, factor, _ := sourceInterval(15 * time.Minute); name != "15m" || factor != 1 {
+		t.Errorf("15m = %s x%d", name, factor)
+	}
+}
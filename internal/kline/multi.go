@@ -0,0 +1,149 @@
+package kline
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FormatInterval formats an interval in Binance notation: 5m, 1h, 4h, 1d.
+func FormatInterval(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour && d%(24*time.Hour) == 0:
+		return strconv.Itoa(int(d/(24*time.Hour))) + "d"
+	case d >= time.Hour && d%time.Hour == 0:
+		return strconv.Itoa(int(d/time.Hour)) + "h"
+	case d >= time.Minute && d%time.Minute == 0:
+		return strconv.Itoa(int(d/time.Minute)) + "m"
+	}
+	return d.String()
+}
+
+// ParseInterval parses "15m", "4h", "1d" or plain minutes like "15".
+func ParseInterval(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.Atoi(s); err == nil && n > 0 {
+		return time.Duration(n) * time.Minute, nil
+	}
+	if strings.HasSuffix(s, "d") {
+		if n, err := strconv.Atoi(strings.TrimSuffix(s, "d")); err == nil && n > 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < time.Minute {
+		return 0, fmt.Errorf("invalid kline interval %q", s)
+	}
+	return d, nil
+}
+
+// ParseIntervals parses a comma-separated interval list, e.g. "5m,15m,1h,4h".
+// Duplicates are dropped; order is kept.
+func ParseIntervals(s string) ([]time.Duration, error) {
+	var out []time.Duration
+	seen := make(map[time.Duration]bool)
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		d, err := ParseInterval(part)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[d] {
+			seen[d] = true
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+// MultiStore keeps one Store per timeframe, all built from the same ticks.
+type MultiStore struct {
+	stores []*Store // primary first, then by interval
+}
+
+// NewMultiStore creates a store per interval. The first interval is the
+// primary timeframe.
+func NewMultiStore(intervals []time.Duration, maxCount int) *MultiStore {
+	m := &MultiStore{}
+	for _, d := range intervals {
+		m.stores = append(m.stores, NewStore(d, maxCount))
+	}
+	if len(m.stores) > 1 {
+		rest := m.stores[1:]
+		sort.Slice(rest, func(i, j int) bool { return rest[i].interval < rest[j].interval })
+	}
+	return m
+}
+
+// Update feeds a price to every timeframe. Returns true if any kline closed.
+func (m *MultiStore) Update(symbol string, price float64, ts time.Time) bool {
+	closed := false
+	for _, s := range m.stores {
+		if s.Update(symbol, price, ts) {
+			closed = true
+		}
+	}
+	return closed
+}
+
+// SetOnClose sets the close callback of every timeframe.
+func (m *MultiStore) SetOnClose(fn func(timeframe, symbol string, klines []Kline)) {
+	for _, s := range m.stores {
+		tf := s.Timeframe()
+		s.SetOnClose(func(symbol string, klines []Kline) { fn(tf, symbol, klines) })
+	}
+}
+
+// SetSynchronous sets Store.SetSynchronous on every timeframe.
+func (m *MultiStore) SetSynchronous(sync bool) {
+	for _, s := range m.stores {
+		s.SetSynchronous(sync)
+	}
+}
+
+// Primary returns the primary timeframe's store, or nil if there is none.
+func (m *MultiStore) Primary() *Store {
+	if len(m.stores) == 0 {
+		return nil
+	}
+	return m.stores[0]
+}
+
+// Get returns the store for a timeframe such as "1h". An empty timeframe
+// returns the primary store.
+func (m *MultiStore) Get(timeframe string) (*Store, bool) {
+	if timeframe == "" {
+		p := m.Primary()
+		return p, p != nil
+	}
+	d, err := ParseInterval(timeframe)
+	if err != nil {
+		return nil, false
+	}
+	for _, s := range m.stores {
+		if s.interval == d {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// Stores returns every timeframe's store, primary first.
+func (m *MultiStore) Stores() []*Store {
+	out := make([]*Store, len(m.stores))
+	copy(out, m.stores)
+	return out
+}
+
+// Timeframes lists the timeframes, primary first.
+func (m *MultiStore) Timeframes() []string {
+	out := make([]string, len(m.stores))
+	for i, s := range m.stores {
+		out[i] = s.Timeframe()
+	}
+	return out
+}
@@ -0,0 +1,91 @@
+package kline
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetKlineOpenTime_Hours(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 10, 37, 12, 0, time.UTC)
+	cases := []struct {
+		interval time.Duration
+		want     time.Time
+	}{
+		{time.Hour, time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)},
+		{4 * time.Hour, time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)},
+		{24 * time.Hour, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{10 * time.Second, time.Date(2024, 1, 1, 10, 37, 0, 0, time.UTC)}, // below one minute
+	}
+	for _, c := range cases {
+		if got := getKlineOpenTime(ts, c.interval); !got.Equal(c.want) {
+			t.Errorf("%v: open = %v, want %v", c.interval, got, c.want)
+		}
+	}
+
+	// 非 UTC 时区同样按 UTC 边界对齐
+	shanghai := time.FixedZone("CST", 8*3600)
+	got := getKlineOpenTime(ts.In(shanghai), 4*time.Hour)
+	if !got.Equal(time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)) || got.Location() != shanghai {
+		t.Errorf("shanghai 4h open = %v", got)
+	}
+}
+
+func TestParseIntervals(t *testing.T) {
+	got, err := ParseIntervals("15m, 5m,1h,4h,15,1d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []time.Duration{15 * time.Minute, 5 * time.Minute, time.Hour, 4 * time.Hour, 24 * time.Hour}
+	if len(got) != len(want) {
+		t.Fatalf("got %v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("interval %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+	for _, bad := range []string{"x", "30s", "0"} {
+		if _, err := ParseIntervals(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+	for d, want := range map[time.Duration]string{5 * time.Minute: "5m", time.Hour: "1h", 4 * time.Hour: "4h", 24 * time.Hour: "1d", 90 * time.Minute: "90m"} {
+		if got := FormatInterval(d); got != want {
+			t.Errorf("FormatInterval(%v) = %s, want %s", d, got, want)
+		}
+	}
+}
+
+func TestMultiStore(t *testing.T) {
+	m := NewMultiStore([]time.Duration{15 * time.Minute, time.Hour, 5 * time.Minute}, 10)
+	if tfs := m.Timeframes(); len(tfs) != 3 || tfs[0] != "15m" || tfs[1] != "5m" || tfs[2] != "1h" {
+		t.Fatalf("timeframes = %v", tfs)
+	}
+
+	m.SetSynchronous(true)
+	closed := make(map[string]int)
+	m.SetOnClose(func(tf, symbol string, klines []Kline) { closed[tf]++ })
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i <= 60; i++ {
+		m.Update("BTCUSDT", 100+float64(i), start.Add(time.Duration(i)*time.Minute))
+	}
+	if closed["5m"] != 12 || closed["15m"] != 4 || closed["1h"] != 1 {
+		t.Errorf("closes = %v", closed)
+	}
+
+	h, ok := m.Get("1h")
+	if !ok || h.KlineCount("BTCUSDT") != 1 {
+		t.Fatalf("1h store = %v, %v", h, ok)
+	}
+	klines, _ := h.GetKlines("BTCUSDT")
+	if klines[0].Open != 100 || klines[0].Close != 159 || klines[0].High != 159 {
+		t.Errorf("1h kline = %+v", klines[0])
+	}
+	if p, _ := m.Get(""); p != m.Primary() || p.Timeframe() != "15m" {
+		t.Errorf("primary = %v", p)
+	}
+	if _, ok := m.Get("4h"); ok {
+		t.Error("4h should not exist")
+	}
+}
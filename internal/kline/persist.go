@@ -0,0 +1,207 @@
+package kline
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const klineSubDir = "klines"
+
+// persistedKlines is the structure for persisted kline data.
+type persistedKlines struct {
+	Timeframe string          `json:"timeframe"`
+	Symbols   []*SymbolKlines `json:"symbols"`
+	SavedAt   time.Time       `json:"saved_at"`
+}
+
+// SetDataDir enables Persist and Load. Data is kept in
+// <dataDir>/klines/<timeframe>.json.
+func (s *Store) SetDataDir(dataDir string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dataDir = dataDir
+}
+
+func (s *Store) filePath() string {
+	return filepath.Join(s.dataDir, klineSubDir, s.Timeframe()+".json")
+}
+
+// Persist saves every symbol's history and forming kline to disk.
+func (s *Store) Persist() error {
+	s.mu.RLock()
+	if s.dataDir == "" {
+		s.mu.RUnlock()
+		return nil // No persistence configured
+	}
+	data := persistedKlines{
+		Timeframe: s.Timeframe(),
+		Symbols:   make([]*SymbolKlines, 0, len(s.klines)),
+		SavedAt:   time.Now(),
+	}
+	for _, sk := range s.klines {
+		cp := &SymbolKlines{
+			Symbol:   sk.Symbol,
+			History:  make([]Kline, len(sk.History)),
+			LastSeen: sk.LastSeen,
+		}
+		copy(cp.History, sk.History)
+		if sk.Current != nil {
+			cur := sk.Current.Clone()
+			cp.Current = &cur
+		}
+		data.Symbols = append(data.Symbols, cp)
+	}
+	filePath := s.filePath()
+	s.mu.RUnlock()
+
+	// Create directory if needed
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return err
+	}
+
+	// Write to temp file first, then rename for atomicity
+	tempPath := filePath + ".tmp"
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(tempPath, jsonData, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tempPath, filePath)
+}
+
+// Load restores klines saved by Persist. Data saved for another interval is
+// ignored. Per symbol, only the newest contiguous run of closed klines is
+// kept, so a restart never feeds pattern detection a series with a hole in
+// it; the forming kline is kept if it is still forming. A run that ends
+// before the last closed interval (the process was down for a while) is
+// marked stale: Seed fills the missing klines, and if nothing does, the run
+// is dropped when the next live kline closes.
+func (s *Store) Load() error {
+	return s.load(time.Now())
+}
+
+func (s *Store) load(now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.dataDir == "" {
+		return nil // No persistence configured
+	}
+
+	jsonData, err := os.ReadFile(s.filePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil // No data file yet
+		}
+		return err
+	}
+
+	var data persistedKlines
+	if err := json.Unmarshal(jsonData, &data); err != nil {
+		return err
+	}
+	if data.Timeframe != s.Timeframe() {
+		return fmt.Errorf("kline store: %s holds %q klines, want %q", s.filePath(), data.Timeframe, s.Timeframe())
+	}
+
+	currentOpen := getKlineOpenTime(now, s.interval)
+	loaded, stale, dropped := 0, 0, 0
+	for _, saved := range data.Symbols {
+		if saved == nil || saved.Symbol == "" {
+			continue
+		}
+		history, gap := s.contiguousTail(saved.History, currentOpen)
+		var current *Kline
+		if saved.Current != nil && saved.Current.OpenTime.Equal(currentOpen) && !saved.Current.IsClosed {
+			cur := saved.Current.Clone()
+			current = &cur
+		}
+		if len(history) == 0 && current == nil {
+			dropped++
+			continue
+		}
+		if _, exists := s.klines[saved.Symbol]; exists {
+			continue // 已有实时数据，不覆盖
+		}
+		s.klines[saved.Symbol] = &SymbolKlines{
+			Symbol:   saved.Symbol,
+			Current:  current,
+			History:  history,
+			LastSeen: saved.LastSeen,
+			Stale:    gap,
+		}
+		loaded++
+		if gap {
+			stale++
+		}
+	}
+
+	log.Printf("kline store %s: loaded %d symbols from disk (%d missing recent klines), discarded %d", s.Timeframe(), loaded, stale, dropped)
+	return nil
+}
+
+// contiguousTail returns the newest run of closed, aligned, back-to-back
+// klines that closed by currentOpen, and whether intervals are missing
+// between its last kline and currentOpen.
+func (s *Store) contiguousTail(history []Kline, currentOpen time.Time) ([]Kline, bool) {
+	start, end := len(history), len(history)
+	for i := len(history) - 1; i >= 0; i-- {
+		k := history[i]
+		ok := k.IsClosed && getKlineOpenTime(k.OpenTime, s.interval).Equal(k.OpenTime) &&
+			getKlineCloseTime(k.OpenTime, s.interval).Equal(k.CloseTime) && !k.CloseTime.After(currentOpen)
+		if start == end {
+			// 跳过末尾无法使用的 K 线，直到找到最新一段的结尾
+			if ok {
+				start, end = i, i+1
+			}
+			continue
+		}
+		if !ok || !k.CloseTime.Equal(history[start].OpenTime) {
+			break
+		}
+		start = i
+	}
+	tail := history[start:end]
+	if len(tail) > s.maxCount {
+		tail = tail[len(tail)-s.maxCount:]
+	}
+	out := make([]Kline, len(tail), s.maxCount)
+	copy(out, tail)
+	gap := len(out) > 0 && !out[len(out)-1].CloseTime.Equal(currentOpen)
+	return out, gap
+}
+
+// SetDataDir enables persistence for every timeframe.
+func (m *MultiStore) SetDataDir(dataDir string) {
+	for _, s := range m.stores {
+		s.SetDataDir(dataDir)
+	}
+}
+
+// Persist saves every timeframe, returning the first error.
+func (m *MultiStore) Persist() error {
+	var first error
+	for _, s := range m.stores {
+		if err := s.Persist(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Load restores every timeframe, returning the first error.
+func (m *MultiStore) Load() error {
+	var first error
+	for _, s := range m.stores {
+		if err := s.Load(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
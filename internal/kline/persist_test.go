@@ -0,0 +1,144 @@
+package kline
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore_PersistLoad(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	store := NewStore(5*time.Minute, 4)
+	store.SetDataDir(dir)
+	for i := 0; i <= 25; i++ {
+		ts := base.Add(time.Duration(i) * time.Minute)
+		store.Update("BTCUSDT", float64(100+i), ts)
+		store.Update("ETHUSDT", float64(10+i), ts)
+	}
+	if err := store.Persist(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 仍在同一根 K 线内重启：历史与当前 K 线都恢复
+	restored := NewStore(5*time.Minute, 4)
+	restored.SetDataDir(dir)
+	if err := restored.load(base.Add(27 * time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	history, ok := restored.GetKlines("BTCUSDT")
+	if !ok || len(history) != 4 || !history[3].OpenTime.Equal(base.Add(20*time.Minute)) {
+		t.Fatalf("history = %+v", history)
+	}
+	cur, ok := restored.GetCurrentKline("BTCUSDT")
+	if !ok || cur.Close != 125 {
+		t.Errorf("current = %+v", cur)
+	}
+
+	// 跨过一根 K 线后重启：当前 K 线已过期，历史保留但标记为缺最近的 K 线
+	late := NewStore(5*time.Minute, 4)
+	late.SetDataDir(dir)
+	if err := late.load(base.Add(37 * time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	st := late.Stats()
+	if st.SymbolCount != 2 {
+		t.Fatalf("late restore kept %d symbols, want 2", st.SymbolCount)
+	}
+	for _, ss := range st.Symbols {
+		if !ss.Stale || ss.HasCurrent || ss.KlineCount != 4 {
+			t.Errorf("late restore %+v, want 4 stale klines and no current", ss)
+		}
+	}
+
+	// 周期不符的文件不加载
+	other := NewStore(15*time.Minute, 4)
+	other.SetDataDir(dir)
+	if err := os.Rename(filepath.Join(dir, "klines", "5m.json"), filepath.Join(dir, "klines", "15m.json")); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.load(base.Add(27 * time.Minute)); err == nil {
+		t.Error("expected interval mismatch error")
+	}
+}
+
+func TestStore_LoadDropsGaps(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 32, 0, 0, time.UTC)
+	store := NewStore(5*time.Minute, 10)
+	k := func(min int) Kline {
+		open := time.Date(2024, 1, 1, 10, min, 0, 0, time.UTC)
+		return Kline{Open: 1, High: 1, Low: 1, Close: 1, OpenTime: open, CloseTime: open.Add(5 * time.Minute), IsClosed: true}
+	}
+	currentOpen := getKlineOpenTime(now, 5*time.Minute)
+	// 10:10 之后缺 10:15，只保留 10:20、10:25
+	got, gap := store.contiguousTail([]Kline{k(5), k(10), k(20), k(25)}, currentOpen)
+	if len(got) != 2 || got[0].OpenTime.Minute() != 20 || gap {
+		t.Errorf("tail = %+v, gap %v", got, gap)
+	}
+	// 未对齐的 K 线视为缺口，保留之前的一段并标记缺最近的 K 线
+	bad := k(25)
+	bad.OpenTime = bad.OpenTime.Add(time.Minute)
+	got, gap = store.contiguousTail([]Kline{k(15), k(20), bad}, currentOpen)
+	if len(got) != 2 || got[1].OpenTime.Minute() != 20 || !gap {
+		t.Errorf("misaligned tail = %+v, gap %v", got, gap)
+	}
+	// 最新一段不以 10:25 结尾时同样保留
+	got, gap = store.contiguousTail([]Kline{k(0), k(10), k(15)}, currentOpen)
+	if len(got) != 2 || got[0].OpenTime.Minute() != 10 || !gap {
+		t.Errorf("old tail = %+v, gap %v", got, gap)
+	}
+}
+
+func TestStore_SeedFillsRestoredGap(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	store := NewStore(5*time.Minute, 4)
+	store.SetDataDir(dir)
+	for i := 0; i <= 25; i++ {
+		store.Update("BTCUSDT", float64(100+i), base.Add(time.Duration(i)*time.Minute))
+		store.Update("ETHUSDT", float64(10+i), base.Add(time.Duration(i)*time.Minute))
+	}
+	if err := store.Persist(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 停机到 10:37：缺 10:25、10:30 两根
+	now := base.Add(37 * time.Minute)
+	restored := NewStore(5*time.Minute, 4)
+	restored.SetDataDir(dir)
+	if err := restored.load(now); err != nil {
+		t.Fatal(err)
+	}
+	var rest []Kline
+	for min := 15; min <= 35; min += 5 {
+		rest = append(rest, Kline{Open: 1, High: 2, Low: 1, Close: 2, OpenTime: base.Add(time.Duration(min) * time.Minute)})
+	}
+	if n := restored.Seed("BTCUSDT", rest, now); n != 2 {
+		t.Errorf("seeded %d klines, want 2", n)
+	}
+	history, _ := restored.GetKlines("BTCUSDT")
+	if len(history) != 4 || !history[0].OpenTime.Equal(base.Add(15*time.Minute)) || !history[3].OpenTime.Equal(base.Add(30*time.Minute)) {
+		t.Fatalf("history = %+v", history)
+	}
+	// 已有的 K 线不被 REST 数据覆盖
+	if history[1].Close != 124 {
+		t.Errorf("restored 10:20 kline overwritten: %+v", history[1])
+	}
+	for _, ss := range restored.Stats().Symbols {
+		if want := ss.Symbol == "ETHUSDT"; ss.Stale != want {
+			t.Errorf("%s stale = %v, want %v", ss.Symbol, ss.Stale, want)
+		}
+	}
+
+	// 未预填的 ETHUSDT：下一根 K 线收盘时丢弃缺口之前的历史
+	restored.Update("ETHUSDT", 50, now)
+	restored.Update("ETHUSDT", 51, base.Add(40*time.Minute))
+	history, _ = restored.GetKlines("ETHUSDT")
+	if len(history) != 1 || !history[0].OpenTime.Equal(base.Add(35*time.Minute)) {
+		t.Errorf("history after gap = %+v", history)
+	}
+	if restored.Stats().Symbols[0].Stale {
+		t.Error("still stale after a live close")
+	}
+}
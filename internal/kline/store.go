@@ -2,16 +2,20 @@ package kline
 
 import (
 	"log"
+	"sort"
 	"sync"
 	"time"
 )
 
 // SymbolKlines holds kline data for a single trading pair.
 type SymbolKlines struct {
-	Symbol   string
-	Current  *Kline  // Current forming kline
-	History  []Kline // Completed historical klines (oldest first, newest last)
-	LastSeen time.Time
+	Symbol   string    `json:"symbol"`
+	Current  *Kline    `json:"current,omitempty"` // Current forming kline
+	History  []Kline   `json:"history"`           // Completed historical klines (oldest first, newest last)
+	LastSeen time.Time `json:"last_seen"`
+	// Stale is set when history restored from disk ends before the last
+	// closed interval; see Store.Load.
+	Stale bool `json:"-"`
 }
 
 // Store manages kline data for all trading pairs.
@@ -21,6 +25,8 @@ type Store struct {
 	interval time.Duration
 	maxCount int
 	onClose  func(symbol string, klines []Kline)
+	syncCB   bool
+	dataDir  string // empty disables Persist/Load
 }
 
 // DefaultKlineCount is the default number of klines to maintain per symbol.
@@ -51,20 +57,26 @@ func (s *Store) SetOnClose(fn func(symbol string, klines []Kline)) {
 	s.onClose = fn
 }
 
+// SetSynchronous makes the close callback run on the caller's goroutine
+// instead of a new one, so replays see pattern signals in a fixed order.
+func (s *Store) SetSynchronous(sync bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.syncCB = sync
+}
+
 // getKlineOpenTime calculates the kline open time aligned to interval boundary.
-// For 5-minute intervals: 0, 5, 10, 15, 20, 25, 30, 35, 40, 45, 50, 55
+// Boundaries are counted from the Unix epoch, so intervals that divide a day
+// line up with Binance's UTC candles: 5m opens at 0, 5, 10 ... past the hour,
+// 4h at 00:00, 04:00 ... UTC. Intervals below one minute are treated as one
+// minute.
 func getKlineOpenTime(ts time.Time, interval time.Duration) time.Time {
-	intervalMinutes := int(interval.Minutes())
-	if intervalMinutes == 0 {
-		intervalMinutes = 1
+	if interval < time.Minute {
+		interval = time.Minute
 	}
-	minute := ts.Minute()
-	alignedMinute := (minute / intervalMinutes) * intervalMinutes
-	return time.Date(
-		ts.Year(), ts.Month(), ts.Day(),
-		ts.Hour(), alignedMinute, 0, 0,
-		ts.Location(),
-	)
+	ms := ts.UnixMilli()
+	step := interval.Milliseconds()
+	return time.UnixMilli(ms - ((ms%step)+step)%step).In(ts.Location())
 }
 
 // getKlineCloseTime calculates the kline close time.
@@ -112,6 +124,14 @@ func (s *Store) Update(symbol string, price float64, ts time.Time) bool {
 		sk.Current.IsClosed = true
 		sk.Current.CloseTime = getKlineCloseTime(sk.Current.OpenTime, s.interval)
 
+		// 恢复的历史与这根 K 线之间的缺口没被预填补齐：丢弃缺口之前的部分
+		if sk.Stale {
+			if n := len(sk.History); n > 0 && !sk.History[n-1].CloseTime.Equal(sk.Current.OpenTime) {
+				sk.History = sk.History[:0]
+			}
+			sk.Stale = false
+		}
+
 		// Append to history (oldest first, newest last)
 		sk.History = append(sk.History, *sk.Current)
 
@@ -137,12 +157,17 @@ func (s *Store) Update(symbol string, price float64, ts time.Time) bool {
 
 		// Get callback reference while holding lock
 		onClose := s.onClose
+		syncCB := s.syncCB
 
 		s.mu.Unlock()
 
 		// Call callback outside lock to avoid deadlock
 		if onClose != nil {
-			go onClose(symbol, snapshot)
+			if syncCB {
+				onClose(symbol, snapshot)
+			} else {
+				go onClose(symbol, snapshot)
+			}
 		}
 
 		return true
@@ -229,6 +254,113 @@ func (s *Store) GetAllKlines(symbol string) ([]Kline, bool) {
 	return result, true
 }
 
+// Seed merges klines fetched from the exchange into a symbol's history, e.g.
+// on startup so pattern detection does not wait for KLINE_COUNT intervals,
+// or to fill the gap between klines restored from disk and live ones.
+// klines must be oldest first and match the store interval. Klines that
+// closed before now fill the intervals missing from history, live-built ones
+// win where both exist; one that is still forming becomes, or is merged into,
+// the current kline. No close callbacks are fired. Returns the number of
+// klines added to history.
+func (s *Store) Seed(symbol string, klines []Kline, now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sk := s.getOrCreate(symbol)
+	if sk.LastSeen.IsZero() {
+		sk.LastSeen = now
+	}
+
+	// 实时数据已生成的 K 线优先，REST 数据只补充缺失的周期
+	have := make(map[int64]bool, len(sk.History))
+	for _, k := range sk.History {
+		have[k.OpenTime.UnixMilli()] = true
+	}
+
+	var seeded []Kline
+	for _, k := range klines {
+		if !getKlineOpenTime(k.OpenTime, s.interval).Equal(k.OpenTime) {
+			continue // 与本地周期不对齐
+		}
+		k.Symbol = symbol
+		closeTime := getKlineCloseTime(k.OpenTime, s.interval)
+		if closeTime.After(now) {
+			s.mergeCurrentLocked(sk, k)
+			continue
+		}
+		if sk.Current != nil && !k.OpenTime.Before(sk.Current.OpenTime) {
+			continue // 由实时的当前 K 线收盘产生
+		}
+		if have[k.OpenTime.UnixMilli()] {
+			continue
+		}
+		have[k.OpenTime.UnixMilli()] = true
+		k.CloseTime = closeTime
+		k.IsClosed = true
+		seeded = append(seeded, k)
+	}
+	if len(seeded) > 0 {
+		history := make([]Kline, 0, len(seeded)+len(sk.History))
+		history = append(history, sk.History...)
+		history = append(history, seeded...)
+		sort.SliceStable(history, func(i, j int) bool {
+			return history[i].OpenTime.Before(history[j].OpenTime)
+		})
+		if len(history) > s.maxCount {
+			history = history[len(history)-s.maxCount:]
+		}
+		sk.History = history
+	}
+
+	added := 0
+	for _, k := range seeded {
+		if len(sk.History) > 0 && !k.OpenTime.Before(sk.History[0].OpenTime) {
+			added++
+		}
+	}
+	if sk.Stale {
+		if tail, gap := s.contiguousTail(sk.History, getKlineOpenTime(now, s.interval)); !gap {
+			sk.History = tail
+			sk.Stale = false
+		}
+	}
+	return added
+}
+
+// mergeCurrentLocked installs a forming kline from the exchange as the
+// current kline, keeping the live close if live ticks already started it.
+func (s *Store) mergeCurrentLocked(sk *SymbolKlines, k Kline) {
+	k.IsClosed = false
+	k.CloseTime = time.Time{}
+	switch {
+	case sk.Current == nil:
+		sk.Current = &k
+	case sk.Current.OpenTime.Equal(k.OpenTime):
+		sk.Current.Open = k.Open
+		if k.High > sk.Current.High {
+			sk.Current.High = k.High
+		}
+		if k.Low < sk.Current.Low {
+			sk.Current.Low = k.Low
+		}
+	}
+}
+
+// Interval returns the kline interval.
+func (s *Store) Interval() time.Duration {
+	return s.interval
+}
+
+// Timeframe returns the interval in Binance notation, e.g. "15m" or "4h".
+func (s *Store) Timeframe() string {
+	return FormatInterval(s.interval)
+}
+
+// MaxCount returns the number of historical klines kept per symbol.
+func (s *Store) MaxCount() int {
+	return s.maxCount
+}
+
 // CleanupStale removes symbols that haven't been updated for staleThreshold.
 // Returns the number of symbols removed.
 func (s *Store) CleanupStale(staleThreshold time.Duration) int {
@@ -272,6 +404,8 @@ type StoreStats struct {
 	Enabled      bool              `json:"enabled"`
 	SymbolCount  int               `json:"symbol_count"`
 	Interval     string            `json:"interval"`
+	Timeframe    string            `json:"timeframe"`
+	Timeframes   []string          `json:"timeframes,omitempty"` // all timeframes kept, see MultiStore
 	MaxCount     int               `json:"max_count"`
 	Symbols      []SymbolStats     `json:"symbols,omitempty"`
 }
@@ -284,6 +418,7 @@ type SymbolStats struct {
 	LastSeen     time.Time `json:"last_seen"`
 	CurrentOpen  float64   `json:"current_open,omitempty"`
 	CurrentClose float64   `json:"current_close,omitempty"`
+	Stale        bool      `json:"stale,omitempty"` // restored history not yet caught up, see Store.Load
 }
 
 // Stats returns statistics about the kline store.
@@ -295,6 +430,7 @@ func (s *Store) Stats() StoreStats {
 		Enabled:     true,
 		SymbolCount: len(s.klines),
 		Interval:    s.interval.String(),
+		Timeframe:   s.Timeframe(),
 		MaxCount:    s.maxCount,
 		Symbols:     make([]SymbolStats, 0, len(s.klines)),
 	}
@@ -305,6 +441,7 @@ func (s *Store) Stats() StoreStats {
 			KlineCount: len(sk.History),
 			HasCurrent: sk.Current != nil,
 			LastSeen:   sk.LastSeen,
+			Stale:      sk.Stale,
 		}
 		if sk.Current != nil {
 			ss.CurrentOpen = sk.Current.Open
@@ -0,0 +1,127 @@
+package monitor
+
+import (
+	"time"
+
+	"example.com/binance-pivot-monitor/internal/pivot"
+)
+
+// Confirmation rules recorded in Signal.Confirmation.
+const (
+	ConfirmHold        = "hold"
+	ConfirmKlineClose  = "kline_close"
+	ConfirmPenetration = "penetration"
+)
+
+// Confirmation controls when a level crossing becomes a signal. The zero
+// value fires on the first tick that crosses the level.
+//
+// Hold and KlineClose are alternatives: whichever is satisfied first
+// confirms the crossing. MinPenetration applies on top of them; on its own it
+// fires as soon as the price is far enough beyond the level. A crossing is
+// dropped as soon as the price falls back through the level.
+type Confirmation struct {
+	// Hold requires the price to stay beyond the level for this long.
+	Hold time.Duration
+	// KlineClose requires a kline of this interval to close beyond the level.
+	KlineClose time.Duration
+	// MinPenetration is the minimum distance beyond the level, in percent of
+	// the level price.
+	MinPenetration float64
+}
+
+func (c Confirmation) enabled() bool {
+	return c.Hold > 0 || c.KlineClose > 0 || c.MinPenetration > 0
+}
+
+// rule names the satisfied rule, noting penetration when it also applied.
+func (c Confirmation) rule(name string) string {
+	if c.MinPenetration > 0 && name != ConfirmPenetration {
+		return name + "+" + ConfirmPenetration
+	}
+	return name
+}
+
+// pendingCross is a crossing waiting for confirmation.
+type pendingCross struct {
+	direction   string
+	beyondSince time.Time // zero while the price is short of MinPenetration
+}
+
+// beyond reports whether price is at least pct percent past level in direction.
+func beyond(direction string, price, level, pct float64) bool {
+	if direction == "up" {
+		return price >= level*(1+pct/100)
+	}
+	return price <= level*(1-pct/100)
+}
+
+// confirmLevel tracks a crossing of one level until a confirmation rule is
+// satisfied. crossed is the direction of a crossing on this tick, or "".
+func (m *Monitor) confirmLevel(symbol string, period pivot.Period, method pivot.Method, levelName string, levelPrice, prev, price float64, ts time.Time, crossed string) {
+	c := m.Confirmation
+	key := symbol + "|" + string(period) + "|" + string(method) + "|" + levelName
+
+	p := m.pending[key]
+
+	// The previous tick was the last one of a kline: prev is its close. This
+	// runs before the crossing is handled, so a first tick that crosses back
+	// does not discard a kline that already closed beyond the level.
+	if p != nil && c.KlineClose > 0 {
+		if last, ok := m.lastTick[symbol]; ok && ts.Truncate(c.KlineClose).After(last.Truncate(c.KlineClose)) {
+			if beyond(p.direction, prev, levelPrice, c.MinPenetration) {
+				delete(m.pending, key)
+				sig := levelSignal(symbol, period, method, levelName, prev, p.direction, ts.Truncate(c.KlineClose))
+				sig.Confirmation = c.rule(ConfirmKlineClose)
+				m.emit(sig)
+				if crossed == "" {
+					return
+				}
+				p = nil // 这根 tick 又穿回去了：作为新的穿越处理
+			}
+		}
+	}
+
+	if crossed != "" {
+		// 回到原来一侧只是取消未确认的穿越，不算反向穿越
+		if p != nil && p.direction != crossed {
+			delete(m.pending, key)
+			return
+		}
+		p = &pendingCross{direction: crossed}
+		m.pending[key] = p
+	} else if p == nil {
+		return
+	}
+
+	// 价格回到点位另一侧：视为假突破，放弃确认
+	if !beyond(p.direction, price, levelPrice, 0) {
+		delete(m.pending, key)
+		return
+	}
+
+	if !beyond(p.direction, price, levelPrice, c.MinPenetration) {
+		p.beyondSince = time.Time{}
+		return
+	}
+	if p.beyondSince.IsZero() {
+		p.beyondSince = ts
+	}
+
+	switch {
+	case c.Hold > 0:
+		if ts.Sub(p.beyondSince) >= c.Hold {
+			delete(m.pending, key)
+			sig := levelSignal(symbol, period, method, levelName, price, p.direction, ts)
+			sig.Confirmation = c.rule(ConfirmHold)
+			m.emit(sig)
+		}
+	case c.KlineClose > 0:
+		// wait for the kline to close
+	default:
+		delete(m.pending, key)
+		sig := levelSignal(symbol, period, method, levelName, price, p.direction, ts)
+		sig.Confirmation = ConfirmPenetration
+		m.emit(sig)
+	}
+}
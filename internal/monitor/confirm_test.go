@@ -0,0 +1,133 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"example.com/binance-pivot-monitor/internal/pivot"
+	signalpkg "example.com/binance-pivot-monitor/internal/signal"
+	"example.com/binance-pivot-monitor/internal/sse"
+)
+
+func newConfirmMonitor(c Confirmation) (*Monitor, *signalpkg.History) {
+	pivotStore := pivot.NewStore(pivot.PeriodDaily)
+	pivotStore.Swap(pivot.PeriodDaily, &pivot.Snapshot{
+		Period:  pivot.PeriodDaily,
+		Symbols: map[string]pivot.Levels{"BTCUSDT": {R3: 100}},
+	})
+	history := signalpkg.NewHistory(100)
+	m := NewWithConfig(MonitorConfig{
+		PivotStore:   pivotStore,
+		Broker:       sse.NewBroker[signalpkg.Signal](),
+		History:      history,
+		Confirmation: c,
+	})
+	return m, history
+}
+
+type tick struct {
+	after time.Duration
+	price float64
+}
+
+func feed(m *Monitor, base time.Time, ticks []tick) {
+	for _, tk := range ticks {
+		m.onPrice("BTCUSDT", tk.price, base.Add(tk.after))
+	}
+}
+
+func TestConfirmation_Hold(t *testing.T) {
+	base := time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC)
+
+	// A one-second wick through the level is ignored.
+	m, history := newConfirmMonitor(Confirmation{Hold: 5 * time.Second})
+	feed(m, base, []tick{{0, 99}, {time.Second, 101}, {2 * time.Second, 99}, {10 * time.Second, 99}})
+	if n := history.Count(); n != 0 {
+		t.Fatalf("wick produced %d signals, want 0", n)
+	}
+
+	// Staying above the level for 5s confirms the crossing.
+	feed(m, base.Add(time.Minute), []tick{{0, 101}, {3 * time.Second, 101.5}, {5 * time.Second, 102}})
+	res := history.Query("", "", "", "", "", 10)
+	if len(res) != 1 {
+		t.Fatalf("signals = %d, want 1", len(res))
+	}
+	if res[0].Confirmation != ConfirmHold || res[0].Direction != "up" || res[0].Price != 102 {
+		t.Errorf("signal = %+v, want hold up at 102", res[0])
+	}
+}
+
+func TestConfirmation_KlineClose(t *testing.T) {
+	base := time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC)
+	m, history := newConfirmMonitor(Confirmation{KlineClose: time.Minute})
+
+	// Crosses and falls back inside the same minute: no signal.
+	feed(m, base, []tick{{0, 99}, {10 * time.Second, 101}, {40 * time.Second, 99}, {61 * time.Second, 99}})
+	if n := history.Count(); n != 0 {
+		t.Fatalf("intra-kline reversal produced %d signals, want 0", n)
+	}
+
+	// Crosses and the minute closes above the level.
+	feed(m, base, []tick{{70 * time.Second, 101}, {119 * time.Second, 100.5}, {121 * time.Second, 100.2}})
+	res := history.Query("", "", "", "", "", 10)
+	if len(res) != 1 {
+		t.Fatalf("signals = %d, want 1", len(res))
+	}
+	if res[0].Confirmation != ConfirmKlineClose || res[0].Price != 100.5 || !res[0].TriggeredAt.Equal(base.Add(2*time.Minute)) {
+		t.Errorf("signal = %+v, want kline_close at 100.5 on the 10:02 close", res[0])
+	}
+}
+
+func TestConfirmation_KlineCloseThenCrossBack(t *testing.T) {
+	base := time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC)
+	m, history := newConfirmMonitor(Confirmation{KlineClose: time.Minute})
+
+	// The 10:00 kline closes above the level; the first tick of 10:01 is
+	// back below it.
+	feed(m, base, []tick{{0, 99}, {10 * time.Second, 101}, {59 * time.Second, 100.8}, {61 * time.Second, 99.5}})
+	res := history.Query("", "", "", "", "", 10)
+	if len(res) != 1 {
+		t.Fatalf("signals = %d, want 1", len(res))
+	}
+	if res[0].Confirmation != ConfirmKlineClose || res[0].Direction != "up" || res[0].Price != 100.8 || !res[0].TriggeredAt.Equal(base.Add(time.Minute)) {
+		t.Errorf("signal = %+v, want kline_close up at 100.8 on the 10:01 close", res[0])
+	}
+
+	// The cross back is tracked as a new crossing and confirms on its own close.
+	feed(m, base, []tick{{119 * time.Second, 99.2}, {121 * time.Second, 99.4}})
+	res = history.Query("", "", "", "", "", 10)
+	if len(res) != 2 || res[0].Direction != "down" || res[0].Price != 99.2 {
+		t.Errorf("signals = %+v, want a kline_close down at 99.2 after the up signal", res)
+	}
+}
+
+func TestConfirmation_Penetration(t *testing.T) {
+	base := time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC)
+	m, history := newConfirmMonitor(Confirmation{MinPenetration: 0.5})
+
+	feed(m, base, []tick{{0, 101}, {time.Second, 99.8}, {2 * time.Second, 99.6}})
+	if n := history.Count(); n != 0 {
+		t.Fatalf("shallow penetration produced %d signals, want 0", n)
+	}
+	feed(m, base, []tick{{3 * time.Second, 99.4}})
+	res := history.Query("", "", "", "", "", 10)
+	if len(res) != 1 || res[0].Confirmation != ConfirmPenetration || res[0].Direction != "down" {
+		t.Fatalf("signals = %+v, want one penetration down signal", res)
+	}
+}
+
+func TestConfirmation_HoldWithPenetration(t *testing.T) {
+	base := time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC)
+	m, history := newConfirmMonitor(Confirmation{Hold: 2 * time.Second, MinPenetration: 1})
+
+	// Above the level but short of 1%: the hold clock does not run.
+	feed(m, base, []tick{{0, 99}, {time.Second, 100.5}, {5 * time.Second, 100.6}})
+	if n := history.Count(); n != 0 {
+		t.Fatalf("signals = %d, want 0", n)
+	}
+	feed(m, base, []tick{{6 * time.Second, 101.2}, {7 * time.Second, 101.1}, {8 * time.Second, 101.3}})
+	res := history.Query("", "", "", "", "", 10)
+	if len(res) != 1 || res[0].Confirmation != "hold+penetration" {
+		t.Fatalf("signals = %+v, want one hold+penetration signal", res)
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"example.com/binance-pivot-monitor/internal/kline"
 	"example.com/binance-pivot-monitor/internal/pattern"
 	"example.com/binance-pivot-monitor/internal/pivot"
+	"example.com/binance-pivot-monitor/internal/recorder"
 	signalpkg "example.com/binance-pivot-monitor/internal/signal"
 	"example.com/binance-pivot-monitor/internal/sse"
 	"github.com/gorilla/websocket"
@@ -30,9 +31,32 @@ type Monitor struct {
 	Cooldown       *signalpkg.Cooldown
 	Source         string
 	HeartbeatEvery time.Duration
-
-	// K-line pattern recognition
+	// Endpoints lists the WebSocket base URLs to dial, with failover. Nil
+	// uses binance.FStreamWSBaseURL.
+	Endpoints *binance.WSEndpoints
+	// Listings, if set, is told about every symbol so that symbols missing
+	// from the pivot snapshots get levels on demand.
+	Listings *pivot.ListingWatcher
+	// PivotMethods lists the pivot methods to alert on. Empty means the
+	// primary method of each snapshot.
+	PivotMethods []pivot.Method
+	// Confirmation delays signals until a crossing is confirmed.
+	Confirmation Confirmation
+	// Rejection emits touch-and-reject signals near levels.
+	Rejection Rejection
+	// Recorder, if set, stores every decoded mark price frame for replay.
+	Recorder *recorder.Recorder
+	// Outcomes follows the price after each emitted signal.
+	Outcomes *signalpkg.OutcomeTracker
+	// OnSignal and OnPattern are called synchronously for every emitted
+	// signal, after history and broker. Replay uses them to write output.
+	OnSignal  func(signalpkg.Signal)
+	OnPattern func(pattern.Signal)
+
+	// K-line pattern recognition. KlineStores, if set, keeps several
+	// timeframes and replaces KlineStore, which then points at its primary.
 	KlineStore      *kline.Store
+	KlineStores     *kline.MultiStore
 	PatternDetector *pattern.Detector
 	PatternHistory  *pattern.History
 	PatternBroker   *sse.Broker[pattern.Signal]
@@ -40,6 +64,9 @@ type Monitor struct {
 
 	idCounter   uint64
 	lastPrice   map[string]float64
+	lastTick    map[string]time.Time
+	pending     map[string]*pendingCross
+	touches     map[string]*touchState
 	symbolsSeen int64
 }
 
@@ -51,6 +78,9 @@ func New(pivotStore *pivot.Store, broker *sse.Broker[signalpkg.Signal], history
 		Cooldown:   cooldown,
 		Source:     "markPrice",
 		lastPrice:  make(map[string]float64),
+		lastTick:   make(map[string]time.Time),
+		pending:    make(map[string]*pendingCross),
+		touches:    make(map[string]*touchState),
 	}
 }
 
@@ -60,7 +90,12 @@ type MonitorConfig struct {
 	Broker          *sse.Broker[signalpkg.Signal]
 	History         *signalpkg.History
 	Cooldown        *signalpkg.Cooldown
+	PivotMethods    []pivot.Method
+	Confirmation    Confirmation
+	Rejection       Rejection
+	Outcomes        *signalpkg.OutcomeTracker
 	KlineStore      *kline.Store
+	KlineStores     *kline.MultiStore
 	PatternDetector *pattern.Detector
 	PatternHistory  *pattern.History
 	PatternBroker   *sse.Broker[pattern.Signal]
@@ -74,17 +109,32 @@ func NewWithConfig(cfg MonitorConfig) *Monitor {
 		Broker:          cfg.Broker,
 		History:         cfg.History,
 		Cooldown:        cfg.Cooldown,
+		PivotMethods:    cfg.PivotMethods,
+		Confirmation:    cfg.Confirmation,
+		Rejection:       cfg.Rejection,
+		Outcomes:        cfg.Outcomes,
 		KlineStore:      cfg.KlineStore,
+		KlineStores:     cfg.KlineStores,
 		PatternDetector: cfg.PatternDetector,
 		PatternHistory:  cfg.PatternHistory,
 		PatternBroker:   cfg.PatternBroker,
 		SignalCombiner:  cfg.SignalCombiner,
 		Source:          "markPrice",
 		lastPrice:       make(map[string]float64),
+		lastTick:        make(map[string]time.Time),
+		pending:         make(map[string]*pendingCross),
+		touches:         make(map[string]*touchState),
 	}
 
 	// Set up kline close callback for pattern detection
-	if m.KlineStore != nil && m.PatternDetector != nil {
+	if m.KlineStores != nil {
+		if m.KlineStore == nil {
+			m.KlineStore = m.KlineStores.Primary()
+		}
+		if m.PatternDetector != nil {
+			m.KlineStores.SetOnClose(m.detectPatterns)
+		}
+	} else if m.KlineStore != nil && m.PatternDetector != nil {
 		m.KlineStore.SetOnClose(m.onKlineClose)
 	}
 
@@ -231,9 +281,9 @@ func (m *Monitor) Run(ctx context.Context) {
 			return
 		}
 
-		conn, _, err := binance.DialMarkPriceArr1s(ctx)
+		conn, base, _, err := binance.DialMarkPriceArr1s(ctx, m.Endpoints)
 		if err != nil {
-			log.Printf("monitor ws dial failed: %v", err)
+			log.Printf("monitor ws dial %s failed: %v", base, err)
 			if !sleepContext(ctx, backoff) {
 				return
 			}
@@ -241,7 +291,7 @@ func (m *Monitor) Run(ctx context.Context) {
 			continue
 		}
 
-		log.Printf("monitor ws connected")
+		log.Printf("monitor ws connected: %s", base)
 		backoff = 1 * time.Second
 
 		err = m.readLoop(ctx, conn)
@@ -313,6 +363,7 @@ func (m *Monitor) readLoop(ctx context.Context, conn *websocket.Conn) error {
 	defer close(done)
 
 	unmarshalSampleLogged := 0
+	recordErrLogged := 0
 	for {
 		if ctx.Err() != nil {
 			return ctx.Err()
@@ -373,17 +424,29 @@ func (m *Monitor) readLoop(ctx context.Context, conn *websocket.Conn) error {
 		}
 
 		now := time.Now().UTC()
-		for _, ev := range events {
-			price, err := strconv.ParseFloat(ev.MarkPrice, 64)
-			if err != nil {
-				continue
-			}
-			ts := now
-			if ev.EventTime > 0 {
-				ts = time.UnixMilli(ev.EventTime).UTC()
+		if m.Recorder != nil {
+			if err := m.Recorder.Record(now, events); err != nil && recordErrLogged < 3 {
+				recordErrLogged++
+				log.Printf("monitor recorder write failed: %v", err)
 			}
-			m.onPrice(ev.Symbol, price, ts)
 		}
+		m.Feed(events, now)
+	}
+}
+
+// Feed processes one decoded mark price frame received at now. Events
+// without an event time are stamped with now.
+func (m *Monitor) Feed(events []binance.MarkPriceEvent, now time.Time) {
+	for _, ev := range events {
+		price, err := strconv.ParseFloat(ev.MarkPrice, 64)
+		if err != nil {
+			continue
+		}
+		ts := now
+		if ev.EventTime > 0 {
+			ts = time.UnixMilli(ev.EventTime).UTC()
+		}
+		m.onPrice(ev.Symbol, price, ts)
 	}
 }
 
@@ -395,84 +458,112 @@ func (m *Monitor) onPrice(symbol string, price float64, ts time.Time) {
 	}
 
 	// Update kline data (if enabled)
-	if m.KlineStore != nil {
+	if m.KlineStores != nil {
+		m.KlineStores.Update(symbol, price, ts)
+	} else if m.KlineStore != nil {
 		m.KlineStore.Update(symbol, price, ts)
 	}
+	if m.Outcomes != nil {
+		m.Outcomes.Update(symbol, price, ts)
+	}
+	if m.Listings != nil {
+		m.Listings.Observe(symbol, ts)
+	}
 
 	// Check pivot levels (only if we have previous price)
-	if !ok {
-		return
+	if ok {
+		for _, period := range m.PivotStore.Periods() {
+			m.checkPeriod(symbol, period, prev, price, ts)
+		}
 	}
-
-	m.checkPeriod(symbol, pivot.PeriodDaily, prev, price, ts)
-	m.checkPeriod(symbol, pivot.PeriodWeekly, prev, price, ts)
+	m.lastTick[symbol] = ts
 }
 
 func (m *Monitor) checkPeriod(symbol string, period pivot.Period, prev, price float64, ts time.Time) {
-	lv, ok := m.PivotStore.GetLevels(period, symbol)
-	if !ok {
+	snap, err := m.PivotStore.Snapshot(period)
+	if err != nil || snap == nil {
 		return
 	}
 
-	// Check all 11 pivot levels: PP, R1-R5, S1-S5
-	m.checkLevel(symbol, period, "PP", lv.PP, prev, price, ts)
+	methods := m.PivotMethods
+	if len(methods) == 0 {
+		methods = []pivot.Method{snap.PrimaryMethod()}
+	}
 
-	m.checkLevel(symbol, period, "R1", lv.R1, prev, price, ts)
-	m.checkLevel(symbol, period, "R2", lv.R2, prev, price, ts)
-	m.checkLevel(symbol, period, "R3", lv.R3, prev, price, ts)
-	m.checkLevel(symbol, period, "R4", lv.R4, prev, price, ts)
-	m.checkLevel(symbol, period, "R5", lv.R5, prev, price, ts)
+	for _, method := range methods {
+		lv, ok := snap.Levels(method, symbol)
+		if !ok {
+			continue
+		}
 
-	m.checkLevel(symbol, period, "S1", lv.S1, prev, price, ts)
-	m.checkLevel(symbol, period, "S2", lv.S2, prev, price, ts)
-	m.checkLevel(symbol, period, "S3", lv.S3, prev, price, ts)
-	m.checkLevel(symbol, period, "S4", lv.S4, prev, price, ts)
-	m.checkLevel(symbol, period, "S5", lv.S5, prev, price, ts)
+		// Check all 11 pivot levels: PP, R1-R5, S1-S5
+		for _, name := range pivot.LevelNames {
+			levelPrice, _ := lv.Price(name)
+			m.checkLevel(symbol, period, method, name, levelPrice, prev, price, ts)
+		}
+	}
 }
 
-func (m *Monitor) checkLevel(symbol string, period pivot.Period, levelName string, levelPrice float64, prev, price float64, ts time.Time) {
+func (m *Monitor) checkLevel(symbol string, period pivot.Period, method pivot.Method, levelName string, levelPrice float64, prev, price float64, ts time.Time) {
 	if levelPrice <= 0 {
 		return
 	}
 
-	if prev < levelPrice && price >= levelPrice {
-		m.emit(symbol, period, levelName, price, "up", ts)
-		return
+	crossed := pivot.Crossing(prev, price, levelPrice)
+
+	if m.Rejection.enabled() {
+		m.trackTouch(symbol, period, method, levelName, levelPrice, price, ts, crossed)
 	}
 
-	if prev > levelPrice && price <= levelPrice {
-		m.emit(symbol, period, levelName, price, "down", ts)
+	if m.Confirmation.enabled() {
+		m.confirmLevel(symbol, period, method, levelName, levelPrice, prev, price, ts, crossed)
 		return
 	}
-}
-
-func (m *Monitor) emit(symbol string, period pivot.Period, levelName string, price float64, direction string, ts time.Time) {
-	key := symbol + "|" + string(period) + "|" + levelName
-	if m.Cooldown != nil {
-		if !m.Cooldown.Allow(key, ts) {
-			return
-		}
+	if crossed != "" {
+		m.emit(levelSignal(symbol, period, method, levelName, price, crossed, ts))
 	}
+}
 
-	log.Printf("signal %s %s %s %s price=%g", symbol, period, levelName, direction, price)
-
-	seq := atomic.AddUint64(&m.idCounter, 1)
-	id := fmt.Sprintf("%d-%d", ts.UnixNano(), seq)
-
-	sig := signalpkg.Signal{
-		ID:          id,
+// levelSignal builds a crossing signal; emit assigns its ID and source.
+func levelSignal(symbol string, period pivot.Period, method pivot.Method, levelName string, price float64, direction string, ts time.Time) signalpkg.Signal {
+	return signalpkg.Signal{
 		Symbol:      symbol,
 		Period:      string(period),
+		Method:      string(method),
+		Kind:        signalpkg.KindCross,
 		Level:       levelName,
 		Price:       price,
 		Direction:   direction,
 		TriggeredAt: ts,
-		Source:      m.Source,
+	}
+}
+
+func (m *Monitor) emit(sig signalpkg.Signal) {
+	key := sig.Symbol + "|" + sig.Period + "|" + sig.Level
+	if sig.Method != "" && sig.Method != string(pivot.DefaultMethod) {
+		key += "|" + sig.Method
+	}
+	if sig.Kind == signalpkg.KindReject {
+		key += "|" + sig.Kind
+	}
+	if m.Cooldown != nil {
+		if !m.Cooldown.Allow(key, sig.TriggeredAt) {
+			return
+		}
 	}
 
+	log.Printf("signal %s %s %s %s %s %s price=%g", sig.Symbol, sig.Period, sig.Method, sig.Kind, sig.Level, sig.Direction, sig.Price)
+
+	seq := atomic.AddUint64(&m.idCounter, 1)
+	sig.ID = fmt.Sprintf("%d-%d", sig.TriggeredAt.UnixNano(), seq)
+	sig.Source = m.Source
+
 	if m.History != nil {
 		m.History.Add(sig)
 	}
+	if m.Outcomes != nil {
+		m.Outcomes.Track(sig)
+	}
 	if m.Broker != nil {
 		m.Broker.Publish(sig)
 	}
@@ -481,6 +572,9 @@ func (m *Monitor) emit(symbol string, period pivot.Period, levelName string, pri
 	if m.SignalCombiner != nil {
 		m.SignalCombiner.AddPivotSignal(sig)
 	}
+	if m.OnSignal != nil {
+		m.OnSignal(sig)
+	}
 }
 
 func sleepContext(ctx context.Context, d time.Duration) bool {
@@ -504,10 +598,20 @@ func minDuration(a, b time.Duration) time.Duration {
 	return b
 }
 
-// onKlineClose is called when a kline closes.
+// onKlineClose is called when a KlineStore kline closes.
 // It triggers pattern detection asynchronously.
 // klines is a deep copy snapshot, safe for async use.
 func (m *Monitor) onKlineClose(symbol string, klines []kline.Kline) {
+	var timeframe string
+	if m.KlineStore != nil {
+		timeframe = m.KlineStore.Timeframe()
+	}
+	m.detectPatterns(timeframe, symbol, klines)
+}
+
+// detectPatterns runs pattern detection on the closed klines of one
+// timeframe.
+func (m *Monitor) detectPatterns(timeframe, symbol string, klines []kline.Kline) {
 	// Skip if pattern detection is not enabled
 	if m.PatternDetector == nil {
 		return
@@ -516,12 +620,10 @@ func (m *Monitor) onKlineClose(symbol string, klines []kline.Kline) {
 	// Skip if we don't have pivot data for this symbol (per design: Property 11)
 	// This limits detection to symbols we're actively monitoring
 	hasPivot := false
-	if _, ok := m.PivotStore.GetLevels(pivot.PeriodDaily, symbol); ok {
-		hasPivot = true
-	}
-	if !hasPivot {
-		if _, ok := m.PivotStore.GetLevels(pivot.PeriodWeekly, symbol); ok {
+	for _, period := range m.PivotStore.Periods() {
+		if _, ok := m.PivotStore.GetLevels(period, symbol); ok {
 			hasPivot = true
+			break
 		}
 	}
 	if !hasPivot {
@@ -533,7 +635,7 @@ func (m *Monitor) onKlineClose(symbol string, klines []kline.Kline) {
 	patterns := m.PatternDetector.Detect(klines)
 	elapsed := time.Since(startTime)
 	if elapsed > 100*time.Millisecond {
-		log.Printf("pattern detection slow: symbol=%s elapsed=%v", symbol, elapsed)
+		log.Printf("pattern detection slow: symbol=%s timeframe=%s elapsed=%v", symbol, timeframe, elapsed)
 	}
 
 	if len(patterns) == 0 {
@@ -552,15 +654,15 @@ func (m *Monitor) onKlineClose(symbol string, klines []kline.Kline) {
 
 	// Emit signals for each detected pattern
 	for _, p := range patterns {
-		m.emitPatternSignal(symbol, p, klineTime)
+		m.emitPatternSignal(symbol, timeframe, p, klineTime)
 	}
 }
 
 // emitPatternSignal creates and emits a pattern signal.
-func (m *Monitor) emitPatternSignal(symbol string, p pattern.DetectedPattern, klineTime time.Time) {
-	sig := pattern.NewSignal(symbol, p.Type, p.Direction, p.Confidence, klineTime)
+func (m *Monitor) emitPatternSignal(symbol, timeframe string, p pattern.DetectedPattern, klineTime time.Time) {
+	sig := pattern.NewSignal(symbol, p.Type, p.Direction, p.Confidence, klineTime).WithTimeframe(timeframe)
 
-	log.Printf("pattern %s %s %s confidence=%d", symbol, p.Type, p.Direction, p.Confidence)
+	log.Printf("pattern %s %s %s %s confidence=%d", symbol, timeframe, p.Type, p.Direction, p.Confidence)
 
 	// Record to history
 	if m.PatternHistory != nil {
@@ -578,4 +680,7 @@ func (m *Monitor) emitPatternSignal(symbol string, p pattern.DetectedPattern, kl
 	if m.SignalCombiner != nil {
 		m.SignalCombiner.AddPatternSignal(sig)
 	}
+	if m.OnPattern != nil {
+		m.OnPattern(sig)
+	}
 }
@@ -590,3 +590,81 @@ func TestProperty_FirstPriceBaseline(t *testing.T) {
 
 	properties.TestingRun(t)
 }
+
+// TestCheckPeriod_ConfiguredMethods tests that signals are emitted for every
+// configured pivot method and carry the method name.
+func TestCheckPeriod_ConfiguredMethods(t *testing.T) {
+	pivotStore := pivot.NewStore()
+	pivotStore.Swap(pivot.PeriodDaily, &pivot.Snapshot{
+		Period:  pivot.PeriodDaily,
+		Method:  pivot.MethodCamarilla,
+		Symbols: map[string]pivot.Levels{"BTCUSDT": {R3: 100}},
+		Methods: map[pivot.Method]map[string]pivot.Levels{
+			pivot.MethodClassic: {"BTCUSDT": {R1: 101}},
+		},
+	})
+
+	history := signalpkg.NewHistory(100)
+	m := NewWithConfig(MonitorConfig{
+		PivotStore:   pivotStore,
+		Broker:       sse.NewBroker[signalpkg.Signal](),
+		History:      history,
+		Cooldown:     signalpkg.NewCooldown(5 * time.Minute),
+		PivotMethods: []pivot.Method{pivot.MethodCamarilla, pivot.MethodClassic},
+	})
+
+	ts := time.Now()
+	m.onPrice("BTCUSDT", 99, ts)
+	m.onPrice("BTCUSDT", 102, ts.Add(time.Second))
+
+	got := make(map[string]string)
+	for _, sig := range history.Query("", "", "", "", "", 100) {
+		got[sig.Method] = sig.Level
+	}
+	if got["camarilla"] != "R3" || got["classic"] != "R1" || len(got) != 2 {
+		t.Errorf("signals by method = %v, want camarilla:R3 classic:R1", got)
+	}
+
+	// Without configured methods only the primary method is checked.
+	history2 := signalpkg.NewHistory(100)
+	m2 := NewWithConfig(MonitorConfig{
+		PivotStore: pivotStore,
+		Broker:     sse.NewBroker[signalpkg.Signal](),
+		History:    history2,
+	})
+	m2.onPrice("BTCUSDT", 99, ts)
+	m2.onPrice("BTCUSDT", 102, ts.Add(time.Second))
+	if n := history2.Count(); n != 1 {
+		t.Errorf("primary-only signals = %d, want 1", n)
+	}
+}
+
+// TestOnPrice_AllRegisteredPeriods tests that every registered period is
+// checked, including monthly and intraday periods.
+func TestOnPrice_AllRegisteredPeriods(t *testing.T) {
+	pivotStore := pivot.NewStore(pivot.PeriodMonthly, pivot.Period4h)
+	for _, p := range pivotStore.Periods() {
+		pivotStore.Swap(p, &pivot.Snapshot{
+			Period:  p,
+			Symbols: map[string]pivot.Levels{"BTCUSDT": {R3: 100}},
+		})
+	}
+
+	history := signalpkg.NewHistory(100)
+	m := NewWithConfig(MonitorConfig{
+		PivotStore: pivotStore,
+		Broker:     sse.NewBroker[signalpkg.Signal](),
+		History:    history,
+		Cooldown:   signalpkg.NewCooldown(5 * time.Minute),
+	})
+
+	ts := time.Now()
+	m.onPrice("BTCUSDT", 99, ts)
+	m.onPrice("BTCUSDT", 101, ts.Add(time.Second))
+
+	for _, p := range []string{"1M", "4h"} {
+		if res := history.Query("", p, "", "", "", 100); len(res) != 1 || res[0].Period != p {
+			t.Errorf("period %s signals = %+v, want one R3 signal", p, res)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package monitor
+
+import (
+	"math"
+	"time"
+
+	"example.com/binance-pivot-monitor/internal/pivot"
+	signalpkg "example.com/binance-pivot-monitor/internal/signal"
+)
+
+// Rejection configures touch-and-reject signals. Distances are in percent
+// of the level price. The zero value disables rejection tracking.
+//
+// Price entering the Approach band starts tracking, coming within Touch
+// marks the level as touched, and leaving the Approach band again on the
+// same side without crossing emits a reject signal.
+type Rejection struct {
+	Approach float64
+	// Touch defaults to half of Approach.
+	Touch float64
+}
+
+func (r Rejection) enabled() bool {
+	return r.Approach > 0
+}
+
+func (r Rejection) touchBand() float64 {
+	if r.Touch > 0 && r.Touch <= r.Approach {
+		return r.Touch
+	}
+	return r.Approach / 2
+}
+
+// touchState tracks price near one level.
+type touchState struct {
+	side    int  // +1 above the level, -1 below
+	touched bool // came within the touch band
+	// crossed is set right after a crossing: the price must leave the band
+	// before a new approach can start, so a breakout is not read as a rejection.
+	crossed bool
+}
+
+// trackTouch advances the approach/touch/reject state of one level.
+// crossed is the direction of a crossing on this tick, or "".
+func (m *Monitor) trackTouch(symbol string, period pivot.Period, method pivot.Method, levelName string, levelPrice, price float64, ts time.Time, crossed string) {
+	r := m.Rejection
+	key := symbol + "|" + string(period) + "|" + string(method) + "|" + levelName
+	dist := math.Abs(price-levelPrice) / levelPrice * 100
+
+	side := 0
+	if price > levelPrice {
+		side = 1
+	} else if price < levelPrice {
+		side = -1
+	}
+
+	if crossed != "" {
+		if dist <= r.Approach {
+			m.touches[key] = &touchState{side: side, crossed: true}
+		} else {
+			delete(m.touches, key)
+		}
+		return
+	}
+
+	st := m.touches[key]
+	if st == nil {
+		if dist <= r.Approach && side != 0 {
+			m.touches[key] = &touchState{side: side, touched: dist <= r.touchBand()}
+		}
+		return
+	}
+
+	if side != 0 && side != st.side {
+		// 价格贴着点位反转到另一侧但未被判定为穿越，重新开始跟踪
+		delete(m.touches, key)
+		return
+	}
+
+	if dist <= r.touchBand() {
+		st.touched = true
+	}
+	if dist <= r.Approach {
+		return
+	}
+
+	delete(m.touches, key)
+	if st.crossed || !st.touched {
+		return
+	}
+
+	// 触及后未穿越即离开：阻力位向下拒绝，支撑位向上拒绝
+	direction := "down"
+	if st.side > 0 {
+		direction = "up"
+	}
+	sig := levelSignal(symbol, period, method, levelName, price, direction, ts)
+	sig.Kind = signalpkg.KindReject
+	m.emit(sig)
+}
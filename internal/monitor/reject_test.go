@@ -0,0 +1,72 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"example.com/binance-pivot-monitor/internal/pivot"
+	signalpkg "example.com/binance-pivot-monitor/internal/signal"
+	"example.com/binance-pivot-monitor/internal/sse"
+)
+
+func newRejectMonitor(r Rejection) (*Monitor, *signalpkg.History) {
+	pivotStore := pivot.NewStore(pivot.PeriodDaily)
+	pivotStore.Swap(pivot.PeriodDaily, &pivot.Snapshot{
+		Period:  pivot.PeriodDaily,
+		Symbols: map[string]pivot.Levels{"BTCUSDT": {R3: 100}},
+	})
+	history := signalpkg.NewHistory(100)
+	m := NewWithConfig(MonitorConfig{
+		PivotStore: pivotStore,
+		Broker:     sse.NewBroker[signalpkg.Signal](),
+		History:    history,
+		Rejection:  r,
+	})
+	return m, history
+}
+
+func TestRejection_TouchAndReject(t *testing.T) {
+	base := time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC)
+	m, history := newRejectMonitor(Rejection{Approach: 0.5, Touch: 0.1})
+
+	// Approaches R3 from below, touches 99.95 and falls back out of the band.
+	feed(m, base, []tick{{0, 99}, {time.Second, 99.7}, {2 * time.Second, 99.95}, {3 * time.Second, 99.6}, {4 * time.Second, 99.4}})
+
+	res := history.Query("", "", "", "", "", 10)
+	if len(res) != 1 {
+		t.Fatalf("signals = %+v, want one reject", res)
+	}
+	if res[0].Kind != signalpkg.KindReject || res[0].Direction != "down" || res[0].Level != "R3" {
+		t.Errorf("signal = %+v, want reject down at R3", res[0])
+	}
+}
+
+func TestRejection_NoTouchNoSignal(t *testing.T) {
+	base := time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC)
+	m, history := newRejectMonitor(Rejection{Approach: 0.5, Touch: 0.1})
+
+	// Enters the approach band but never within the touch band.
+	feed(m, base, []tick{{0, 99}, {time.Second, 99.7}, {2 * time.Second, 99.8}, {3 * time.Second, 99}})
+	if n := history.Count(); n != 0 {
+		t.Errorf("signals = %d, want 0", n)
+	}
+}
+
+func TestRejection_BreakoutIsNotRejection(t *testing.T) {
+	base := time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC)
+	m, history := newRejectMonitor(Rejection{Approach: 0.5, Touch: 0.1})
+
+	// Touches, crosses and keeps going: only the crossing is emitted.
+	feed(m, base, []tick{{0, 99}, {time.Second, 99.95}, {2 * time.Second, 100.05}, {3 * time.Second, 101}})
+	res := history.Query("", "", "", "", "", 10)
+	if len(res) != 1 || res[0].Kind != signalpkg.KindCross || res[0].Direction != "up" {
+		t.Fatalf("signals = %+v, want one cross up", res)
+	}
+
+	// Retest from above: support rejection.
+	feed(m, base, []tick{{4 * time.Second, 100.3}, {5 * time.Second, 100.08}, {6 * time.Second, 100.6}})
+	res = history.Query("", "", "", "", "", 10)
+	if len(res) != 2 || res[0].Kind != signalpkg.KindReject || res[0].Direction != "up" {
+		t.Fatalf("signals = %+v, want reject up after the cross", res)
+	}
+}
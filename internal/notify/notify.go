@@ -0,0 +1,605 @@
+// Package notify delivers pivot and pattern signals to webhook endpoints.
+package notify
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"example.com/binance-pivot-monitor/internal/pattern"
+	signalpkg "example.com/binance-pivot-monitor/internal/signal"
+	"example.com/binance-pivot-monitor/internal/sse"
+	"example.com/binance-pivot-monitor/internal/subscription"
+)
+
+// Event types carried in Payload.Type and the X-Webhook-Event header.
+const (
+	EventSignal  = subscription.EventSignal
+	EventPattern = subscription.EventPattern
+)
+
+// Request headers. The signature is hex HMAC-SHA256 over
+// "<timestamp>.<body>" with the endpoint secret, prefixed by "sha256=".
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderID        = "X-Webhook-Id"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+const (
+	defaultMaxAttempts = 5
+	defaultBackoff     = time.Second
+	defaultMaxBackoff  = time.Minute
+	defaultTimeout     = 10 * time.Second
+	queueSize          = 256
+
+	defaultMaxDeadLetters = 1000
+)
+
+// Endpoint is one webhook receiver. Empty filters match everything.
+// Levels and Periods only apply to pivot signals, Patterns and
+// MinConfidence only to pattern signals. Kinds (cross, reject) applies to
+// pivot signals. Directions accepts up/down and
+// bullish/bearish; up matches bullish and down matches bearish.
+type Endpoint struct {
+	Name          string   `json:"name"`
+	URL           string   `json:"url"`
+	Secret        string   `json:"secret,omitempty"`
+	Events        []string `json:"events,omitempty"` // signal, pattern
+	Symbols       []string `json:"symbols,omitempty"`
+	Levels        []string `json:"levels,omitempty"`
+	Periods       []string `json:"periods,omitempty"`
+	Directions    []string `json:"directions,omitempty"`
+	Kinds         []string `json:"kinds,omitempty"`
+	Patterns      []string `json:"patterns,omitempty"`
+	MinConfidence int      `json:"min_confidence,omitempty"`
+}
+
+// Config configures a Dispatcher.
+type Config struct {
+	Endpoints []Endpoint
+	// MaxAttempts per delivery, default 5.
+	MaxAttempts int
+	// Backoff is the delay before the first retry, doubled up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Timeout per request, default 10s.
+	Timeout time.Duration
+	// DeadLetterFile stores deliveries that exhausted their attempts.
+	// Empty keeps no dead letters.
+	DeadLetterFile string
+	// MaxDeadLetters caps how many dead letters are kept, default 1000.
+	// Older ones are dropped from the file.
+	MaxDeadLetters int
+	// Client defaults to an http.Client with Timeout.
+	Client *http.Client
+}
+
+// fileConfig is the JSON form of Config; durations are strings like "2s".
+type fileConfig struct {
+	Endpoints      []Endpoint `json:"endpoints"`
+	MaxAttempts    int        `json:"max_attempts"`
+	Backoff        string     `json:"backoff"`
+	MaxBackoff     string     `json:"max_backoff"`
+	Timeout        string     `json:"timeout"`
+	DeadLetterFile string     `json:"dead_letter_file"`
+	MaxDeadLetters int        `json:"max_dead_letters"`
+}
+
+// LoadConfig reads a JSON config file.
+func LoadConfig(path string) (Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	var fc fileConfig
+	if err := json.Unmarshal(b, &fc); err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	cfg := Config{
+		Endpoints:      fc.Endpoints,
+		MaxAttempts:    fc.MaxAttempts,
+		DeadLetterFile: fc.DeadLetterFile,
+		MaxDeadLetters: fc.MaxDeadLetters,
+	}
+	for _, d := range []struct {
+		raw string
+		dst *time.Duration
+	}{{fc.Backoff, &cfg.Backoff}, {fc.MaxBackoff, &cfg.MaxBackoff}, {fc.Timeout, &cfg.Timeout}} {
+		if d.raw == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("%s: %w", path, err)
+		}
+		*d.dst = v
+	}
+	return cfg, nil
+}
+
+// Payload is the JSON body POSTed to endpoints.
+type Payload struct {
+	ID     string          `json:"id"`
+	Type   string          `json:"type"`
+	SentAt time.Time       `json:"sent_at"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// DeadLetter is a delivery that could not be completed.
+type DeadLetter struct {
+	Endpoint  string    `json:"endpoint"`
+	URL       string    `json:"url,omitempty"`
+	Payload   Payload   `json:"payload"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error"`
+	FailedAt  time.Time `json:"failed_at"`
+}
+
+// EndpointStats counts deliveries of one endpoint. The URL is left out as
+// webhook URLs often embed tokens.
+type EndpointStats struct {
+	Name      string `json:"name"`
+	Delivered int64  `json:"delivered"`
+	Retried   int64  `json:"retried"`
+	Failed    int64  `json:"failed"`
+	Queued    int    `json:"queued"`
+}
+
+type endpoint struct {
+	Endpoint
+	matcher *subscription.Matcher
+
+	queue     chan Payload
+	delivered int64
+	retried   int64
+	failed    int64
+}
+
+// Dispatcher fans signals out to endpoints. Each endpoint has its own
+// queue and worker so a slow receiver does not delay the others.
+type Dispatcher struct {
+	cfg       Config
+	client    *http.Client
+	endpoints []*endpoint
+	seq       uint64
+
+	dlqMu sync.Mutex
+	// dlq is the newest MaxDeadLetters dead letters; the file holds them
+	// plus up to as many older ones until it is compacted.
+	dlq      []DeadLetter
+	dlqLines int
+}
+
+// New validates cfg and creates a dispatcher.
+func New(cfg Config) (*Dispatcher, error) {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaultMaxAttempts
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = defaultBackoff
+	}
+	if cfg.MaxBackoff < cfg.Backoff {
+		cfg.MaxBackoff = defaultMaxBackoff
+		if cfg.MaxBackoff < cfg.Backoff {
+			cfg.MaxBackoff = cfg.Backoff
+		}
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.MaxDeadLetters <= 0 {
+		cfg.MaxDeadLetters = defaultMaxDeadLetters
+	}
+	client := cfg.Client
+	if client == nil {
+		client = &http.Client{Timeout: cfg.Timeout}
+	}
+
+	d := &Dispatcher{cfg: cfg, client: client}
+	for i, ep := range cfg.Endpoints {
+		u, err := url.Parse(ep.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("endpoint %d: invalid url %q", i, ep.URL)
+		}
+		if ep.Name == "" {
+			ep.Name = u.Host
+		}
+		for _, ev := range ep.Events {
+			if ev = strings.ToLower(strings.TrimSpace(ev)); ev != EventSignal && ev != EventPattern {
+				return nil, fmt.Errorf("endpoint %s: unknown event %q", ep.Name, ev)
+			}
+		}
+		f := ep.Filter()
+		if err := f.Validate(); err != nil {
+			return nil, fmt.Errorf("endpoint %s: %w", ep.Name, err)
+		}
+		d.endpoints = append(d.endpoints, &endpoint{
+			Endpoint: ep,
+			matcher:  f.Compile(nil),
+			queue:    make(chan Payload, queueSize),
+		})
+	}
+	if cfg.DeadLetterFile != "" {
+		if err := os.MkdirAll(filepath.Dir(cfg.DeadLetterFile), 0o755); err != nil {
+			return nil, err
+		}
+		if err := d.loadDeadLetters(); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// Filter returns the endpoint's filters as a stream subscription filter, so
+// webhooks and stream clients match signals the same way.
+func (ep Endpoint) Filter() subscription.Filter {
+	return subscription.Filter{
+		Events:        ep.Events,
+		Symbols:       ep.Symbols,
+		Periods:       ep.Periods,
+		Levels:        ep.Levels,
+		Directions:    ep.Directions,
+		Kinds:         ep.Kinds,
+		Patterns:      ep.Patterns,
+		MinConfidence: ep.MinConfidence,
+	}
+}
+
+func (e *endpoint) acceptsSignal(sig signalpkg.Signal) bool {
+	return e.matcher.Signal(sig)
+}
+
+func (e *endpoint) acceptsPattern(sig pattern.Signal) bool {
+	return e.matcher.Pattern(sig)
+}
+
+// Run subscribes to the brokers (either may be nil) and delivers until ctx
+// is done. Deliveries still queued or retrying at shutdown are written to
+// the dead-letter file.
+func (d *Dispatcher) Run(ctx context.Context, signals *sse.Broker[signalpkg.Signal], patterns *sse.Broker[pattern.Signal]) {
+	var sigCh chan signalpkg.Signal
+	var patCh chan pattern.Signal
+	if signals != nil {
+		sigCh = signals.SubscribePinned("notify", queueSize)
+		defer signals.Unsubscribe(sigCh)
+	}
+	if patterns != nil {
+		patCh = patterns.SubscribePinned("notify", queueSize)
+		defer patterns.Unsubscribe(patCh)
+	}
+
+	var wg sync.WaitGroup
+	for _, ep := range d.endpoints {
+		wg.Add(1)
+		go func(ep *endpoint) {
+			defer wg.Done()
+			d.worker(ctx, ep)
+		}(ep)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case sig, ok := <-sigCh:
+			if !ok {
+				log.Printf("notify: signal subscription closed")
+				sigCh = nil
+				continue
+			}
+			d.NotifySignal(sig)
+		case sig, ok := <-patCh:
+			if !ok {
+				log.Printf("notify: pattern subscription closed")
+				patCh = nil
+				continue
+			}
+			d.NotifyPattern(sig)
+		}
+	}
+}
+
+// NotifySignal queues a pivot signal for every matching endpoint.
+func (d *Dispatcher) NotifySignal(sig signalpkg.Signal) {
+	var p *Payload
+	for _, ep := range d.endpoints {
+		if !ep.acceptsSignal(sig) {
+			continue
+		}
+		if p == nil {
+			if p = d.payload(EventSignal, sig); p == nil {
+				return
+			}
+		}
+		d.enqueue(ep, *p)
+	}
+}
+
+// NotifyPattern queues a pattern signal for every matching endpoint.
+func (d *Dispatcher) NotifyPattern(sig pattern.Signal) {
+	var p *Payload
+	for _, ep := range d.endpoints {
+		if !ep.acceptsPattern(sig) {
+			continue
+		}
+		if p == nil {
+			if p = d.payload(EventPattern, sig); p == nil {
+				return
+			}
+		}
+		d.enqueue(ep, *p)
+	}
+}
+
+func (d *Dispatcher) payload(typ string, v any) *Payload {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("notify: encode %s: %v", typ, err)
+		return nil
+	}
+	seq := atomic.AddUint64(&d.seq, 1)
+	now := time.Now().UTC()
+	return &Payload{
+		ID:     strconv.FormatInt(now.UnixNano(), 10) + "-" + strconv.FormatUint(seq, 10),
+		Type:   typ,
+		SentAt: now,
+		Data:   data,
+	}
+}
+
+func (d *Dispatcher) enqueue(ep *endpoint, p Payload) {
+	select {
+	case ep.queue <- p:
+	default:
+		atomic.AddInt64(&ep.failed, 1)
+		d.deadLetter(ep, p, 0, errors.New("queue full"))
+	}
+}
+
+func (d *Dispatcher) worker(ctx context.Context, ep *endpoint) {
+	for {
+		select {
+		case <-ctx.Done():
+			// 退出前把队列中未发送的通知写入死信
+			for {
+				select {
+				case p := <-ep.queue:
+					d.deadLetter(ep, p, 0, ctx.Err())
+				default:
+					return
+				}
+			}
+		case p := <-ep.queue:
+			d.deliver(ctx, ep, p)
+		}
+	}
+}
+
+// deliver POSTs p with retries. 4xx responses other than 408 and 429 are
+// not retried.
+func (d *Dispatcher) deliver(ctx context.Context, ep *endpoint, p Payload) {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return
+	}
+
+	backoff := d.cfg.Backoff
+	var lastErr error
+	attempt := 0
+	for attempt < d.cfg.MaxAttempts {
+		attempt++
+		retry, err := d.post(ctx, ep, p, body)
+		if err == nil {
+			atomic.AddInt64(&ep.delivered, 1)
+			return
+		}
+		lastErr = err
+		if !retry || attempt >= d.cfg.MaxAttempts {
+			break
+		}
+		atomic.AddInt64(&ep.retried, 1)
+		t := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			atomic.AddInt64(&ep.failed, 1)
+			d.deadLetter(ep, p, attempt, ctx.Err())
+			return
+		case <-t.C:
+		}
+		backoff *= 2
+		if backoff > d.cfg.MaxBackoff {
+			backoff = d.cfg.MaxBackoff
+		}
+	}
+
+	atomic.AddInt64(&ep.failed, 1)
+	log.Printf("notify %s: giving up on %s after %d attempts: %v", ep.Name, p.ID, attempt, lastErr)
+	d.deadLetter(ep, p, attempt, lastErr)
+}
+
+func (d *Dispatcher) post(ctx context.Context, ep *endpoint, p Payload, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, p.Type)
+	req.Header.Set(HeaderID, p.ID)
+	req.Header.Set(HeaderTimestamp, ts)
+	if ep.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(ep.Secret, ts, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	_ = resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	err = fmt.Errorf("http %d", resp.StatusCode)
+	retry = resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout
+	return retry, err
+}
+
+// Sign returns the X-Webhook-Signature value for body sent at timestamp.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature produced by Sign.
+func Verify(secret, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+func (d *Dispatcher) deadLetter(ep *endpoint, p Payload, attempts int, cause error) {
+	if d.cfg.DeadLetterFile == "" {
+		return
+	}
+	msg := ""
+	if cause != nil {
+		msg = cause.Error()
+	}
+	dl := DeadLetter{
+		Endpoint:  ep.Name,
+		URL:       ep.URL,
+		Payload:   p,
+		Attempts:  attempts,
+		LastError: msg,
+		FailedAt:  time.Now().UTC(),
+	}
+
+	d.dlqMu.Lock()
+	defer d.dlqMu.Unlock()
+	d.dlq = append(d.dlq, dl)
+	if n := len(d.dlq) - d.cfg.MaxDeadLetters; n > 0 {
+		d.dlq = append(d.dlq[:0], d.dlq[n:]...)
+	}
+
+	// 文件行数达到上限的两倍时重写，只保留最新的部分
+	if d.dlqLines+1 >= 2*d.cfg.MaxDeadLetters {
+		if err := d.compactDeadLettersLocked(); err != nil {
+			log.Printf("notify: dead letter write failed: %v", err)
+		}
+		return
+	}
+	f, err := os.OpenFile(d.cfg.DeadLetterFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		log.Printf("notify: dead letter write failed: %v", err)
+		return
+	}
+	if err := json.NewEncoder(f).Encode(dl); err != nil {
+		log.Printf("notify: dead letter write failed: %v", err)
+	}
+	_ = f.Close()
+	d.dlqLines++
+}
+
+// loadDeadLetters reads the dead-letter file, keeping the newest
+// MaxDeadLetters in memory, and compacts it if it holds too many.
+func (d *Dispatcher) loadDeadLetters() error {
+	f, err := os.Open(d.cfg.DeadLetterFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		d.dlqLines++
+		var dl DeadLetter
+		if err := json.Unmarshal(scanner.Bytes(), &dl); err != nil {
+			continue
+		}
+		d.dlq = append(d.dlq, dl)
+		if len(d.dlq) > 2*d.cfg.MaxDeadLetters {
+			d.dlq = append(d.dlq[:0], d.dlq[len(d.dlq)-d.cfg.MaxDeadLetters:]...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if n := len(d.dlq) - d.cfg.MaxDeadLetters; n > 0 {
+		d.dlq = append(d.dlq[:0], d.dlq[n:]...)
+	}
+	if d.dlqLines > d.cfg.MaxDeadLetters {
+		return d.compactDeadLettersLocked()
+	}
+	return nil
+}
+
+// compactDeadLettersLocked rewrites the dead-letter file with the in-memory
+// tail.
+func (d *Dispatcher) compactDeadLettersLocked() error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, dl := range d.dlq {
+		if err := enc.Encode(dl); err != nil {
+			return err
+		}
+	}
+	tmp := d.cfg.DeadLetterFile + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, d.cfg.DeadLetterFile); err != nil {
+		return err
+	}
+	d.dlqLines = len(d.dlq)
+	return nil
+}
+
+// DeadLetters returns the newest MaxDeadLetters dead letters, oldest first.
+func (d *Dispatcher) DeadLetters() []DeadLetter {
+	d.dlqMu.Lock()
+	defer d.dlqMu.Unlock()
+	return append([]DeadLetter(nil), d.dlq...)
+}
+
+// Stats returns delivery counters per endpoint.
+func (d *Dispatcher) Stats() []EndpointStats {
+	out := make([]EndpointStats, len(d.endpoints))
+	for i, ep := range d.endpoints {
+		out[i] = EndpointStats{
+			Name:      ep.Name,
+			Delivered: atomic.LoadInt64(&ep.delivered),
+			Retried:   atomic.LoadInt64(&ep.retried),
+			Failed:    atomic.LoadInt64(&ep.failed),
+			Queued:    len(ep.queue),
+		}
+	}
+	return out
+}
@@ -0,0 +1,353 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"example.com/binance-pivot-monitor/internal/pattern"
+	signalpkg "example.com/binance-pivot-monitor/internal/signal"
+	"example.com/binance-pivot-monitor/internal/sse"
+)
+
+// receiver records webhook requests and answers with the next status code.
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	bodies   [][]byte
+	headers  []http.Header
+	got      chan struct{}
+}
+
+func newReceiver(statuses ...int) (*receiver, *httptest.Server) {
+	rc := &receiver{statuses: statuses, got: make(chan struct{}, 64)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		rc.mu.Lock()
+		rc.bodies = append(rc.bodies, b)
+		rc.headers = append(rc.headers, r.Header.Clone())
+		status := http.StatusOK
+		if len(rc.statuses) > 0 {
+			status = rc.statuses[0]
+			rc.statuses = rc.statuses[1:]
+		}
+		rc.mu.Unlock()
+		w.WriteHeader(status)
+		rc.got <- struct{}{}
+	}))
+	return rc, srv
+}
+
+func (rc *receiver) wait(t *testing.T, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-rc.got:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for request %d", i+1)
+		}
+	}
+}
+
+func (rc *receiver) count() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.bodies)
+}
+
+func start(t *testing.T, d *Dispatcher) (*sse.Broker[signalpkg.Signal], *sse.Broker[pattern.Signal], func()) {
+	t.Helper()
+	sb := sse.NewBroker[signalpkg.Signal]()
+	pb := sse.NewBroker[pattern.Signal]()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx, sb, pb)
+		close(done)
+	}()
+	for sb.SubscriberCount() == 0 || pb.SubscriberCount() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	return sb, pb, func() {
+		cancel()
+		<-done
+	}
+}
+
+func TestDispatcher_DeliversSigned(t *testing.T) {
+	rc, srv := newReceiver()
+	defer srv.Close()
+
+	d, err := New(Config{Endpoints: []Endpoint{{Name: "ops", URL: srv.URL, Secret: "s3cret"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sb, _, stop := start(t, d)
+	defer stop()
+
+	sb.Publish(signalpkg.Signal{ID: "1", Symbol: "BTCUSDT", Period: "1d", Level: "R4", Direction: "up", Price: 100})
+	rc.wait(t, 1)
+
+	rc.mu.Lock()
+	body, h := rc.bodies[0], rc.headers[0]
+	rc.mu.Unlock()
+
+	if h.Get(HeaderEvent) != EventSignal || h.Get("Content-Type") != "application/json" {
+		t.Errorf("headers = %v", h)
+	}
+	if !Verify("s3cret", h.Get(HeaderTimestamp), body, h.Get(HeaderSignature)) {
+		t.Errorf("bad signature %q", h.Get(HeaderSignature))
+	}
+	if Verify("other", h.Get(HeaderTimestamp), body, h.Get(HeaderSignature)) {
+		t.Error("signature verified with the wrong secret")
+	}
+
+	var p Payload
+	if err := json.Unmarshal(body, &p); err != nil {
+		t.Fatal(err)
+	}
+	var sig signalpkg.Signal
+	_ = json.Unmarshal(p.Data, &sig)
+	if p.Type != EventSignal || p.ID != h.Get(HeaderID) || sig.Symbol != "BTCUSDT" || sig.Level != "R4" {
+		t.Errorf("payload = %+v, signal = %+v", p, sig)
+	}
+}
+
+func TestDispatcher_Filters(t *testing.T) {
+	rc, srv := newReceiver()
+	defer srv.Close()
+
+	d, err := New(Config{Endpoints: []Endpoint{{
+		URL:           srv.URL,
+		Symbols:       []string{"btcusdt"},
+		Levels:        []string{"R4", "S4"},
+		Periods:       []string{"1d"},
+		Directions:    []string{"up"},
+		MinConfidence: 70,
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		sig  signalpkg.Signal
+		want bool
+	}{
+		{signalpkg.Signal{Symbol: "BTCUSDT", Period: "1d", Level: "R4", Direction: "up"}, true},
+		{signalpkg.Signal{Symbol: "ETHUSDT", Period: "1d", Level: "R4", Direction: "up"}, false},
+		{signalpkg.Signal{Symbol: "BTCUSDT", Period: "1w", Level: "R4", Direction: "up"}, false},
+		{signalpkg.Signal{Symbol: "BTCUSDT", Period: "1d", Level: "R3", Direction: "up"}, false},
+		{signalpkg.Signal{Symbol: "BTCUSDT", Period: "1d", Level: "S4", Direction: "down"}, false},
+	}
+	for i, c := range cases {
+		if got := d.endpoints[0].acceptsSignal(c.sig); got != c.want {
+			t.Errorf("case %d: accepts = %v, want %v", i, got, c.want)
+		}
+	}
+
+	// 形态信号：up 对应 bullish，置信度需达到阈值；level/period 不适用
+	ep := d.endpoints[0]
+	if !ep.acceptsPattern(pattern.Signal{Symbol: "BTCUSDT", Direction: pattern.DirectionBullish, Confidence: 80}) {
+		t.Error("bullish pattern with confidence 80 should match")
+	}
+	if ep.acceptsPattern(pattern.Signal{Symbol: "BTCUSDT", Direction: pattern.DirectionBullish, Confidence: 60}) {
+		t.Error("confidence below minimum should not match")
+	}
+	if ep.acceptsPattern(pattern.Signal{Symbol: "BTCUSDT", Direction: pattern.DirectionBearish, Confidence: 80}) {
+		t.Error("bearish pattern should not match direction up")
+	}
+
+	rejects, err := New(Config{Endpoints: []Endpoint{{URL: srv.URL, Kinds: []string{"reject"}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rejects.endpoints[0].acceptsSignal(cases[0].sig) {
+		t.Error("reject-only endpoint accepted a crossing")
+	}
+	reject := cases[0].sig
+	reject.Kind = signalpkg.KindReject
+	if !rejects.endpoints[0].acceptsSignal(reject) {
+		t.Error("reject-only endpoint dropped a reject signal")
+	}
+	if _, err := New(Config{Endpoints: []Endpoint{{URL: srv.URL, Kinds: []string{"bounce"}}}}); err == nil {
+		t.Error("expected error for unknown kind")
+	}
+
+	onlyPatterns, _ := New(Config{Endpoints: []Endpoint{{URL: srv.URL, Events: []string{"pattern"}}}})
+	if onlyPatterns.endpoints[0].acceptsSignal(cases[0].sig) {
+		t.Error("pattern-only endpoint accepted a pivot signal")
+	}
+	if rc.count() != 0 {
+		t.Error("filters must not send requests")
+	}
+}
+
+func TestDispatcher_RetryThenDeadLetter(t *testing.T) {
+	rc, srv := newReceiver(http.StatusServiceUnavailable, http.StatusOK)
+	defer srv.Close()
+	dlq := filepath.Join(t.TempDir(), "notify", "dlq.jsonl")
+
+	d, err := New(Config{
+		Endpoints:      []Endpoint{{Name: "a", URL: srv.URL}},
+		MaxAttempts:    3,
+		Backoff:        time.Millisecond,
+		DeadLetterFile: dlq,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sb, pb, stop := start(t, d)
+
+	// 503 后重试成功
+	sb.Publish(signalpkg.Signal{ID: "1", Symbol: "BTCUSDT"})
+	rc.wait(t, 2)
+
+	// 400 不重试，直接进入死信
+	rc.mu.Lock()
+	rc.statuses = []int{http.StatusBadRequest}
+	rc.mu.Unlock()
+	pb.Publish(pattern.Signal{ID: "p1", Symbol: "ETHUSDT", Confidence: 90})
+	rc.wait(t, 1)
+
+	// 持续 500，耗尽 3 次尝试
+	rc.mu.Lock()
+	rc.statuses = []int{500, 500, 500}
+	rc.mu.Unlock()
+	sb.Publish(signalpkg.Signal{ID: "2", Symbol: "BTCUSDT"})
+	rc.wait(t, 3)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for d.Stats()[0].Failed < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	stop()
+
+	st := d.Stats()[0]
+	if st.Delivered != 1 || st.Failed != 2 || st.Retried != 3 {
+		t.Errorf("stats = %+v, want delivered 1, failed 2, retried 3", st)
+	}
+
+	dls := d.DeadLetters()
+	if len(dls) != 2 {
+		t.Fatalf("dead letters = %d, want 2", len(dls))
+	}
+	if dls[0].Payload.Type != EventPattern || dls[0].Attempts != 1 || dls[0].LastError != "http 400" {
+		t.Errorf("first dead letter = %+v", dls[0])
+	}
+	if dls[1].Payload.Type != EventSignal || dls[1].Attempts != 3 || dls[1].Endpoint != "a" {
+		t.Errorf("second dead letter = %+v", dls[1])
+	}
+
+	// 死信跨重启保留
+	d2, _ := New(Config{Endpoints: []Endpoint{{URL: srv.URL}}, DeadLetterFile: dlq})
+	if again := d2.DeadLetters(); len(again) != 2 {
+		t.Errorf("reloaded dead letters = %d, want 2", len(again))
+	}
+}
+
+func TestDispatcher_ShutdownDeadLetters(t *testing.T) {
+	var hits int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+	dlq := filepath.Join(t.TempDir(), "dlq.jsonl")
+
+	d, _ := New(Config{Endpoints: []Endpoint{{URL: srv.URL}}, DeadLetterFile: dlq})
+	for i := 0; i < 3; i++ {
+		d.NotifySignal(signalpkg.Signal{Symbol: "BTCUSDT"})
+	}
+	_, _, stop := start(t, d)
+	for atomic.LoadInt32(&hits) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	stop()
+
+	dls := d.DeadLetters()
+	if len(dls) != 3 {
+		t.Errorf("dead letters after shutdown = %d, want 3", len(dls))
+	}
+}
+
+func TestDispatcher_DeadLetterCap(t *testing.T) {
+	dlq := filepath.Join(t.TempDir(), "dlq.jsonl")
+	d, err := New(Config{Endpoints: []Endpoint{{Name: "a", URL: "http://x"}}, DeadLetterFile: dlq, MaxDeadLetters: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := func() int {
+		b, _ := os.ReadFile(dlq)
+		return strings.Count(string(b), "\n")
+	}
+	for i := 1; i <= 7; i++ {
+		d.deadLetter(d.endpoints[0], Payload{ID: strconv.Itoa(i)}, 1, errors.New("boom"))
+		if n := lines(); n >= 6 {
+			t.Fatalf("file has %d lines after %d dead letters, want < 6", n, i)
+		}
+	}
+
+	ids := func(dls []DeadLetter) string {
+		var out []string
+		for _, dl := range dls {
+			out = append(out, dl.Payload.ID)
+		}
+		return strings.Join(out, ",")
+	}
+	if got := ids(d.DeadLetters()); got != "5,6,7" {
+		t.Errorf("dead letters = %s, want 5,6,7", got)
+	}
+
+	// 重启后只加载最新的部分，并压缩文件
+	d2, err := New(Config{Endpoints: []Endpoint{{URL: "http://x"}}, DeadLetterFile: dlq, MaxDeadLetters: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(d2.DeadLetters()); got != "5,6,7" {
+		t.Errorf("reloaded dead letters = %s, want 5,6,7", got)
+	}
+	if n := lines(); n != 3 {
+		t.Errorf("file has %d lines after reload, want 3", n)
+	}
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	if _, err := New(Config{Endpoints: []Endpoint{{URL: "ftp://x"}}}); err == nil {
+		t.Error("expected error for non-http url")
+	}
+	if _, err := New(Config{Endpoints: []Endpoint{{URL: "http://x", Events: []string{"ticker"}}}}); err == nil {
+		t.Error("expected error for unknown event")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "webhooks.json")
+	_ = os.WriteFile(path, []byte(`{
+  "max_attempts": 4,
+  "backoff": "500ms",
+  "endpoints": [{"name": "ops", "url": "https://example.com/hook", "levels": ["R4"]}]
+}`), 0o644)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MaxAttempts != 4 || cfg.Backoff != 500*time.Millisecond || len(cfg.Endpoints) != 1 || cfg.Endpoints[0].Levels[0] != "R4" {
+		t.Errorf("cfg = %+v", cfg)
+	}
+}
@@ -162,6 +162,7 @@ type QueryOptions struct {
 	Symbol    string
 	Pattern   PatternType
 	Direction Direction
+	Timeframe string
 	Limit     int
 	Since     time.Time
 }
@@ -187,6 +188,9 @@ func (h *History) Query(opts QueryOptions) []Signal {
 		if opts.Direction != "" && sig.Direction != opts.Direction {
 			continue
 		}
+		if opts.Timeframe != "" && sig.Timeframe != opts.Timeframe {
+			continue
+		}
 		if !opts.Since.IsZero() && sig.DetectedAt.Before(opts.Since) {
 			continue
 		}
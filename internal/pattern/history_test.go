@@ -95,6 +95,23 @@ func TestHistory_Query(t *testing.T) {
 	}
 }
 
+func TestHistory_QueryTimeframe(t *testing.T) {
+	h, _ := NewHistory("", 100)
+
+	klineTime := time.Now()
+	base := NewSignal("BTCUSDT", PatternHammer, DirectionBullish, 75, klineTime)
+	h.Add(base.WithTimeframe("15m"))
+	h.Add(base.WithTimeframe("1h"))
+
+	results := h.Query(QueryOptions{Timeframe: "1h"})
+	if len(results) != 1 || results[0].Timeframe != "1h" || results[0].ID != base.ID+"-1h" {
+		t.Errorf("Query by timeframe: %+v", results)
+	}
+	if len(h.Query(QueryOptions{})) != 2 {
+		t.Error("same pattern on two timeframes should be two signals")
+	}
+}
+
 func TestHistory_Persistence(t *testing.T) {
 	// Create temp directory
 	tmpDir, err := os.MkdirTemp("", "pattern_history_test")
@@ -0,0 +1,392 @@
+package pattern
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"example.com/binance-pivot-monitor/internal/kline"
+)
+
+// KlineSource provides closed klines per symbol. *kline.Store implements it.
+type KlineSource interface {
+	GetKlines(symbol string) ([]kline.Kline, bool)
+}
+
+// LiveStatsConfig configures empirical pattern statistics.
+type LiveStatsConfig struct {
+	// Horizon is the number of klines after the pattern kline whose close
+	// decides the outcome. Default 3.
+	Horizon int
+	// PriorWeight is how many samples the static table counts as. Default 20.
+	PriorWeight float64
+	// MinSamples is the number of outcomes before the efficiency rank is
+	// derived from live data instead of the static table. Default 30.
+	MinSamples int
+	// MaxOutcomes caps the stored outcomes, oldest dropped first. Default 20000.
+	MaxOutcomes int
+	// FilePath persists outcomes; empty keeps them in memory only.
+	FilePath string
+	// Timeframe limits outcomes to signals detected on this timeframe, the
+	// one the klines passed to Recompute belong to. Signals without a
+	// timeframe are always included.
+	Timeframe string
+}
+
+// PatternOutcome is what price did after one detected pattern.
+type PatternOutcome struct {
+	SignalID  string      `json:"signal_id"`
+	Symbol    string      `json:"symbol"`
+	Pattern   PatternType `json:"pattern"`
+	Direction Direction   `json:"direction"`
+	KlineTime time.Time   `json:"kline_time"`
+	Entry     float64     `json:"entry"`
+	Exit      float64     `json:"exit"`
+	Return    float64     `json:"return"` // percent
+}
+
+// LiveStat is the empirical statistic of one pattern.
+type LiveStat struct {
+	Pattern PatternType `json:"pattern"`
+	Samples int         `json:"samples"`
+	Up      int         `json:"up"`
+	Down    int         `json:"down"`
+	// Hits counts bullish signals followed by a rise and bearish signals
+	// followed by a fall, out of Directional moved outcomes.
+	Hits        int `json:"hits"`
+	Directional int `json:"directional"`
+	// RawUpPercent is the live up ratio without the prior.
+	RawUpPercent float64 `json:"raw_up_percent"`
+	AvgReturn    float64 `json:"avg_return"`
+	// UpPercent, DownPercent and EfficiencyRank are what signals carry:
+	// live outcomes blended with the static prior.
+	UpPercent      int    `json:"up_percent"`
+	DownPercent    int    `json:"down_percent"`
+	EfficiencyRank string `json:"efficiency_rank"`
+}
+
+// LiveStats derives pattern statistics from the server's own detections.
+// Recompute resolves outcomes of detected patterns from kline data and
+// installs the blended statistics so new signals use them.
+type LiveStats struct {
+	cfg LiveStatsConfig
+
+	mu        sync.RWMutex
+	outcomes  map[string]PatternOutcome
+	stats     map[PatternType]LiveStat
+	updatedAt time.Time
+}
+
+// NewLiveStats creates the engine and loads persisted outcomes.
+func NewLiveStats(cfg LiveStatsConfig) *LiveStats {
+	if cfg.Horizon <= 0 {
+		cfg.Horizon = 3
+	}
+	if cfg.PriorWeight <= 0 {
+		cfg.PriorWeight = 20
+	}
+	if cfg.MinSamples <= 0 {
+		cfg.MinSamples = 30
+	}
+	if cfg.MaxOutcomes <= 0 {
+		cfg.MaxOutcomes = 20000
+	}
+	e := &LiveStats{
+		cfg:      cfg,
+		outcomes: make(map[string]PatternOutcome),
+		stats:    make(map[PatternType]LiveStat),
+	}
+	if cfg.FilePath != "" {
+		if err := e.load(); err != nil && !os.IsNotExist(err) {
+			log.Printf("WARN: pattern stats load failed: %v", err)
+		}
+	}
+	return e
+}
+
+// Config returns the effective configuration.
+func (e *LiveStats) Config() LiveStatsConfig {
+	return e.cfg
+}
+
+// Run recomputes statistics every interval until ctx is done.
+func (e *LiveStats) Run(ctx context.Context, history *History, klines KlineSource, every time.Duration) {
+	if every <= 0 {
+		every = 15 * time.Minute
+	}
+	e.Recompute(history.Recent(0), klines)
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			e.Recompute(history.Recent(0), klines)
+		}
+	}
+}
+
+// Recompute resolves the outcomes of signals whose horizon has passed,
+// re-aggregates all outcomes and installs the result via SetLiveStats.
+func (e *LiveStats) Recompute(signals []Signal, klines KlineSource) {
+	e.mu.Lock()
+	added := 0
+	cache := make(map[string][]kline.Kline)
+	for _, sig := range signals {
+		if _, ok := e.outcomes[sig.ID]; ok || sig.ID == "" {
+			continue
+		}
+		if e.cfg.Timeframe != "" && sig.Timeframe != "" && sig.Timeframe != e.cfg.Timeframe {
+			continue
+		}
+		ks, ok := cache[sig.Symbol]
+		if !ok {
+			ks, _ = klines.GetKlines(sig.Symbol)
+			cache[sig.Symbol] = ks
+		}
+		if o, ok := resolveOutcome(sig, ks, e.cfg.Horizon); ok {
+			e.outcomes[sig.ID] = o
+			added++
+		}
+	}
+	e.trimLocked()
+	e.stats = e.aggregateLocked()
+	e.updatedAt = time.Now().UTC()
+	installed := e.installLocked()
+	total := len(e.outcomes)
+	var snapshot []PatternOutcome
+	if added > 0 && e.cfg.FilePath != "" {
+		snapshot = e.sortedOutcomesLocked()
+	}
+	e.mu.Unlock()
+
+	SetLiveStats(installed)
+	if snapshot != nil {
+		if err := saveOutcomes(e.cfg.FilePath, snapshot); err != nil {
+			log.Printf("WARN: pattern stats save failed: %v", err)
+		}
+	}
+	if added > 0 {
+		log.Printf("pattern stats: resolved %d new outcomes, total %d", added, total)
+	}
+}
+
+// resolveOutcome finds the pattern kline and the kline Horizon bars later.
+func resolveOutcome(sig Signal, klines []kline.Kline, horizon int) (PatternOutcome, bool) {
+	for i := range klines {
+		k := klines[i]
+		if !k.CloseTime.Equal(sig.KlineTime) && !k.OpenTime.Equal(sig.KlineTime) {
+			continue
+		}
+		j := i + horizon
+		if j >= len(klines) || k.Close <= 0 {
+			return PatternOutcome{}, false
+		}
+		exit := klines[j].Close
+		return PatternOutcome{
+			SignalID:  sig.ID,
+			Symbol:    sig.Symbol,
+			Pattern:   sig.Pattern,
+			Direction: sig.Direction,
+			KlineTime: sig.KlineTime,
+			Entry:     k.Close,
+			Exit:      exit,
+			Return:    (exit - k.Close) / k.Close * 100,
+		}, true
+	}
+	return PatternOutcome{}, false
+}
+
+func (e *LiveStats) trimLocked() {
+	if len(e.outcomes) <= e.cfg.MaxOutcomes {
+		return
+	}
+	all := e.sortedOutcomesLocked()
+	for _, o := range all[:len(all)-e.cfg.MaxOutcomes] {
+		delete(e.outcomes, o.SignalID)
+	}
+}
+
+func (e *LiveStats) sortedOutcomesLocked() []PatternOutcome {
+	out := make([]PatternOutcome, 0, len(e.outcomes))
+	for _, o := range e.outcomes {
+		out = append(out, o)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].KlineTime.Equal(out[j].KlineTime) {
+			return out[i].KlineTime.Before(out[j].KlineTime)
+		}
+		return out[i].SignalID < out[j].SignalID
+	})
+	return out
+}
+
+func (e *LiveStats) aggregateLocked() map[PatternType]LiveStat {
+	sums := make(map[PatternType]float64)
+	stats := make(map[PatternType]LiveStat)
+	for _, o := range e.outcomes {
+		st := stats[o.Pattern]
+		st.Pattern = o.Pattern
+		switch {
+		case o.Return > 0:
+			st.Up++
+		case o.Return < 0:
+			st.Down++
+		}
+		if o.Return != 0 && o.Direction != DirectionNeutral {
+			st.Directional++
+			if (o.Return > 0) == (o.Direction == DirectionBullish) {
+				st.Hits++
+			}
+		}
+		st.Samples++
+		sums[o.Pattern] += o.Return
+		stats[o.Pattern] = st
+	}
+
+	for pt, st := range stats {
+		st.AvgReturn = sums[pt] / float64(st.Samples)
+		if moved := st.Up + st.Down; moved > 0 {
+			st.RawUpPercent = float64(st.Up) / float64(moved) * 100
+		}
+		stats[pt] = e.blend(st)
+	}
+	return stats
+}
+
+// blend mixes live counts with the static table, which counts as
+// PriorWeight samples. Patterns without a static entry use a 50/50 prior.
+func (e *LiveStats) blend(st LiveStat) LiveStat {
+	static, ok := PatternStatsMap[st.Pattern]
+	priorUp := 50.0
+	if ok && static.UpPercent+static.DownPercent > 0 {
+		priorUp = float64(static.UpPercent) / float64(static.UpPercent+static.DownPercent) * 100
+	}
+	moved := float64(st.Up + st.Down)
+	up := (priorUp*e.cfg.PriorWeight + float64(st.Up)*100) / (e.cfg.PriorWeight + moved)
+	st.UpPercent = int(math.Round(up))
+	st.DownPercent = 100 - st.UpPercent
+
+	st.EfficiencyRank = static.EfficiencyRank
+	if st.Samples >= e.cfg.MinSamples {
+		st.EfficiencyRank = efficiencyRank(st)
+	}
+	return st
+}
+
+// rankBands maps the hit rate in the signal's direction to an efficiency
+// rank on the same A+ ~ J- scale as the static table.
+var rankBands = []struct {
+	min  int
+	rank string
+}{
+	{75, "A+"}, {71, "A"}, {68, "A-"},
+	{66, "B+"}, {64, "B"}, {62, "B-"},
+	{60, "C+"}, {58, "C"}, {56, "C-"},
+	{55, "D+"}, {54, "D"}, {53, "D-"},
+	{52, "E+"}, {51, "E"}, {50, "E-"},
+	{49, "F+"}, {48, "F"}, {47, "F-"},
+	{46, "G+"}, {45, "G"}, {44, "G-"},
+	{43, "H+"}, {42, "H"}, {41, "H-"},
+	{39, "J+"}, {37, "J"},
+}
+
+// efficiencyRank rates directional patterns by their hit rate and neutral
+// ones by how one-sided the following move was.
+func efficiencyRank(st LiveStat) string {
+	hit := 0.0
+	if st.Directional > 0 {
+		hit = float64(st.Hits) / float64(st.Directional) * 100
+	} else {
+		hit = math.Max(st.RawUpPercent, 100-st.RawUpPercent)
+	}
+	for _, b := range rankBands {
+		if hit >= float64(b.min) {
+			return b.rank
+		}
+	}
+	return "J-"
+}
+
+// installLocked builds the table used by GetStats: every static pattern,
+// with live-blended percentages and rank where outcomes exist.
+func (e *LiveStats) installLocked() map[PatternType]PatternStats {
+	out := make(map[PatternType]PatternStats, len(PatternStatsMap))
+	for pt, static := range PatternStatsMap {
+		out[pt] = static
+	}
+	for pt, st := range e.stats {
+		ps := out[pt]
+		ps.UpPercent = st.UpPercent
+		ps.DownPercent = st.DownPercent
+		ps.EfficiencyRank = st.EfficiencyRank
+		ps.StatsSource = "live"
+		ps.IsEstimated = st.Samples < e.cfg.MinSamples
+		out[pt] = ps
+	}
+	return out
+}
+
+// Stats returns the live statistics sorted by pattern and the time they
+// were computed.
+func (e *LiveStats) Stats() ([]LiveStat, time.Time) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	out := make([]LiveStat, 0, len(e.stats))
+	for _, st := range e.stats {
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Pattern < out[j].Pattern })
+	return out, e.updatedAt
+}
+
+// Get returns the live statistic of one pattern.
+func (e *LiveStats) Get(pt PatternType) (LiveStat, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	st, ok := e.stats[pt]
+	return st, ok
+}
+
+func (e *LiveStats) load() error {
+	b, err := os.ReadFile(e.cfg.FilePath)
+	if err != nil {
+		return err
+	}
+	var outcomes []PatternOutcome
+	if err := json.Unmarshal(b, &outcomes); err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, o := range outcomes {
+		if o.SignalID != "" {
+			e.outcomes[o.SignalID] = o
+		}
+	}
+	e.trimLocked()
+	e.stats = e.aggregateLocked()
+	return nil
+}
+
+func saveOutcomes(path string, outcomes []PatternOutcome) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	b, err := json.Marshal(outcomes)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,117 @@
+package pattern
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"example.com/binance-pivot-monitor/internal/kline"
+)
+
+type fakeKlines map[string][]kline.Kline
+
+func (f fakeKlines) GetKlines(symbol string) ([]kline.Kline, bool) {
+	ks, ok := f[symbol]
+	return ks, ok
+}
+
+// series builds 15m klines with the given closes starting at t0.
+func series(symbol string, t0 time.Time, closes ...float64) []kline.Kline {
+	out := make([]kline.Kline, len(closes))
+	for i, c := range closes {
+		open := t0.Add(time.Duration(i) * 15 * time.Minute)
+		out[i] = kline.Kline{
+			Symbol: symbol, Open: c, High: c, Low: c, Close: c,
+			OpenTime: open, CloseTime: open.Add(15 * time.Minute), IsClosed: true,
+		}
+	}
+	return out
+}
+
+func TestLiveStats_Recompute(t *testing.T) {
+	defer SetLiveStats(nil)
+
+	t0 := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	ks := series("BTCUSDT", t0, 100, 101, 102, 103, 99, 98, 97)
+	src := fakeKlines{"BTCUSDT": ks}
+
+	sigs := []Signal{
+		// 锤子线在 100 收盘，3 根后收于 103：上涨
+		{ID: "a", Symbol: "BTCUSDT", Pattern: PatternHammer, Direction: DirectionBullish, KlineTime: ks[0].CloseTime},
+		// 103 收盘，3 根后 97：下跌
+		{ID: "b", Symbol: "BTCUSDT", Pattern: PatternHammer, Direction: DirectionBullish, KlineTime: ks[3].CloseTime},
+		// 后续K线不足，暂不结算
+		{ID: "c", Symbol: "BTCUSDT", Pattern: PatternHammer, Direction: DirectionBullish, KlineTime: ks[5].CloseTime},
+	}
+
+	e := NewLiveStats(LiveStatsConfig{Horizon: 3, PriorWeight: 2, MinSamples: 2})
+	e.Recompute(sigs, src)
+
+	st, ok := e.Get(PatternHammer)
+	if !ok {
+		t.Fatal("no live stat for hammer")
+	}
+	if st.Samples != 2 || st.Up != 1 || st.Down != 1 || st.Hits != 1 || st.Directional != 2 {
+		t.Fatalf("stat = %+v", st)
+	}
+	// 先验 60%（权重 2）+ 实盘 1/2 => (60*2 + 100) / 4 = 55
+	if st.UpPercent != 55 || st.DownPercent != 45 {
+		t.Errorf("blended = %d/%d, want 55/45", st.UpPercent, st.DownPercent)
+	}
+	if st.EfficiencyRank != "E-" {
+		t.Errorf("rank = %q, want E- for a 50%% hit rate", st.EfficiencyRank)
+	}
+
+	got, _ := GetStats(PatternHammer)
+	if got.UpPercent != 55 || got.StatsSource != "live" || got.Source != "custom" {
+		t.Errorf("installed = %+v", got)
+	}
+	sig := NewSignal("BTCUSDT", PatternHammer, DirectionBullish, 80, t0)
+	if sig.UpPercent != 55 || sig.EfficiencyRank != "E-" {
+		t.Errorf("signal stats = %d %q", sig.UpPercent, sig.EfficiencyRank)
+	}
+
+	// 无实盘数据的形态保持静态表
+	if got, _ := GetStats(PatternShootingStar); got != PatternStatsMap[PatternShootingStar] {
+		t.Errorf("shooting star = %+v, want static", got)
+	}
+}
+
+func TestLiveStats_RankNeedsMinSamples(t *testing.T) {
+	defer SetLiveStats(nil)
+
+	t0 := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	ks := series("ETHUSDT", t0, 10, 11, 12, 13)
+	e := NewLiveStats(LiveStatsConfig{Horizon: 1, MinSamples: 5})
+	e.Recompute([]Signal{
+		{ID: "a", Symbol: "ETHUSDT", Pattern: PatternEngulfing, Direction: DirectionBullish, KlineTime: ks[0].CloseTime},
+	}, fakeKlines{"ETHUSDT": ks})
+
+	st, _ := e.Get(PatternEngulfing)
+	if st.EfficiencyRank != PatternStatsMap[PatternEngulfing].EfficiencyRank {
+		t.Errorf("rank = %q, want static until MinSamples", st.EfficiencyRank)
+	}
+	if got, _ := GetStats(PatternEngulfing); !got.IsEstimated {
+		t.Error("stats below MinSamples should be marked estimated")
+	}
+}
+
+func TestLiveStats_Persistence(t *testing.T) {
+	defer SetLiveStats(nil)
+
+	path := filepath.Join(t.TempDir(), "stats.json")
+	t0 := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	ks := series("BTCUSDT", t0, 100, 90)
+	sigs := []Signal{{ID: "a", Symbol: "BTCUSDT", Pattern: PatternShootingStar, Direction: DirectionBearish, KlineTime: ks[0].CloseTime}}
+
+	e := NewLiveStats(LiveStatsConfig{Horizon: 1, FilePath: path})
+	e.Recompute(sigs, fakeKlines{"BTCUSDT": ks})
+
+	// 重启后K线已不在内存中，结果仍从文件恢复
+	e2 := NewLiveStats(LiveStatsConfig{Horizon: 1, FilePath: path})
+	e2.Recompute(sigs, fakeKlines{})
+	st, ok := e2.Get(PatternShootingStar)
+	if !ok || st.Samples != 1 || st.Down != 1 || st.Hits != 1 {
+		t.Fatalf("reloaded = %+v, ok=%v", st, ok)
+	}
+}
@@ -20,12 +20,13 @@ type Signal struct {
 	StatsSource    string      `json:"stats_source"`    // Statistics data source
 	IsEstimated    bool        `json:"is_estimated"`    // Whether stats are estimated
 	KlineTime      time.Time   `json:"kline_time"`      // Kline close time
+	Timeframe      string      `json:"timeframe,omitempty"` // Kline interval, e.g. "15m"
 	DetectedAt     time.Time   `json:"detected_at"`
 }
 
 // NewSignal creates a new pattern signal with statistics populated.
 func NewSignal(symbol string, pattern PatternType, direction Direction, confidence int, klineTime time.Time) Signal {
-	stats := PatternStatsMap[pattern]
+	stats, _ := GetStats(pattern)
 	return Signal{
 		ID:             generateID(symbol, pattern, klineTime),
 		Symbol:         symbol,
@@ -44,6 +45,18 @@ func NewSignal(symbol string, pattern PatternType, direction Direction, confiden
 	}
 }
 
+// WithTimeframe returns the signal tagged with the kline timeframe it was
+// detected on. The timeframe is appended to the ID so that the same pattern
+// closing on two timeframes at once yields two signals.
+func (s Signal) WithTimeframe(timeframe string) Signal {
+	if timeframe == "" {
+		return s
+	}
+	s.Timeframe = timeframe
+	s.ID += "-" + timeframe
+	return s
+}
+
 // generateID generates a unique signal ID using symbol + pattern + klineTime.
 // Format: {klineTime_unix_nano}-{symbol}-{pattern}
 func generateID(symbol string, pattern PatternType, klineTime time.Time) string {
@@ -1,5 +1,7 @@
 package pattern
 
+import "sync/atomic"
+
 // PatternStats holds statistical data for a pattern.
 type PatternStats struct {
 	UpPercent      int    // Historical up probability
@@ -52,9 +54,22 @@ var PatternStatsMap = map[PatternType]PatternStats{
 	PatternGravestoneDoji:  {43, 57, "C+", "E", "custom", "fivehundred.co", false},
 }
 
+// liveStats, when set, replaces PatternStatsMap in GetStats.
+var liveStats atomic.Pointer[map[PatternType]PatternStats]
+
+// SetLiveStats installs statistics derived from live data. nil restores
+// the static table.
+func SetLiveStats(m map[PatternType]PatternStats) {
+	if m == nil {
+		liveStats.Store(nil)
+		return
+	}
+	liveStats.Store(&m)
+}
+
 // IsHighEfficiency returns true if the pattern has efficiency rank A or B.
 func IsHighEfficiency(pt PatternType) bool {
-	stats, ok := PatternStatsMap[pt]
+	stats, ok := GetStats(pt)
 	if !ok {
 		return false
 	}
@@ -75,8 +90,14 @@ func GetHighEfficiencyPatterns() []PatternType {
 	return result
 }
 
-// GetStats returns the statistics for a pattern type.
+// GetStats returns the statistics for a pattern type, live-blended if
+// SetLiveStats has been called.
 func GetStats(pt PatternType) (PatternStats, bool) {
+	if m := liveStats.Load(); m != nil {
+		if stats, ok := (*m)[pt]; ok {
+			return stats, true
+		}
+	}
 	stats, ok := PatternStatsMap[pt]
 	return stats, ok
 }
@@ -0,0 +1,148 @@
+package pivot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// archiveLayout names archived snapshots by session start (UTC), so file
+// names sort by time.
+const archiveLayout = "20060102T1504Z"
+
+// ErrNotArchived is returned when no archived session matches a lookup.
+var ErrNotArchived = errors.New("no archived pivots")
+
+// Archive keeps every snapshot the refresher writes, one file per period and
+// session: <Dir>/<period>/<session start>.json. Rewrites of the same session,
+// such as late symbols merged in, replace its file.
+type Archive struct {
+	Dir string
+	// MaxAge prunes sessions that started longer ago on Save; 0 keeps all.
+	MaxAge time.Duration
+
+	now func() time.Time
+}
+
+// NewArchive creates an archive rooted at dir.
+func NewArchive(dir string) *Archive {
+	return &Archive{Dir: dir, now: time.Now}
+}
+
+func (a *Archive) periodDir(period Period) string {
+	return filepath.Join(a.Dir, strings.TrimSuffix(period.fileName(), ".json"))
+}
+
+// Save archives snap. Snapshots without a session start are skipped.
+func (a *Archive) Save(snap *Snapshot) error {
+	if snap == nil || snap.SessionStart.IsZero() {
+		return nil
+	}
+	b, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	dir := a.periodDir(snap.Period)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	path := filepath.Join(dir, snap.SessionStart.UTC().Format(archiveLayout)+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	if a.MaxAge > 0 {
+		a.prune(snap.Period, a.now().Add(-a.MaxAge))
+	}
+	return nil
+}
+
+func (a *Archive) prune(period Period, before time.Time) {
+	sessions, err := a.Sessions(period)
+	if err != nil {
+		return
+	}
+	for _, t := range sessions {
+		if !t.Before(before) {
+			break
+		}
+		_ = os.Remove(filepath.Join(a.periodDir(period), t.Format(archiveLayout)+".json"))
+	}
+}
+
+// Sessions lists the archived session starts of period, oldest first.
+func (a *Archive) Sessions(period Period) ([]time.Time, error) {
+	entries, err := os.ReadDir(a.periodDir(period))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var out []time.Time
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok || e.IsDir() {
+			continue
+		}
+		t, err := time.Parse(archiveLayout, name)
+		if err != nil {
+			continue
+		}
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Before(out[j]) })
+	return out, nil
+}
+
+// At returns the snapshot in effect at t: the latest session that started
+// at or before t.
+func (a *Archive) At(period Period, t time.Time) (*Snapshot, error) {
+	sessions, err := a.Sessions(period)
+	if err != nil {
+		return nil, err
+	}
+	i := sort.Search(len(sessions), func(i int) bool { return sessions[i].After(t) })
+	if i == 0 {
+		return nil, ErrNotArchived
+	}
+	return a.load(period, sessions[i-1])
+}
+
+// Before returns the last archived session that started before start.
+func (a *Archive) Before(period Period, start time.Time) (*Snapshot, error) {
+	return a.At(period, start.Add(-time.Second))
+}
+
+func (a *Archive) load(period Period, start time.Time) (*Snapshot, error) {
+	snap, err := LoadSnapshotFile(filepath.Join(a.periodDir(period), start.UTC().Format(archiveLayout)+".json"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotArchived
+		}
+		return nil, err
+	}
+	return snap, nil
+}
+
+// ParseDate parses a lookup time: RFC3339, or a date (2006-01-02) meaning
+// the session that starts on that day at the session close.
+func (s Session) ParseDate(v string) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseInLocation("2006-01-02", v, s.Location)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q: want 2006-01-02 or RFC3339", v)
+	}
+	return d.Add(s.Close), nil
+}
@@ -0,0 +1,68 @@
+package pivot
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestArchive_Lookup(t *testing.T) {
+	a := NewArchive(t.TempDir())
+	day := func(d int) time.Time { return time.Date(2025, 1, d, 0, 0, 0, 0, time.UTC) }
+	for d := 1; d <= 3; d++ {
+		snap := &Snapshot{
+			Period:       PeriodDaily,
+			UpdatedAt:    day(d).Add(2 * time.Minute),
+			SessionStart: day(d),
+			Symbols:      map[string]Levels{"BTCUSDT": {PP: float64(100 + d)}},
+		}
+		if err := a.Save(snap); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 没有会话起点的快照不归档
+	if err := a.Save(&Snapshot{Period: PeriodDaily, Symbols: map[string]Levels{}}); err != nil {
+		t.Fatal(err)
+	}
+
+	snap, err := a.At(PeriodDaily, day(2).Add(20*time.Hour))
+	if err != nil || snap.Symbols["BTCUSDT"].PP != 102 {
+		t.Fatalf("at = %+v, %v", snap, err)
+	}
+	prev, err := a.Before(PeriodDaily, snap.SessionStart)
+	if err != nil || prev.Symbols["BTCUSDT"].PP != 101 {
+		t.Fatalf("before = %+v, %v", prev, err)
+	}
+	if _, err := a.At(PeriodDaily, day(1).Add(-time.Hour)); !errors.Is(err, ErrNotArchived) {
+		t.Errorf("err = %v, want ErrNotArchived", err)
+	}
+	if _, err := a.At(PeriodWeekly, day(2)); !errors.Is(err, ErrNotArchived) {
+		t.Errorf("weekly err = %v", err)
+	}
+
+	a.MaxAge = 36 * time.Hour
+	a.now = func() time.Time { return day(3).Add(time.Hour) }
+	if err := a.Save(snap); err != nil {
+		t.Fatal(err)
+	}
+	if sessions, _ := a.Sessions(PeriodDaily); len(sessions) != 2 || !sessions[0].Equal(day(2)) {
+		t.Errorf("sessions after prune = %v", sessions)
+	}
+}
+
+func TestSession_ParseDate(t *testing.T) {
+	s := DefaultSession()
+	got, err := s.ParseDate("2025-01-06")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("date = %v, want %v (08:00 Asia/Shanghai)", got, want)
+	}
+	if got, _ := s.ParseDate("2025-01-06T12:00:00Z"); got.Hour() != 12 {
+		t.Errorf("rfc3339 = %v", got)
+	}
+	if _, err := s.ParseDate("yesterday"); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -3,22 +3,25 @@ package pivot
 import "errors"
 
 type Levels struct {
-	High  float64 `json:"high"`
-	Low   float64 `json:"low"`
-	Close float64 `json:"close"`
-	PP    float64 `json:"pp"`
-	R1    float64 `json:"r1"`
-	R2    float64 `json:"r2"`
-	R3    float64 `json:"r3"`
-	R4    float64 `json:"r4"`
-	R5    float64 `json:"r5"`
-	S1    float64 `json:"s1"`
-	S2    float64 `json:"s2"`
-	S3    float64 `json:"s3"`
-	S4    float64 `json:"s4"`
-	S5    float64 `json:"s5"`
+	Method Method  `json:"method,omitempty"`
+	High   float64 `json:"high"`
+	Low    float64 `json:"low"`
+	Close  float64 `json:"close"`
+	PP     float64 `json:"pp"`
+	R1     float64 `json:"r1"`
+	R2     float64 `json:"r2"`
+	R3     float64 `json:"r3"`
+	R4     float64 `json:"r4"`
+	R5     float64 `json:"r5"`
+	S1     float64 `json:"s1"`
+	S2     float64 `json:"s2"`
+	S3     float64 `json:"s3"`
+	S4     float64 `json:"s4"`
+	S5     float64 `json:"s5"`
 }
 
+// Calculate computes Camarilla levels from the previous period's high, low
+// and close.
 func Calculate(high, low, close float64) (Levels, error) {
 	if high <= 0 || low <= 0 {
 		return Levels{}, errors.New("invalid high/low")
@@ -44,19 +47,20 @@ func Calculate(high, low, close float64) (Levels, error) {
 	s4 := close - rng*factor/2.0
 
 	return Levels{
-		High:  high,
-		Low:   low,
-		Close: close,
-		PP:    (high + low + close) / 3.0,
-		R1:    r1,
-		R2:    r2,
-		R3:    r3,
-		R4:    r4,
-		R5:    r5,
-		S1:    s1,
-		S2:    s2,
-		S3:    s3,
-		S4:    s4,
-		S5:    s5,
+		Method: MethodCamarilla,
+		High:   high,
+		Low:    low,
+		Close:  close,
+		PP:     (high + low + close) / 3.0,
+		R1:     r1,
+		R2:     r2,
+		R3:     r3,
+		R4:     r4,
+		R5:     r5,
+		S1:     s1,
+		S2:     s2,
+		S3:     s3,
+		S4:     s4,
+		S5:     s5,
 	}, nil
 }
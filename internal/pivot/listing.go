@@ -0,0 +1,256 @@
+package pivot
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"example.com/binance-pivot-monitor/internal/sse"
+)
+
+// DefaultListingRetry is how often a listed symbol's missing periods are
+// retried. A new contract has no previous candle for a period until its first
+// one closes.
+const DefaultListingRetry = 5 * time.Minute
+
+// listingIgnoreFor is how long a symbol that is not a trading USDT perpetual
+// is left alone before it is checked again.
+const listingIgnoreFor = time.Hour
+
+// Listing is published when a symbol appears in the price stream without
+// pivot levels, e.g. a contract listed after the last refresh.
+type Listing struct {
+	Symbol     string    `json:"symbol"`
+	DetectedAt time.Time `json:"detected_at"`
+	// Periods got levels on demand; Pending are retried until their first
+	// candle has closed or the next refresh covers them.
+	Periods []Period `json:"periods"`
+	Pending []Period `json:"pending,omitempty"`
+}
+
+type listingState struct {
+	detectedAt time.Time
+	next       time.Time
+	announced  bool
+}
+
+// ListingWatcher computes pivots on demand for symbols missing from the
+// live snapshots and merges them in through Refresher.AddSymbols.
+type ListingWatcher struct {
+	Refresher     *Refresher
+	RetryInterval time.Duration
+
+	broker *sse.Broker[Listing]
+	wake   chan struct{}
+	now    func() time.Time
+
+	mu      sync.Mutex
+	pending map[string]*listingState
+	ignored map[string]time.Time
+}
+
+// NewListingWatcher creates a watcher for r's periods.
+func NewListingWatcher(r *Refresher) *ListingWatcher {
+	return &ListingWatcher{
+		Refresher:     r,
+		RetryInterval: DefaultListingRetry,
+		broker:        sse.NewBroker[Listing](),
+		wake:          make(chan struct{}, 1),
+		now:           time.Now,
+		pending:       make(map[string]*listingState),
+		ignored:       make(map[string]time.Time),
+	}
+}
+
+// Broker returns the new listing broker.
+func (w *ListingWatcher) Broker() *sse.Broker[Listing] {
+	return w.broker
+}
+
+// Observe is called for every price tick. It is cheap for known symbols and
+// queues symbols that some current snapshot lacks.
+func (w *ListingWatcher) Observe(symbol string, ts time.Time) {
+	// 只处理 U 本位永续合约，交割合约形如 BTCUSDT_250328
+	if !strings.HasSuffix(symbol, "USDT") || strings.Contains(symbol, "_") {
+		return
+	}
+	if !w.missing(symbol) {
+		return
+	}
+
+	w.mu.Lock()
+	if _, ok := w.pending[symbol]; ok {
+		w.mu.Unlock()
+		return
+	}
+	if until, ok := w.ignored[symbol]; ok && ts.Before(until) {
+		w.mu.Unlock()
+		return
+	}
+	w.pending[symbol] = &listingState{detectedAt: ts}
+	w.mu.Unlock()
+
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// missing reports whether a loaded snapshot lacks symbol. Symbols that a
+// refresh failed on are left to Refresher.RetryFailed.
+func (w *ListingWatcher) missing(symbol string) bool {
+	r := w.Refresher
+	for _, p := range r.Store.Periods() {
+		snap, _ := r.Store.Snapshot(p)
+		if snap == nil {
+			continue
+		}
+		if _, ok := snap.Symbols[symbol]; !ok && !r.failed(p, symbol) {
+			return true
+		}
+	}
+	return false
+}
+
+// Run processes queued symbols until ctx is done.
+func (w *ListingWatcher) Run(ctx context.Context) {
+	interval := w.RetryInterval
+	if interval <= 0 {
+		interval = DefaultListingRetry
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.wake:
+		case <-t.C:
+		}
+		w.process(ctx)
+	}
+}
+
+func (w *ListingWatcher) process(ctx context.Context) {
+	now := w.now()
+	w.mu.Lock()
+	var due []string
+	for sym, st := range w.pending {
+		if !now.Before(st.next) {
+			due = append(due, sym)
+		}
+	}
+	w.mu.Unlock()
+	if len(due) == 0 {
+		return
+	}
+	sort.Strings(due)
+
+	ctxSymbols, cancel := context.WithTimeout(ctx, 20*time.Second)
+	trading, err := w.Refresher.Client.ExchangeInfoUSDTPERP(ctxSymbols)
+	cancel()
+	if err != nil {
+		log.Printf("listing check failed: %v", err)
+		w.retryLater(due, now)
+		return
+	}
+	listed := make(map[string]bool, len(trading))
+	for _, sym := range trading {
+		listed[sym] = true
+	}
+
+	for _, sym := range due {
+		if ctx.Err() != nil {
+			return
+		}
+		if !listed[sym] {
+			w.mu.Lock()
+			delete(w.pending, sym)
+			w.ignored[sym] = now.Add(listingIgnoreFor)
+			w.mu.Unlock()
+			continue
+		}
+		w.add(ctx, sym, now)
+	}
+}
+
+// add fetches sym for every period whose snapshot lacks it.
+func (w *ListingWatcher) add(ctx context.Context, sym string, now time.Time) {
+	var added, pending []Period
+	for _, p := range w.Refresher.Store.Periods() {
+		snap, _ := w.Refresher.Store.Snapshot(p)
+		if snap == nil {
+			continue
+		}
+		if _, ok := snap.Symbols[sym]; ok {
+			continue
+		}
+		ctxRun, cancel := context.WithTimeout(ctx, time.Minute)
+		ok, errs, err := w.Refresher.AddSymbols(ctxRun, p, []string{sym})
+		cancel()
+		switch {
+		case err != nil:
+			log.Printf("listing %s %s: %v", sym, p, err)
+			pending = append(pending, p)
+		case len(ok) > 0:
+			added = append(added, p)
+		default:
+			// 新合约尚无已收盘的上一根 K 线
+			if e := errs[sym]; e != nil {
+				log.Printf("listing %s %s not available yet: %v", sym, p, e)
+			}
+			pending = append(pending, p)
+		}
+	}
+
+	w.mu.Lock()
+	st, ok := w.pending[sym]
+	if !ok {
+		w.mu.Unlock()
+		return
+	}
+	announce := !st.announced
+	st.announced = true
+	detectedAt := st.detectedAt
+	if len(pending) == 0 {
+		delete(w.pending, sym)
+	} else {
+		st.next = now.Add(w.RetryInterval)
+	}
+	w.mu.Unlock()
+
+	if len(added) > 0 {
+		log.Printf("listing %s: pivots added for %v, pending %v", sym, added, pending)
+	}
+	if announce {
+		if added == nil {
+			added = []Period{}
+		}
+		w.broker.Publish(Listing{Symbol: sym, DetectedAt: detectedAt, Periods: added, Pending: pending})
+	}
+}
+
+func (w *ListingWatcher) retryLater(symbols []string, now time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, sym := range symbols {
+		if st, ok := w.pending[sym]; ok {
+			st.next = now.Add(w.RetryInterval)
+		}
+	}
+}
+
+// Pending lists the symbols still waiting for levels in some period.
+func (w *ListingWatcher) Pending() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	out := make([]string, 0, len(w.pending))
+	for sym := range w.pending {
+		out = append(out, sym)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,94 @@
+package pivot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"example.com/binance-pivot-monitor/internal/binance"
+)
+
+func TestListingWatcher_AddsNewSymbol(t *testing.T) {
+	var listed int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/fapi/v1/exchangeInfo":
+			body := `{"symbol":"BTCUSDT","status":"TRADING","contractType":"PERPETUAL","quoteAsset":"USDT"}`
+			if atomic.LoadInt32(&listed) == 1 {
+				body += `,{"symbol":"NEWUSDT","status":"TRADING","contractType":"PERPETUAL","quoteAsset":"USDT"}`
+			}
+			fmt.Fprint(w, `{"symbols":[`+body+`]}`)
+		case "/fapi/v1/klines":
+			if r.URL.Query().Get("symbol") == "NEWUSDT" && r.URL.Query().Get("interval") == "1w" {
+				// 上线不足一周，还没有已收盘的周线
+				fmt.Fprint(w, `[[1,"1","2","0.5","1.5","1",2]]`)
+				return
+			}
+			fmt.Fprint(w, `[[0,"95","100","90","98","1",1],[1,"98","110","95","105","1",2]]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	store := NewStore(PeriodDaily, PeriodWeekly)
+	r := NewRefresher(t.TempDir(), store, binance.NewRESTClient(srv.URL))
+	ctx := context.Background()
+	for _, p := range store.Periods() {
+		if err := r.Refresh(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := NewListingWatcher(r)
+	events := w.Broker().Subscribe(4)
+	now := time.Now()
+	w.Observe("BTCUSDT", now)
+	w.Observe("BTCUSDT_250328", now)
+	w.Observe("NEWUSDT", now)
+	if got := w.Pending(); len(got) != 1 || got[0] != "NEWUSDT" {
+		t.Fatalf("pending = %v", got)
+	}
+
+	// 尚未出现在 exchangeInfo 中：暂时忽略
+	w.process(ctx)
+	if got := w.Pending(); len(got) != 0 {
+		t.Fatalf("pending = %v", got)
+	}
+	w.Observe("NEWUSDT", now)
+	if got := w.Pending(); len(got) != 0 {
+		t.Fatalf("ignored symbol queued again: %v", got)
+	}
+
+	atomic.StoreInt32(&listed, 1)
+	w.ignored = map[string]time.Time{}
+	w.Observe("NEWUSDT", now)
+	w.process(ctx)
+
+	select {
+	case l := <-events:
+		if l.Symbol != "NEWUSDT" || len(l.Periods) != 1 || l.Periods[0] != PeriodDaily || len(l.Pending) != 1 || l.Pending[0] != PeriodWeekly {
+			t.Errorf("listing = %+v", l)
+		}
+	default:
+		t.Fatal("no listing event")
+	}
+	if _, ok := store.GetLevels(PeriodDaily, "NEWUSDT"); !ok {
+		t.Error("daily levels not merged")
+	}
+	if got := w.Pending(); len(got) != 1 {
+		t.Errorf("weekly should stay pending: %v", got)
+	}
+
+	// 重试时间未到不会重复请求，也不会再次推送
+	w.process(ctx)
+	select {
+	case l := <-events:
+		t.Errorf("repeated listing event: %+v", l)
+	default:
+	}
+}
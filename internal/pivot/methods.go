@@ -0,0 +1,283 @@
+package pivot
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Method names a pivot level family (formula).
+type Method string
+
+const (
+	MethodCamarilla Method = "camarilla"
+	MethodClassic   Method = "classic"
+	MethodFibonacci Method = "fibonacci"
+	MethodWoodie    Method = "woodie"
+	MethodDeMark    Method = "demark"
+)
+
+// DefaultMethod is the method used when none is configured.
+const DefaultMethod = MethodCamarilla
+
+// OHLC is the previous period's candle used as input for a pivot formula.
+type OHLC struct {
+	Open  float64
+	High  float64
+	Low   float64
+	Close float64
+}
+
+// Formula computes a family of levels from the previous period's candle.
+// Levels a formula does not define are left at zero and are never alerted on.
+type Formula func(OHLC) (Levels, error)
+
+var (
+	formulasMu sync.RWMutex
+	formulas   = map[Method]Formula{
+		MethodCamarilla: camarilla,
+		MethodClassic:   classic,
+		MethodFibonacci: fibonacci,
+		MethodWoodie:    woodie,
+		MethodDeMark:    demark,
+	}
+)
+
+// Register adds or replaces the formula for a method.
+func Register(method Method, f Formula) {
+	if method == "" || f == nil {
+		return
+	}
+	formulasMu.Lock()
+	formulas[method] = f
+	formulasMu.Unlock()
+}
+
+// Methods returns all registered methods sorted by name.
+func Methods() []Method {
+	formulasMu.RLock()
+	out := make([]Method, 0, len(formulas))
+	for m := range formulas {
+		out = append(out, m)
+	}
+	formulasMu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+// ParseMethod normalizes a method name and reports whether it is registered.
+func ParseMethod(s string) (Method, bool) {
+	m := Method(strings.ToLower(strings.TrimSpace(s)))
+	formulasMu.RLock()
+	_, ok := formulas[m]
+	formulasMu.RUnlock()
+	return m, ok
+}
+
+// ParseMethods parses a comma-separated method list, e.g. "camarilla,classic".
+// Duplicates are dropped and the order is preserved; the first entry is the
+// primary method of a snapshot.
+func ParseMethods(s string) ([]Method, error) {
+	var out []Method
+	seen := make(map[Method]struct{})
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		m, ok := ParseMethod(part)
+		if !ok {
+			return nil, fmt.Errorf("unknown pivot method %q", part)
+		}
+		if _, dup := seen[m]; dup {
+			continue
+		}
+		seen[m] = struct{}{}
+		out = append(out, m)
+	}
+	if len(out) == 0 {
+		return []Method{DefaultMethod}, nil
+	}
+	return out, nil
+}
+
+// CalculateWith computes levels for the given method.
+func CalculateWith(method Method, in OHLC) (Levels, error) {
+	if method == "" {
+		method = DefaultMethod
+	}
+	formulasMu.RLock()
+	f, ok := formulas[method]
+	formulasMu.RUnlock()
+	if !ok {
+		return Levels{}, fmt.Errorf("unknown pivot method %q", method)
+	}
+	lv, err := f(in)
+	if err != nil {
+		return Levels{}, err
+	}
+	lv.Method = method
+	return lv, nil
+}
+
+// LevelNames lists the level names in the order they are checked.
+var LevelNames = []string{"PP", "R1", "R2", "R3", "R4", "R5", "S1", "S2", "S3", "S4", "S5"}
+
+// Crossing reports whether a move from prev to price crosses level:
+// "up" when it reaches the level from below, "down" from above, "" otherwise.
+// The live monitor and the backtest share this rule.
+func Crossing(prev, price, level float64) string {
+	if level <= 0 {
+		return ""
+	}
+	if prev < level && price >= level {
+		return "up"
+	}
+	if prev > level && price <= level {
+		return "down"
+	}
+	return ""
+}
+
+// Price returns the price of a named level (PP, R1-R5, S1-S5).
+func (lv Levels) Price(name string) (float64, bool) {
+	switch strings.ToUpper(name) {
+	case "PP":
+		return lv.PP, true
+	case "R1":
+		return lv.R1, true
+	case "R2":
+		return lv.R2, true
+	case "R3":
+		return lv.R3, true
+	case "R4":
+		return lv.R4, true
+	case "R5":
+		return lv.R5, true
+	case "S1":
+		return lv.S1, true
+	case "S2":
+		return lv.S2, true
+	case "S3":
+		return lv.S3, true
+	case "S4":
+		return lv.S4, true
+	case "S5":
+		return lv.S5, true
+	default:
+		return 0, false
+	}
+}
+
+func checkRange(high, low float64) error {
+	if high <= 0 || low <= 0 {
+		return errors.New("invalid high/low")
+	}
+	if high < low {
+		return errors.New("high < low")
+	}
+	return nil
+}
+
+func camarilla(in OHLC) (Levels, error) {
+	return Calculate(in.High, in.Low, in.Close)
+}
+
+// classic is the standard floor-trader pivot.
+func classic(in OHLC) (Levels, error) {
+	if err := checkRange(in.High, in.Low); err != nil {
+		return Levels{}, err
+	}
+	h, l, c := in.High, in.Low, in.Close
+	rng := h - l
+	pp := (h + l + c) / 3.0
+	return Levels{
+		High:  h,
+		Low:   l,
+		Close: c,
+		PP:    pp,
+		R1:    2*pp - l,
+		R2:    pp + rng,
+		R3:    h + 2*(pp-l),
+		S1:    2*pp - h,
+		S2:    pp - rng,
+		S3:    l - 2*(h-pp),
+	}, nil
+}
+
+// fibonacci projects 38.2% / 61.8% / 100% of the range from the floor pivot.
+func fibonacci(in OHLC) (Levels, error) {
+	if err := checkRange(in.High, in.Low); err != nil {
+		return Levels{}, err
+	}
+	h, l, c := in.High, in.Low, in.Close
+	rng := h - l
+	pp := (h + l + c) / 3.0
+	return Levels{
+		High:  h,
+		Low:   l,
+		Close: c,
+		PP:    pp,
+		R1:    pp + rng*0.382,
+		R2:    pp + rng*0.618,
+		R3:    pp + rng,
+		S1:    pp - rng*0.382,
+		S2:    pp - rng*0.618,
+		S3:    pp - rng,
+	}, nil
+}
+
+// woodie weights the close twice. Crypto trades continuously, so the
+// previous close stands in for the current session's open.
+func woodie(in OHLC) (Levels, error) {
+	if err := checkRange(in.High, in.Low); err != nil {
+		return Levels{}, err
+	}
+	h, l, c := in.High, in.Low, in.Close
+	rng := h - l
+	pp := (h + l + 2*c) / 4.0
+	return Levels{
+		High:  h,
+		Low:   l,
+		Close: c,
+		PP:    pp,
+		R1:    2*pp - l,
+		R2:    pp + rng,
+		R3:    h + 2*(pp-l),
+		R4:    pp + 2*rng,
+		S1:    2*pp - h,
+		S2:    pp - rng,
+		S3:    l - 2*(h-pp),
+		S4:    pp - 2*rng,
+	}, nil
+}
+
+// demark only defines one resistance and one support, chosen by how the
+// previous candle closed relative to its open.
+func demark(in OHLC) (Levels, error) {
+	if err := checkRange(in.High, in.Low); err != nil {
+		return Levels{}, err
+	}
+	if in.Open <= 0 {
+		return Levels{}, errors.New("demark requires open")
+	}
+	h, l, c, o := in.High, in.Low, in.Close, in.Open
+	var x float64
+	switch {
+	case c < o:
+		x = h + 2*l + c
+	case c > o:
+		x = 2*h + l + c
+	default:
+		x = h + l + 2*c
+	}
+	return Levels{
+		High:  h,
+		Low:   l,
+		Close: c,
+		PP:    x / 4.0,
+		R1:    x/2.0 - l,
+		S1:    x/2.0 - h,
+	}, nil
+}
@@ -0,0 +1,121 @@
+package pivot
+
+import (
+	"math"
+	"testing"
+)
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestCalculateWith_Formulas(t *testing.T) {
+	in := OHLC{Open: 100, High: 110, Low: 90, Close: 105}
+
+	tests := []struct {
+		method Method
+		check  map[string]float64
+	}{
+		{
+			method: MethodCamarilla,
+			check:  map[string]float64{"R3": 105 + 20*1.1/4, "S3": 105 - 20*1.1/4},
+		},
+		{
+			method: MethodClassic,
+			check: map[string]float64{
+				"PP": 305.0 / 3, "R1": 2*305.0/3 - 90, "S1": 2*305.0/3 - 110,
+				"R2": 305.0/3 + 20, "S2": 305.0/3 - 20, "R3": 110 + 2*(305.0/3-90),
+			},
+		},
+		{
+			method: MethodFibonacci,
+			check:  map[string]float64{"R1": 305.0/3 + 20*0.382, "S2": 305.0/3 - 20*0.618, "R3": 305.0/3 + 20},
+		},
+		{
+			method: MethodWoodie,
+			check:  map[string]float64{"PP": 102.5, "R1": 115, "S1": 95, "R2": 122.5, "S2": 82.5},
+		},
+		{
+			// close > open: X = 2H + L + C = 415
+			method: MethodDeMark,
+			check:  map[string]float64{"PP": 103.75, "R1": 117.5, "S1": 97.5, "R2": 0, "S2": 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.method), func(t *testing.T) {
+			lv, err := CalculateWith(tt.method, in)
+			if err != nil {
+				t.Fatalf("CalculateWith(%s) error: %v", tt.method, err)
+			}
+			if lv.Method != tt.method {
+				t.Errorf("Method = %q, want %q", lv.Method, tt.method)
+			}
+			for name, want := range tt.check {
+				got, _ := lv.Price(name)
+				if !almostEqual(got, want) {
+					t.Errorf("%s = %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCalculateWith_InvalidInput(t *testing.T) {
+	for _, m := range Methods() {
+		if _, err := CalculateWith(m, OHLC{Open: 1, High: 90, Low: 110, Close: 100}); err == nil {
+			t.Errorf("%s: expected error for high < low", m)
+		}
+	}
+	if _, err := CalculateWith(MethodDeMark, OHLC{High: 110, Low: 90, Close: 100}); err == nil {
+		t.Error("demark: expected error without open")
+	}
+	if _, err := CalculateWith("nope", OHLC{High: 110, Low: 90, Close: 100}); err == nil {
+		t.Error("expected error for unknown method")
+	}
+}
+
+func TestParseMethods(t *testing.T) {
+	got, err := ParseMethods(" Classic, camarilla,classic ")
+	if err != nil {
+		t.Fatalf("ParseMethods error: %v", err)
+	}
+	if len(got) != 2 || got[0] != MethodClassic || got[1] != MethodCamarilla {
+		t.Errorf("ParseMethods = %v, want [classic camarilla]", got)
+	}
+
+	got, err = ParseMethods("")
+	if err != nil || len(got) != 1 || got[0] != DefaultMethod {
+		t.Errorf("ParseMethods(\"\") = %v, %v; want [%s]", got, err, DefaultMethod)
+	}
+
+	if _, err := ParseMethods("camarilla,unknown"); err == nil {
+		t.Error("expected error for unknown method")
+	}
+}
+
+func TestSnapshotLevels_PrimaryAndExtra(t *testing.T) {
+	snap := &Snapshot{
+		Period:  PeriodDaily,
+		Symbols: map[string]Levels{"BTCUSDT": {R3: 1}},
+		Methods: map[Method]map[string]Levels{
+			MethodClassic: {"BTCUSDT": {R3: 2}},
+		},
+	}
+
+	if snap.PrimaryMethod() != MethodCamarilla {
+		t.Errorf("legacy snapshot primary = %q, want camarilla", snap.PrimaryMethod())
+	}
+	if lv, ok := snap.Levels("", "BTCUSDT"); !ok || lv.R3 != 1 {
+		t.Errorf("primary levels = %+v, %v", lv, ok)
+	}
+	if lv, ok := snap.Levels(MethodCamarilla, "BTCUSDT"); !ok || lv.R3 != 1 {
+		t.Errorf("camarilla levels = %+v, %v", lv, ok)
+	}
+	if lv, ok := snap.Levels(MethodClassic, "BTCUSDT"); !ok || lv.R3 != 2 {
+		t.Errorf("classic levels = %+v, %v", lv, ok)
+	}
+	if _, ok := snap.Levels(MethodFibonacci, "BTCUSDT"); ok {
+		t.Error("fibonacci levels should be missing")
+	}
+}
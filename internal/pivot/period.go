@@ -0,0 +1,113 @@
+package pivot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Period string
+
+const (
+	PeriodDaily   Period = "1d"
+	PeriodWeekly  Period = "1w"
+	PeriodMonthly Period = "1M"
+	Period4h      Period = "4h"
+	Period8h      Period = "8h"
+)
+
+// DefaultPeriods are registered when a Store is created without periods.
+var DefaultPeriods = []Period{PeriodDaily, PeriodWeekly}
+
+// ParsePeriod normalizes a period name. Besides 1d/1w/1M (and their long
+// names) it accepts intraday sessions "Nh" where N divides 24 and Binance
+// has a matching kline interval (1h, 2h, 4h, 6h, 8h, 12h).
+func ParsePeriod(s string) (Period, error) {
+	v := strings.TrimSpace(s)
+	switch strings.ToLower(v) {
+	case "1d", "d", "daily":
+		return PeriodDaily, nil
+	case "1w", "w", "weekly":
+		return PeriodWeekly, nil
+	case "monthly", "month":
+		return PeriodMonthly, nil
+	}
+	// "1M" is monthly, "1m" would be a one-minute kline
+	if v == "1M" {
+		return PeriodMonthly, nil
+	}
+	p := Period(strings.ToLower(v))
+	if _, ok := p.intraday(); ok {
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown pivot period %q", s)
+}
+
+// ParsePeriods parses a comma-separated period list, e.g. "1d,1w,4h".
+func ParsePeriods(s string) ([]Period, error) {
+	var out []Period
+	seen := make(map[Period]struct{})
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		p, err := ParsePeriod(part)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := seen[p]; dup {
+			continue
+		}
+		seen[p] = struct{}{}
+		out = append(out, p)
+	}
+	if len(out) == 0 {
+		return append([]Period(nil), DefaultPeriods...), nil
+	}
+	return out, nil
+}
+
+// Interval returns the Binance kline interval of the period, or "" if the
+// period is unknown.
+func (p Period) Interval() string {
+	switch p {
+	case PeriodDaily, PeriodWeekly, PeriodMonthly:
+		return string(p)
+	}
+	if _, ok := p.intraday(); ok {
+		return string(p)
+	}
+	return ""
+}
+
+// intraday returns the session length of an "Nh" period.
+func (p Period) intraday() (time.Duration, bool) {
+	s := string(p)
+	if !strings.HasSuffix(s, "h") {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(s, "h"))
+	if err != nil {
+		return 0, false
+	}
+	switch n {
+	case 1, 2, 4, 6, 8, 12:
+		return time.Duration(n) * time.Hour, true
+	}
+	return 0, false
+}
+
+// fileName returns the snapshot file name under <data-dir>/pivots.
+func (p Period) fileName() string {
+	switch p {
+	case PeriodDaily:
+		return "daily.json"
+	case PeriodWeekly:
+		return "weekly.json"
+	case PeriodMonthly:
+		return "monthly.json"
+	default:
+		return string(p) + ".json"
+	}
+}
@@ -0,0 +1,47 @@
+package pivot
+
+import "testing"
+
+func TestParsePeriods(t *testing.T) {
+	got, err := ParsePeriods("daily, 1w,1M,4H,1d")
+	if err != nil {
+		t.Fatalf("ParsePeriods error: %v", err)
+	}
+	want := []Period{PeriodDaily, PeriodWeekly, PeriodMonthly, Period4h}
+	if len(got) != len(want) {
+		t.Fatalf("ParsePeriods = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ParsePeriods[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	for _, bad := range []string{"1m", "5h", "3d", "x"} {
+		if _, err := ParsePeriod(bad); err == nil {
+			t.Errorf("ParsePeriod(%q): expected error", bad)
+		}
+	}
+}
+
+func TestStore_RegisteredPeriods(t *testing.T) {
+	store := NewStore(PeriodDaily, Period4h)
+	store.Register(Period4h)
+	store.Register(PeriodMonthly)
+
+	periods := store.Periods()
+	if len(periods) != 3 || periods[0] != PeriodDaily || periods[1] != Period4h || periods[2] != PeriodMonthly {
+		t.Fatalf("Periods = %v, want [1d 4h 1M]", periods)
+	}
+
+	snap := &Snapshot{Period: Period4h, Symbols: map[string]Levels{"BTCUSDT": {R3: 1}}}
+	if err := store.Swap(Period4h, snap); err != nil {
+		t.Fatalf("Swap 4h: %v", err)
+	}
+	if lv, ok := store.GetLevels(Period4h, "BTCUSDT"); !ok || lv.R3 != 1 {
+		t.Errorf("GetLevels 4h = %+v, %v", lv, ok)
+	}
+	if err := store.Swap(PeriodWeekly, snap); err == nil {
+		t.Error("expected error swapping unregistered period")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
@@ -19,8 +20,24 @@ type Refresher struct {
 	Store   *Store
 	Client  *binance.RESTClient
 	Workers int
-
-	mu sync.Mutex
+	// Methods lists the level families computed on each refresh. The first
+	// entry is the primary method stored in Snapshot.Symbols.
+	Methods []Method
+	// Session anchors the period boundaries and refresh schedule.
+	Session Session
+	// RetryRounds is how many times failed symbols are retried within a
+	// refresh, waiting RetryBackoff before the first retry and doubling it.
+	RetryRounds  int
+	RetryBackoff time.Duration
+	// PartialRetryInterval is the delay between follow-up refreshes of the
+	// symbols a refresh left out.
+	PartialRetryInterval time.Duration
+	// Archive, if set, keeps a copy of every snapshot written.
+	Archive *Archive
+
+	mu       sync.Mutex
+	failMu   sync.Mutex
+	failures map[Period]map[string]SymbolFailure
 }
 
 func NewRefresher(dataDir string, store *Store, client *binance.RESTClient) *Refresher {
@@ -29,42 +46,39 @@ func NewRefresher(dataDir string, store *Store, client *binance.RESTClient) *Ref
 		Store:   store,
 		Client:  client,
 		Workers: 16,
-		mu:      sync.Mutex{},
+		Methods: []Method{DefaultMethod},
+		Session: DefaultSession(),
+
+		RetryRounds:          2,
+		RetryBackoff:         5 * time.Second,
+		PartialRetryInterval: 5 * time.Minute,
+		Archive:              NewArchive(filepath.Join(dataDir, "pivots", "archive")),
+		mu:                   sync.Mutex{},
 	}
 }
 
 func (r *Refresher) pivotFilePath(period Period) (string, error) {
-	switch period {
-	case PeriodDaily:
-		return filepath.Join(r.DataDir, "pivots", "daily.json"), nil
-	case PeriodWeekly:
-		return filepath.Join(r.DataDir, "pivots", "weekly.json"), nil
-	default:
+	if period.Interval() == "" {
 		return "", errors.New("unknown period")
 	}
+	return filepath.Join(r.DataDir, "pivots", period.fileName()), nil
 }
 
 func (r *Refresher) LoadFromDisk() {
-	for _, p := range []Period{PeriodDaily, PeriodWeekly} {
+	for _, p := range r.Store.Periods() {
 		path, err := r.pivotFilePath(p)
 		if err != nil {
 			continue
 		}
 
-		b, err := os.ReadFile(path)
+		snap, err := LoadSnapshotFile(path)
 		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				log.Printf("pivot load %s failed: %v", path, err)
+			}
 			continue
 		}
-
-		var snap Snapshot
-		if err := json.Unmarshal(b, &snap); err != nil {
-			log.Printf("pivot load %s failed: %v", path, err)
-			continue
-		}
-		if snap.Symbols == nil {
-			continue
-		}
-		if err := r.Store.Swap(p, &snap); err != nil {
+		if err := r.Store.Swap(p, snap); err != nil {
 			log.Printf("pivot swap %s failed: %v", p, err)
 			continue
 		}
@@ -72,17 +86,29 @@ func (r *Refresher) LoadFromDisk() {
 	}
 }
 
+// LoadSnapshotFile reads a snapshot written by Refresh.
+func LoadSnapshotFile(path string) (*Snapshot, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var snap Snapshot
+	if err := json.Unmarshal(b, &snap); err != nil {
+		return nil, err
+	}
+	if snap.Symbols == nil {
+		return nil, fmt.Errorf("%s: no symbols", path)
+	}
+	return &snap, nil
+}
+
 func (r *Refresher) Refresh(ctx context.Context, period Period) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	interval := ""
-	switch period {
-	case PeriodDaily:
-		interval = "1d"
-	case PeriodWeekly:
-		interval = "1w"
-	default:
+	interval := period.Interval()
+	if interval == "" {
 		return errors.New("unknown period")
 	}
 
@@ -94,20 +120,93 @@ func (r *Refresher) Refresh(ctx context.Context, period Period) error {
 		return err
 	}
 
+	methods := r.Methods
+	if len(methods) == 0 {
+		methods = []Method{DefaultMethod}
+	}
+
+	// 会话收盘与币安 UTC K线不对齐时，用低周期K线聚合上一会话的 HLC
+	start, end := r.Session.PrevBounds(time.Now(), period)
+	aligned := r.Session.Aligned(period, start)
+	if !aligned {
+		log.Printf("pivot %s session %s not aligned with binance candles, aggregating %s klines for %s - %s",
+			period, r.Session, aggregateInterval(start, end), start.Format(time.RFC3339), end.Format(time.RFC3339))
+	}
+
+	results, failed := r.fetchWithRetry(ctx, symbols, period, methods, start, end, aligned)
+
+	primary := methods[0]
+	levelsBySymbol := make(map[string]Levels, len(symbols))
+	extra := make(map[Method]map[string]Levels, len(methods)-1)
+	for _, m := range methods[1:] {
+		extra[m] = make(map[string]Levels, len(symbols))
+	}
+	for sym, levels := range results {
+		levelsBySymbol[sym] = levels[primary]
+		for _, m := range methods[1:] {
+			if lv, ok := levels[m]; ok {
+				extra[m][sym] = lv
+			}
+		}
+	}
+	fail := len(failed)
+
+	expected := len(symbols)
+	minCount := expected / 2
+	if minCount < 1 {
+		minCount = 1
+	}
+	if oldSnap, _ := r.Store.Snapshot(period); oldSnap != nil {
+		oldMin := len(oldSnap.Symbols) * 8 / 10
+		if oldMin > minCount {
+			minCount = oldMin
+		}
+	}
+	if len(levelsBySymbol) < minCount {
+		return fmt.Errorf("pivots computed too few symbols: got=%d expected=%d min=%d", len(levelsBySymbol), expected, minCount)
+	}
+
+	snap := &Snapshot{
+		Period:       period,
+		Method:       primary,
+		UpdatedAt:    time.Now().UTC(),
+		SessionStart: end.UTC(),
+		Symbols:      levelsBySymbol,
+	}
+	if len(extra) > 0 {
+		snap.Methods = extra
+	}
+
+	if err := r.writeSnapshot(period, snap); err != nil {
+		return err
+	}
+
+	if err := r.Store.Swap(period, snap); err != nil {
+		return err
+	}
+	r.setFailures(period, failed)
+
+	log.Printf("pivot refreshed %s symbols=%d fail=%d", period, len(levelsBySymbol), fail)
+	return nil
+}
+
+// fetchLevels computes the levels of symbols concurrently. Symbols that
+// failed are returned with their error.
+func (r *Refresher) fetchLevels(ctx context.Context, symbols []string, interval string, methods []Method, start, end time.Time, aligned bool) (map[string]map[Method]Levels, map[string]error) {
 	type result struct {
 		symbol string
-		lv     Levels
+		levels map[Method]Levels
 		err    error
 	}
 
-	jobs := make(chan string)
-	results := make(chan result, r.Workers)
-
 	workers := r.Workers
 	if workers <= 0 {
 		workers = 16
 	}
 
+	jobs := make(chan string)
+	results := make(chan result, workers)
+
 	var wg sync.WaitGroup
 	for i := 0; i < workers; i++ {
 		wg.Add(1)
@@ -115,17 +214,26 @@ func (r *Refresher) Refresh(ctx context.Context, period Period) error {
 			defer wg.Done()
 			for sym := range jobs {
 				if ctx.Err() != nil {
-					return
+					results <- result{symbol: sym, err: ctx.Err()}
+					continue
+				}
+				var in OHLC
+				var err error
+				if aligned {
+					ctxKline, cancel := context.WithTimeout(ctx, 15*time.Second)
+					in.Open, in.High, in.Low, in.Close, err = r.Client.PrevOHLC(ctxKline, sym, interval)
+					cancel()
+				} else {
+					ctxKline, cancel := context.WithTimeout(ctx, time.Minute)
+					in, err = r.sessionOHLC(ctxKline, sym, start, end)
+					cancel()
 				}
-				ctxKline, cancel := context.WithTimeout(ctx, 15*time.Second)
-				h, l, c, err := r.Client.PrevKline(ctxKline, sym, interval)
-				cancel()
 				if err != nil {
 					results <- result{symbol: sym, err: err}
 					continue
 				}
-				lv, err := Calculate(h, l, c)
-				results <- result{symbol: sym, lv: lv, err: err}
+				levels, err := calculateMethods(methods, in)
+				results <- result{symbol: sym, levels: levels, err: err}
 			}
 		}()
 	}
@@ -138,45 +246,186 @@ func (r *Refresher) Refresh(ctx context.Context, period Period) error {
 	go func() {
 		defer close(jobs)
 		for _, sym := range symbols {
-			select {
-			case <-ctx.Done():
-				return
-			case jobs <- sym:
-			}
+			jobs <- sym
 		}
 	}()
 
-	levelsBySymbol := make(map[string]Levels, len(symbols))
-	fail := 0
+	ok := make(map[string]map[Method]Levels, len(symbols))
+	failed := make(map[string]error)
 	for res := range results {
 		if res.err != nil {
-			fail++
+			failed[res.symbol] = res.err
 			continue
 		}
-		levelsBySymbol[res.symbol] = res.lv
+		ok[res.symbol] = res.levels
 	}
+	return ok, failed
+}
 
-	expected := len(symbols)
-	minCount := expected / 2
-	if minCount < 1 {
-		minCount = 1
+// fetchWithRetry runs fetchLevels, then retries the failed symbols up to
+// RetryRounds times with a doubling backoff. Failures still left are
+// returned with the number of attempts made.
+func (r *Refresher) fetchWithRetry(ctx context.Context, symbols []string, period Period, methods []Method, start, end time.Time, aligned bool) (map[string]map[Method]Levels, map[string]SymbolFailure) {
+	interval := period.Interval()
+	results, errs := r.fetchLevels(ctx, symbols, interval, methods, start, end, aligned)
+	attempts := 1
+	backoff := r.RetryBackoff
+	for round := 0; round < r.RetryRounds && len(errs) > 0; round++ {
+		if !sleepContext(ctx, backoff) {
+			break
+		}
+		backoff *= 2
+		retry := make([]string, 0, len(errs))
+		for sym := range errs {
+			retry = append(retry, sym)
+		}
+		sort.Strings(retry)
+		log.Printf("pivot %s retrying %d failed symbols (round %d)", period, len(retry), round+1)
+		late, stillFailed := r.fetchLevels(ctx, retry, interval, methods, start, end, aligned)
+		for sym, levels := range late {
+			results[sym] = levels
+		}
+		errs = stillFailed
+		attempts++
 	}
-	if oldSnap, _ := r.Store.Snapshot(period); oldSnap != nil {
-		oldMin := len(oldSnap.Symbols) * 8 / 10
-		if oldMin > minCount {
-			minCount = oldMin
+
+	failed := make(map[string]SymbolFailure, len(errs))
+	now := time.Now().UTC()
+	for sym, err := range errs {
+		failed[sym] = SymbolFailure{Symbol: sym, Error: err.Error(), Attempts: attempts, LastAttempt: now}
+	}
+	return results, failed
+}
+
+// RetryFailed recomputes the symbols that failed in the last refresh of
+// period and merges the ones that succeed into the live snapshot. It does
+// nothing if the snapshot is stale; a full Refresh is due then.
+func (r *Refresher) RetryFailed(ctx context.Context, period Period) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	prev := r.Failures(period)
+	if len(prev) == 0 || r.needsRefresh(period) {
+		return nil
+	}
+	old, _ := r.Store.Snapshot(period)
+	if old == nil {
+		return nil
+	}
+
+	symbols := make([]string, 0, len(prev))
+	for _, f := range prev {
+		symbols = append(symbols, f.Symbol)
+	}
+	added, errs, err := r.mergeLocked(ctx, period, old, symbols)
+	if err != nil {
+		return err
+	}
+
+	failed := make(map[string]SymbolFailure, len(errs))
+	now := time.Now().UTC()
+	for _, f := range prev {
+		if err, ok := errs[f.Symbol]; ok {
+			f.Error = err.Error()
+			f.Attempts++
+			f.LastAttempt = now
+			failed[f.Symbol] = f
 		}
 	}
-	if len(levelsBySymbol) < minCount {
-		return fmt.Errorf("pivots computed too few symbols: got=%d expected=%d min=%d", len(levelsBySymbol), expected, minCount)
+	r.setFailures(period, failed)
+
+	log.Printf("pivot %s partial refresh: recovered=%d still_failed=%d", period, len(added), len(failed))
+	return nil
+}
+
+// AddSymbols computes the levels of symbols missing from period's live
+// snapshot, e.g. a contract listed after the last refresh, and merges them
+// in. It returns the symbols added and the errors of the ones that failed.
+// Nothing is fetched while the snapshot is missing or stale.
+func (r *Refresher) AddSymbols(ctx context.Context, period Period, symbols []string) ([]string, map[string]error, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old, err := r.Store.Snapshot(period)
+	if err != nil {
+		return nil, nil, err
+	}
+	if old == nil || r.needsRefresh(period) {
+		return nil, nil, fmt.Errorf("pivot %s snapshot is not current", period)
+	}
+	var missing []string
+	for _, sym := range symbols {
+		if _, ok := old.Symbols[sym]; !ok {
+			missing = append(missing, sym)
+		}
+	}
+	if len(missing) == 0 {
+		return nil, nil, nil
+	}
+	return r.mergeLocked(ctx, period, old, missing)
+}
+
+// mergeLocked computes symbols for the current session and swaps in a copy
+// of old that includes the ones that succeeded. r.mu must be held.
+func (r *Refresher) mergeLocked(ctx context.Context, period Period, old *Snapshot, symbols []string) ([]string, map[string]error, error) {
+	methods := []Method{old.PrimaryMethod()}
+	for _, m := range r.Methods {
+		if m != methods[0] {
+			methods = append(methods, m)
+		}
+	}
+	start, end := r.Session.PrevBounds(time.Now(), period)
+	aligned := r.Session.Aligned(period, start)
+
+	late, errs := r.fetchLevels(ctx, symbols, period.Interval(), methods, start, end, aligned)
+	if len(late) == 0 {
+		return nil, errs, nil
 	}
 
+	// 快照只读，合并到副本后整体替换
 	snap := &Snapshot{
-		Period:    period,
-		UpdatedAt: time.Now().UTC(),
-		Symbols:   levelsBySymbol,
+		Period:       old.Period,
+		Method:       old.Method,
+		UpdatedAt:    old.UpdatedAt,
+		SessionStart: old.SessionStart,
+		Symbols:      make(map[string]Levels, len(old.Symbols)+len(late)),
+	}
+	for sym, lv := range old.Symbols {
+		snap.Symbols[sym] = lv
+	}
+	if len(old.Methods) > 0 {
+		snap.Methods = make(map[Method]map[string]Levels, len(old.Methods))
+		for m, bySym := range old.Methods {
+			cp := make(map[string]Levels, len(bySym)+len(late))
+			for sym, lv := range bySym {
+				cp[sym] = lv
+			}
+			snap.Methods[m] = cp
+		}
+	}
+	added := make([]string, 0, len(late))
+	for sym, levels := range late {
+		snap.Symbols[sym] = levels[methods[0]]
+		for _, m := range methods[1:] {
+			if lv, ok := levels[m]; ok && snap.Methods[m] != nil {
+				snap.Methods[m][sym] = lv
+			}
+		}
+		added = append(added, sym)
+	}
+	sort.Strings(added)
+
+	if err := r.writeSnapshot(period, snap); err != nil {
+		return nil, errs, err
+	}
+	if err := r.Store.Swap(period, snap); err != nil {
+		return nil, errs, err
 	}
+	return added, errs, nil
+}
 
+// writeSnapshot saves snap to the period's pivot file.
+func (r *Refresher) writeSnapshot(period Period, snap *Snapshot) error {
 	b, err := json.MarshalIndent(snap, "", "  ")
 	if err != nil {
 		return err
@@ -198,77 +447,134 @@ func (r *Refresher) Refresh(ctx context.Context, period Period) error {
 		return err
 	}
 
-	if err := r.Store.Swap(period, snap); err != nil {
-		return err
+	if r.Archive != nil {
+		if err := r.Archive.Save(snap); err != nil {
+			log.Printf("pivot archive %s failed: %v", period, err)
+		}
 	}
-
-	log.Printf("pivot refreshed %s symbols=%d fail=%d", period, len(levelsBySymbol), fail)
 	return nil
 }
 
-func (r *Refresher) StartScheduler(ctx context.Context) {
-	loc, err := time.LoadLocation("Asia/Shanghai")
+// SymbolFailure records a symbol whose levels could not be computed.
+type SymbolFailure struct {
+	Symbol      string    `json:"symbol"`
+	Error       string    `json:"error"`
+	Attempts    int       `json:"attempts"`
+	LastAttempt time.Time `json:"last_attempt"`
+}
+
+func (r *Refresher) setFailures(period Period, failed map[string]SymbolFailure) {
+	r.failMu.Lock()
+	defer r.failMu.Unlock()
+	if r.failures == nil {
+		r.failures = make(map[Period]map[string]SymbolFailure)
+	}
+	r.failures[period] = failed
+}
+
+// failed reports whether symbol was left out of period's last refresh.
+func (r *Refresher) failed(period Period, symbol string) bool {
+	r.failMu.Lock()
+	defer r.failMu.Unlock()
+	_, ok := r.failures[period][symbol]
+	return ok
+}
+
+// Failures returns the symbols left out of period's snapshot, by symbol.
+func (r *Refresher) Failures(period Period) []SymbolFailure {
+	r.failMu.Lock()
+	defer r.failMu.Unlock()
+	out := make([]SymbolFailure, 0, len(r.failures[period]))
+	for _, f := range r.failures[period] {
+		out = append(out, f)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Symbol < out[j].Symbol })
+	return out
+}
+
+// sessionOHLC aggregates the candle of [start, end) from lower-timeframe klines.
+func (r *Refresher) sessionOHLC(ctx context.Context, symbol string, start, end time.Time) (OHLC, error) {
+	klines, err := r.Client.KlinesRange(ctx, symbol, aggregateInterval(start, end), start, end)
 	if err != nil {
-		loc = time.FixedZone("UTC+8", 8*60*60)
+		return OHLC{}, err
+	}
+	in, ok := AggregateOHLC(klines)
+	if !ok {
+		return OHLC{}, fmt.Errorf("klines %s: no data for session %s", symbol, start.Format(time.RFC3339))
+	}
+	return in, nil
+}
+
+// calculateMethods computes every method for one candle. The primary
+// (first) method must succeed; other methods that reject the input, such as
+// DeMark without an open price, are skipped for that symbol.
+func calculateMethods(methods []Method, in OHLC) (map[Method]Levels, error) {
+	out := make(map[Method]Levels, len(methods))
+	for i, m := range methods {
+		lv, err := CalculateWith(m, in)
+		if err != nil {
+			if i == 0 {
+				return nil, err
+			}
+			continue
+		}
+		out[m] = lv
 	}
+	return out, nil
+}
 
-	go r.loop(ctx, PeriodDaily, loc)
-	go r.loop(ctx, PeriodWeekly, loc)
+func (r *Refresher) StartScheduler(ctx context.Context) {
+	for _, p := range r.Store.Periods() {
+		go r.loop(ctx, p)
+	}
 }
 
-func (r *Refresher) needsRefresh(period Period, loc *time.Location) bool {
+func (r *Refresher) needsRefresh(period Period) bool {
 	snap, _ := r.Store.Snapshot(period)
 	if snap == nil {
 		return true
 	}
 
-	now := time.Now().In(loc)
+	// 配置了新的计算方法时立即补算
+	if !r.hasMethods(snap) {
+		return true
+	}
 
-	// 延迟2分钟刷新，确保币安K线数据已完全收盘
-	// 币安日线在 UTC 00:00 (UTC+8 08:00) 收盘，延迟到 08:02 确保数据稳定
-	switch period {
-	case PeriodDaily:
-		today8am02 := time.Date(now.Year(), now.Month(), now.Day(), 8, 2, 0, 0, loc)
-		if !now.Before(today8am02) && snap.UpdatedAt.In(loc).Before(today8am02) {
-			return true
-		}
-	case PeriodWeekly:
-		thisMonday8am02 := getThisWeekMonday(now, loc)
-		if !now.Before(thisMonday8am02) && snap.UpdatedAt.In(loc).Before(thisMonday8am02) {
-			return true
+	return snap.UpdatedAt.Before(r.Session.lastRefresh(time.Now(), period))
+}
+
+// hasMethods reports whether snap contains every configured method.
+func (r *Refresher) hasMethods(snap *Snapshot) bool {
+	if len(r.Methods) == 0 {
+		return true
+	}
+	if snap.PrimaryMethod() != r.Methods[0] {
+		return false
+	}
+	for _, m := range r.Methods[1:] {
+		if _, ok := snap.Methods[m]; !ok {
+			return false
 		}
 	}
-	return false
+	return true
 }
 
-// getThisWeekMonday 计算本周一 08:02 的时间
+// getThisWeekMonday 计算本周一 08:02 的时间（默认会话）
 // 修复：周日时 Weekday()=0，需要特殊处理，确保返回的是本周一而不是下周一
 func getThisWeekMonday(now time.Time, loc *time.Location) time.Time {
-	today8am02 := time.Date(now.Year(), now.Month(), now.Day(), 8, 2, 0, 0, loc)
-
-	// time.Weekday: Sunday=0, Monday=1, ..., Saturday=6
-	// 我们需要计算距离本周一的天数差
-	weekday := int(now.Weekday())
-	var daysFromMonday int
-	if weekday == 0 {
-		// 周日：距离本周一是 6 天前
-		daysFromMonday = 6
-	} else {
-		// 周一到周六：weekday - 1
-		daysFromMonday = weekday - 1
-	}
-
-	return today8am02.AddDate(0, 0, -daysFromMonday)
+	s := DefaultSession()
+	s.Location = loc
+	return s.lastRefresh(now, PeriodWeekly)
 }
 
-func (r *Refresher) loop(ctx context.Context, period Period, loc *time.Location) {
+func (r *Refresher) loop(ctx context.Context, period Period) {
 	for {
 		if ctx.Err() != nil {
 			return
 		}
 
 		// 检查数据是否过期，过期则立即刷新
-		if r.needsRefresh(period, loc) {
+		if r.needsRefresh(period) {
 			log.Printf("pivot %s data is stale, refreshing now", period)
 			ctxRun, cancel := context.WithTimeout(ctx, 10*time.Minute)
 			err := r.Refresh(ctxRun, period)
@@ -278,85 +584,92 @@ func (r *Refresher) loop(ctx context.Context, period Period, loc *time.Location)
 			}
 		}
 
-		now := time.Now().In(loc)
-		next := nextRun(now, period, loc)
+		next := r.Session.nextRefresh(time.Now(), period)
 		d := time.Until(next)
+		// 有失败的交易对时提前唤醒，补算后合并进当前快照
+		partial := len(r.Failures(period)) > 0 && r.PartialRetryInterval > 0 && r.PartialRetryInterval < d
+		if partial {
+			d = r.PartialRetryInterval
+		}
 		if d < time.Minute {
 			d = time.Minute // 避免过于频繁的循环
 		}
 
-		t := time.NewTimer(d)
-		select {
-		case <-ctx.Done():
-			t.Stop()
+		if !sleepContext(ctx, d) {
 			return
-		case <-t.C:
+		}
+		if partial && !r.needsRefresh(period) {
+			ctxRun, cancel := context.WithTimeout(ctx, 10*time.Minute)
+			if err := r.RetryFailed(ctxRun, period); err != nil {
+				log.Printf("pivot partial refresh %s failed: %v", period, err)
+			}
+			cancel()
 		}
 	}
 }
 
-func nextRun(now time.Time, period Period, loc *time.Location) time.Time {
-	switch period {
-	case PeriodDaily:
-		// 延迟到 08:02 刷新，确保币安K线数据已完全收盘
-		t := time.Date(now.Year(), now.Month(), now.Day(), 8, 2, 0, 0, loc)
-		if !now.Before(t) {
-			t = t.AddDate(0, 0, 1)
-		}
-		return t
-	case PeriodWeekly:
-		// 延迟到 08:02 刷新
-		today := time.Date(now.Year(), now.Month(), now.Day(), 8, 2, 0, 0, loc)
-		delta := (int(time.Monday) - int(now.Weekday()) + 7) % 7
-		t := today.AddDate(0, 0, delta)
-		if delta == 0 && !now.Before(today) {
-			t = t.AddDate(0, 0, 7)
-		}
-		return t
-	default:
-		return now.Add(24 * time.Hour)
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
 	}
 }
 
+// refreshDelay 延迟2分钟刷新，确保币安K线数据已完全收盘
+const refreshDelay = 2 * time.Minute
+
 type PivotPeriodStatus struct {
 	UpdatedAt     *time.Time `json:"updated_at"`
 	NextRefreshAt time.Time  `json:"next_refresh_at"`
 	SecondsUntil  int64      `json:"seconds_until"`
 	IsStale       bool       `json:"is_stale"`
 	SymbolCount   int        `json:"symbol_count"`
+	// Failed lists the symbols missing from the snapshot and why.
+	FailedCount int             `json:"failed_count"`
+	Failed      []SymbolFailure `json:"failed,omitempty"`
 }
 
 type PivotStatusResponse struct {
-	Daily  PivotPeriodStatus `json:"daily"`
-	Weekly PivotPeriodStatus `json:"weekly"`
+	Daily   PivotPeriodStatus            `json:"daily"`
+	Weekly  PivotPeriodStatus            `json:"weekly"`
+	Periods map[Period]PivotPeriodStatus `json:"periods"`
 }
 
 func (r *Refresher) PivotStatus() PivotStatusResponse {
-	loc, err := time.LoadLocation("Asia/Shanghai")
-	if err != nil {
-		loc = time.FixedZone("UTC+8", 8*60*60)
-	}
-
-	now := time.Now().In(loc)
+	now := time.Now()
 
 	buildStatus := func(period Period) PivotPeriodStatus {
 		snap, _ := r.Store.Snapshot(period)
-		next := nextRun(now, period, loc)
+		next := r.Session.nextRefresh(now, period)
 		status := PivotPeriodStatus{
 			NextRefreshAt: next.UTC(),
 			SecondsUntil:  int64(time.Until(next).Seconds()),
-			IsStale:       r.needsRefresh(period, loc),
+			IsStale:       r.needsRefresh(period),
 		}
 		if snap != nil {
 			t := snap.UpdatedAt
 			status.UpdatedAt = &t
 			status.SymbolCount = len(snap.Symbols)
 		}
+		status.Failed = r.Failures(period)
+		status.FailedCount = len(status.Failed)
 		return status
 	}
 
-	return PivotStatusResponse{
-		Daily:  buildStatus(PeriodDaily),
-		Weekly: buildStatus(PeriodWeekly),
+	resp := PivotStatusResponse{
+		Daily:   buildStatus(PeriodDaily),
+		Weekly:  buildStatus(PeriodWeekly),
+		Periods: make(map[Period]PivotPeriodStatus),
+	}
+	for _, p := range r.Store.Periods() {
+		resp.Periods[p] = buildStatus(p)
 	}
+	return resp
 }
@@ -1,8 +1,15 @@
 package pivot
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"example.com/binance-pivot-monitor/internal/binance"
 )
 
 func TestGetThisWeekMonday(t *testing.T) {
@@ -247,3 +254,131 @@ func TestProperty_StaleDetectionPersistence(t *testing.T) {
 		}
 	}
 }
+
+func TestNextRun_Periods(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Shanghai")
+	s := DefaultSession()
+	s.Location = loc
+
+	tests := []struct {
+		name     string
+		period   Period
+		now      time.Time
+		wantLast string
+		wantNext string
+	}{
+		{"daily before close", PeriodDaily, time.Date(2025, 1, 6, 7, 0, 0, 0, loc), "2025-01-05 08:02", "2025-01-06 08:02"},
+		{"daily after close", PeriodDaily, time.Date(2025, 1, 6, 9, 0, 0, 0, loc), "2025-01-06 08:02", "2025-01-07 08:02"},
+		{"weekly monday early", PeriodWeekly, time.Date(2025, 1, 6, 8, 0, 0, 0, loc), "2024-12-30 08:02", "2025-01-06 08:02"},
+		{"weekly sunday", PeriodWeekly, time.Date(2025, 1, 5, 10, 0, 0, 0, loc), "2024-12-30 08:02", "2025-01-06 08:02"},
+		{"monthly", PeriodMonthly, time.Date(2025, 1, 31, 10, 0, 0, 0, loc), "2025-01-01 08:02", "2025-02-01 08:02"},
+		{"monthly first day early", PeriodMonthly, time.Date(2025, 3, 1, 8, 1, 0, 0, loc), "2025-02-01 08:02", "2025-03-01 08:02"},
+		// 4h 周期按 UTC 对齐：UTC 00/04/08... 即北京时间 08/12/16...
+		{"4h", Period4h, time.Date(2025, 1, 6, 13, 0, 0, 0, loc), "2025-01-06 12:02", "2025-01-06 16:02"},
+		{"8h", Period8h, time.Date(2025, 1, 6, 16, 1, 0, 0, loc), "2025-01-06 08:02", "2025-01-06 16:02"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			last := s.lastRefresh(tt.now, tt.period).In(loc)
+			if got := last.Format("2006-01-02 15:04"); got != tt.wantLast {
+				t.Errorf("lastRefresh = %s, want %s", got, tt.wantLast)
+			}
+			next := s.nextRefresh(tt.now, tt.period).In(loc)
+			if got := next.Format("2006-01-02 15:04"); got != tt.wantNext {
+				t.Errorf("nextRefresh = %s, want %s", got, tt.wantNext)
+			}
+			if !next.After(tt.now) {
+				t.Errorf("nextRefresh %s is not after now %s", next, tt.now)
+			}
+		})
+	}
+}
+
+func TestRefresh_RetriesFailedSymbols(t *testing.T) {
+	var ethUp, ethCalls, xrpCalls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/fapi/v1/exchangeInfo":
+			fmt.Fprint(w, `{"symbols":[`+
+				`{"symbol":"BTCUSDT","status":"TRADING","contractType":"PERPETUAL","quoteAsset":"USDT"},`+
+				`{"symbol":"ETHUSDT","status":"TRADING","contractType":"PERPETUAL","quoteAsset":"USDT"},`+
+				`{"symbol":"XRPUSDT","status":"TRADING","contractType":"PERPETUAL","quoteAsset":"USDT"}]}`)
+		case "/fapi/v1/klines":
+			switch r.URL.Query().Get("symbol") {
+			case "ETHUSDT":
+				atomic.AddInt32(&ethCalls, 1)
+				if atomic.LoadInt32(&ethUp) == 0 {
+					http.Error(w, `{"code":-1000}`, http.StatusBadRequest)
+					return
+				}
+			case "XRPUSDT":
+				// 第一次失败，同一轮刷新内重试成功
+				if atomic.AddInt32(&xrpCalls, 1) == 1 {
+					http.Error(w, `{"code":-1000}`, http.StatusBadRequest)
+					return
+				}
+			}
+			fmt.Fprint(w, `[[0,"95","100","90","98","1",1],[1,"98","110","95","105","1",2]]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	store := NewStore(PeriodDaily)
+	r := NewRefresher(dir, store, binance.NewRESTClient(srv.URL))
+	r.Methods = []Method{MethodCamarilla, MethodClassic}
+	r.RetryRounds = 1
+	r.RetryBackoff = time.Millisecond
+
+	if err := r.Refresh(context.Background(), PeriodDaily); err != nil {
+		t.Fatalf("Refresh error: %v", err)
+	}
+	snap, _ := store.Snapshot(PeriodDaily)
+	if len(snap.Symbols) != 2 || snap.Symbols["XRPUSDT"] == (Levels{}) {
+		t.Fatalf("symbols = %v", snap.Symbols)
+	}
+	failed := r.Failures(PeriodDaily)
+	if len(failed) != 1 || failed[0].Symbol != "ETHUSDT" || failed[0].Attempts != 2 || ethCalls != 2 {
+		t.Fatalf("failures = %+v calls=%d", failed, ethCalls)
+	}
+	if st := r.PivotStatus().Periods[PeriodDaily]; st.FailedCount != 1 || len(st.Failed) != 1 {
+		t.Errorf("status = %+v", st)
+	}
+
+	// 后续补算：仍失败时累加次数
+	if err := r.RetryFailed(context.Background(), PeriodDaily); err != nil {
+		t.Fatal(err)
+	}
+	if failed := r.Failures(PeriodDaily); len(failed) != 1 || failed[0].Attempts != 3 {
+		t.Fatalf("failures = %+v", failed)
+	}
+
+	atomic.StoreInt32(&ethUp, 1)
+	if err := r.RetryFailed(context.Background(), PeriodDaily); err != nil {
+		t.Fatal(err)
+	}
+	merged, _ := store.Snapshot(PeriodDaily)
+	if merged == snap || len(merged.Symbols) != 3 || len(snap.Symbols) != 2 {
+		t.Fatalf("merged = %d symbols, old snapshot = %d", len(merged.Symbols), len(snap.Symbols))
+	}
+	if _, ok := merged.Levels(MethodClassic, "ETHUSDT"); !ok {
+		t.Error("classic levels not merged")
+	}
+	if !merged.UpdatedAt.Equal(snap.UpdatedAt) {
+		t.Error("partial refresh changed updated_at")
+	}
+	if len(r.Failures(PeriodDaily)) != 0 {
+		t.Errorf("failures = %+v", r.Failures(PeriodDaily))
+	}
+	onDisk, err := LoadSnapshotFile(dir + "/pivots/" + PeriodDaily.fileName())
+	if err != nil || len(onDisk.Symbols) != 3 {
+		t.Errorf("on disk = %+v, %v", onDisk, err)
+	}
+	archived, err := r.Archive.At(PeriodDaily, time.Now())
+	if err != nil || len(archived.Symbols) != 3 || !archived.SessionStart.Equal(merged.SessionStart) {
+		t.Errorf("archived = %+v, %v", archived, err)
+	}
+}
@@ -0,0 +1,214 @@
+package pivot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"example.com/binance-pivot-monitor/internal/binance"
+)
+
+// Session anchors daily, weekly and monthly pivot periods on a local session
+// close. Intraday periods always follow Binance's UTC-aligned candles.
+type Session struct {
+	Location *time.Location
+	// Close is the session close as an offset from local midnight, e.g. 17h
+	// for a New York 17:00 close.
+	Close     time.Duration
+	WeekStart time.Weekday
+	// Delay postpones the refresh after the close so the last candle is final.
+	Delay time.Duration
+}
+
+// DefaultSession matches Binance's UTC daily candle: 08:00 Asia/Shanghai,
+// weeks starting on Monday, refreshed at 08:02.
+func DefaultSession() Session {
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		loc = time.FixedZone("UTC+8", 8*60*60)
+	}
+	return Session{
+		Location:  loc,
+		Close:     8 * time.Hour,
+		WeekStart: time.Monday,
+		Delay:     refreshDelay,
+	}
+}
+
+// ParseSession builds a session from a timezone name, a "HH:MM" close time
+// and a week start day name.
+func ParseSession(tz, closeAt, weekStart string) (Session, error) {
+	s := DefaultSession()
+
+	if tz = strings.TrimSpace(tz); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return Session{}, fmt.Errorf("invalid timezone %q: %w", tz, err)
+		}
+		s.Location = loc
+	}
+
+	if closeAt = strings.TrimSpace(closeAt); closeAt != "" {
+		t, err := time.Parse("15:04", closeAt)
+		if err != nil {
+			return Session{}, fmt.Errorf("invalid session close %q: want HH:MM", closeAt)
+		}
+		s.Close = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+
+	if weekStart = strings.TrimSpace(weekStart); weekStart != "" {
+		found := false
+		for d := time.Sunday; d <= time.Saturday; d++ {
+			name := d.String()
+			if strings.EqualFold(weekStart, name) || strings.EqualFold(weekStart, name[:3]) {
+				s.WeekStart = d
+				found = true
+				break
+			}
+		}
+		if !found {
+			return Session{}, fmt.Errorf("invalid week start %q", weekStart)
+		}
+	}
+
+	return s, nil
+}
+
+func (s Session) String() string {
+	return fmt.Sprintf("%s %02d:%02d week=%s", s.Location, int(s.Close.Hours()), int(s.Close.Minutes())%60, s.WeekStart)
+}
+
+// closeOn returns the session close on the local calendar day of t.
+func (s Session) closeOn(y int, m time.Month, d int) time.Time {
+	h := int(s.Close / time.Hour)
+	min := int(s.Close % time.Hour / time.Minute)
+	return time.Date(y, m, d, h, min, 0, 0, s.Location)
+}
+
+// Start returns the start of the period that contains t.
+func (s Session) Start(t time.Time, period Period) time.Time {
+	if d, ok := period.intraday(); ok {
+		return t.Truncate(d).In(s.Location)
+	}
+
+	t = t.In(s.Location)
+	switch period {
+	case PeriodWeekly:
+		back := (int(t.Weekday()) - int(s.WeekStart) + 7) % 7
+		start := s.closeOn(t.Year(), t.Month(), t.Day()-back)
+		if t.Before(start) {
+			start = s.closeOn(t.Year(), t.Month(), t.Day()-back-7)
+		}
+		return start
+	case PeriodMonthly:
+		start := s.closeOn(t.Year(), t.Month(), 1)
+		if t.Before(start) {
+			start = s.closeOn(t.Year(), t.Month()-1, 1)
+		}
+		return start
+	default:
+		start := s.closeOn(t.Year(), t.Month(), t.Day())
+		if t.Before(start) {
+			start = s.closeOn(t.Year(), t.Month(), t.Day()-1)
+		}
+		return start
+	}
+}
+
+// Next returns the start of the period following the one that starts at start.
+func (s Session) Next(start time.Time, period Period) time.Time {
+	if d, ok := period.intraday(); ok {
+		return start.Add(d)
+	}
+
+	start = start.In(s.Location)
+	y, m, d := start.Date()
+	switch period {
+	case PeriodWeekly:
+		return s.closeOn(y, m, d+7)
+	case PeriodMonthly:
+		return s.closeOn(y, m+1, 1)
+	default:
+		return s.closeOn(y, m, d+1)
+	}
+}
+
+// PrevBounds returns [start, end) of the last completed period before t.
+func (s Session) PrevBounds(t time.Time, period Period) (start, end time.Time) {
+	end = s.Start(t, period)
+	start = s.Start(end.Add(-time.Second), period)
+	return start, end
+}
+
+// Aligned reports whether a period starting at start coincides with a
+// Binance candle of the same interval, so the exchange's candle can be used
+// directly instead of aggregating lower-timeframe klines.
+func (s Session) Aligned(period Period, start time.Time) bool {
+	if _, ok := period.intraday(); ok {
+		return true
+	}
+	u := start.UTC()
+	if u.Hour() != 0 || u.Minute() != 0 || u.Second() != 0 {
+		return false
+	}
+	switch period {
+	case PeriodWeekly:
+		return u.Weekday() == time.Monday
+	case PeriodMonthly:
+		return u.Day() == 1
+	default:
+		return true
+	}
+}
+
+// lastRefresh returns the most recent refresh time at or before now.
+func (s Session) lastRefresh(now time.Time, period Period) time.Time {
+	return s.Start(now.Add(-s.Delay), period).Add(s.Delay)
+}
+
+// nextRefresh returns the first refresh time strictly after now.
+func (s Session) nextRefresh(now time.Time, period Period) time.Time {
+	start := s.Start(now.Add(-s.Delay), period)
+	return s.Next(start, period).Add(s.Delay)
+}
+
+// aggregateInterval picks the largest kline interval whose candles tile
+// [start, end) exactly.
+func aggregateInterval(start, end time.Time) string {
+	for _, iv := range []struct {
+		name string
+		d    time.Duration
+	}{
+		{"1h", time.Hour},
+		{"30m", 30 * time.Minute},
+		{"15m", 15 * time.Minute},
+		{"5m", 5 * time.Minute},
+	} {
+		if start.Truncate(iv.d).Equal(start) && end.Truncate(iv.d).Equal(end) {
+			return iv.name
+		}
+	}
+	return "1m"
+}
+
+// AggregateOHLC merges consecutive klines into one candle.
+func AggregateOHLC(klines []binance.Kline) (OHLC, bool) {
+	if len(klines) == 0 {
+		return OHLC{}, false
+	}
+	out := OHLC{
+		Open:  klines[0].Open,
+		High:  klines[0].High,
+		Low:   klines[0].Low,
+		Close: klines[len(klines)-1].Close,
+	}
+	for _, k := range klines[1:] {
+		if k.High > out.High {
+			out.High = k.High
+		}
+		if k.Low < out.Low {
+			out.Low = k.Low
+		}
+	}
+	return out, true
+}
@@ -0,0 +1,120 @@
+package pivot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"example.com/binance-pivot-monitor/internal/binance"
+)
+
+func TestSession_NewYorkClose(t *testing.T) {
+	s, err := ParseSession("America/New_York", "17:00", "sunday")
+	if err != nil {
+		t.Fatalf("ParseSession error: %v", err)
+	}
+	ny := s.Location
+
+	now := time.Date(2025, 1, 8, 10, 0, 0, 0, ny) // Wednesday
+	start, end := s.PrevBounds(now, PeriodDaily)
+	if want := time.Date(2025, 1, 6, 17, 0, 0, 0, ny); !start.Equal(want) {
+		t.Errorf("daily start = %s, want %s", start, want)
+	}
+	if want := time.Date(2025, 1, 7, 17, 0, 0, 0, ny); !end.Equal(want) {
+		t.Errorf("daily end = %s, want %s", end, want)
+	}
+	if s.Aligned(PeriodDaily, start) {
+		t.Error("New York 17:00 close should not align with binance daily candle")
+	}
+	if iv := aggregateInterval(start, end); iv != "1h" {
+		t.Errorf("aggregateInterval = %s, want 1h", iv)
+	}
+
+	start, end = s.PrevBounds(now, PeriodWeekly)
+	if want := time.Date(2024, 12, 29, 17, 0, 0, 0, ny); !start.Equal(want) {
+		t.Errorf("weekly start = %s, want %s", start, want)
+	}
+	if want := time.Date(2025, 1, 5, 17, 0, 0, 0, ny); !end.Equal(want) {
+		t.Errorf("weekly end = %s, want %s", end, want)
+	}
+
+	// 17:01 is already in the next session, refreshed at 17:02
+	next := s.nextRefresh(time.Date(2025, 1, 8, 17, 1, 0, 0, ny), PeriodDaily)
+	if want := time.Date(2025, 1, 8, 17, 2, 0, 0, ny); !next.Equal(want) {
+		t.Errorf("nextRefresh = %s, want %s", next, want)
+	}
+}
+
+func TestSession_DefaultIsAligned(t *testing.T) {
+	s := DefaultSession()
+	now := time.Date(2025, 2, 12, 12, 0, 0, 0, time.UTC)
+	for _, p := range []Period{PeriodDaily, PeriodWeekly, PeriodMonthly, Period4h} {
+		start, _ := s.PrevBounds(now, p)
+		if !s.Aligned(p, start) {
+			t.Errorf("%s: default session start %s should align with binance candles", p, start.UTC())
+		}
+	}
+}
+
+func TestParseSession_Invalid(t *testing.T) {
+	for _, tc := range [][3]string{
+		{"Nowhere/City", "08:00", "monday"},
+		{"UTC", "25:00", "monday"},
+		{"UTC", "08:00", "someday"},
+	} {
+		if _, err := ParseSession(tc[0], tc[1], tc[2]); err == nil {
+			t.Errorf("ParseSession(%q, %q, %q): expected error", tc[0], tc[1], tc[2])
+		}
+	}
+}
+
+// TestRefresh_AggregatesUnalignedSession tests that an unaligned session is
+// computed from hourly klines instead of the exchange's daily candle.
+func TestRefresh_AggregatesUnalignedSession(t *testing.T) {
+	var gotInterval string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/fapi/v1/exchangeInfo":
+			fmt.Fprint(w, `{"symbols":[{"symbol":"BTCUSDT","status":"TRADING","contractType":"PERPETUAL","quoteAsset":"USDT"}]}`)
+		case "/fapi/v1/klines":
+			q := r.URL.Query()
+			gotInterval = q.Get("interval")
+			start, _ := strconv.ParseInt(q.Get("startTime"), 10, 64)
+			var rows [][]any
+			for i := 0; i < 24; i++ {
+				open := start + int64(i)*int64(time.Hour/time.Millisecond)
+				high, low := 100.0+float64(i), 90.0-float64(i)
+				rows = append(rows, []any{open, "95", fmt.Sprint(high), fmt.Sprint(low), fmt.Sprint(90 + i), "1", open + 3599999})
+			}
+			_ = json.NewEncoder(w).Encode(rows)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	store := NewStore(PeriodDaily)
+	r := NewRefresher(t.TempDir(), store, binance.NewRESTClient(srv.URL))
+	s, _ := ParseSession("America/New_York", "17:00", "")
+	r.Session = s
+
+	if err := r.Refresh(context.Background(), PeriodDaily); err != nil {
+		t.Fatalf("Refresh error: %v", err)
+	}
+	if gotInterval != "1h" {
+		t.Errorf("klines interval = %q, want 1h", gotInterval)
+	}
+	lv, ok := store.GetLevels(PeriodDaily, "BTCUSDT")
+	if !ok {
+		t.Fatal("no levels for BTCUSDT")
+	}
+	want, _ := Calculate(123, 67, 113)
+	if !almostEqual(lv.R3, want.R3) || !almostEqual(lv.S3, want.S3) {
+		t.Errorf("levels = %+v, want %+v", lv, want)
+	}
+}
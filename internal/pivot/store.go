@@ -2,62 +2,125 @@ package pivot
 
 import (
 	"errors"
+	"sort"
+	"sync"
 	"sync/atomic"
 	"time"
 )
 
-type Period string
+// Snapshot holds the levels of every symbol for one period. Symbols carries
+// the primary method; additional configured methods live in Methods.
+type Snapshot struct {
+	Period    Period    `json:"period"`
+	Method    Method    `json:"method,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// SessionStart is when the session these levels apply to began.
+	SessionStart time.Time                    `json:"session_start,omitempty"`
+	Symbols      map[string]Levels            `json:"symbols"`
+	Methods      map[Method]map[string]Levels `json:"methods,omitempty"`
+}
 
-const (
-	PeriodDaily  Period = "1d"
-	PeriodWeekly Period = "1w"
-)
+// PrimaryMethod returns the method of Symbols. Snapshots written before
+// methods existed are Camarilla.
+func (s *Snapshot) PrimaryMethod() Method {
+	if s.Method == "" {
+		return DefaultMethod
+	}
+	return s.Method
+}
 
-type Snapshot struct {
-	Period    Period            `json:"period"`
-	UpdatedAt time.Time         `json:"updated_at"`
-	Symbols   map[string]Levels `json:"symbols"`
+// Levels returns the levels of symbol for method. An empty method selects
+// the primary method.
+func (s *Snapshot) Levels(method Method, symbol string) (Levels, bool) {
+	if method == "" || method == s.PrimaryMethod() {
+		lv, ok := s.Symbols[symbol]
+		return lv, ok
+	}
+	lv, ok := s.Methods[method][symbol]
+	return lv, ok
+}
+
+// AvailableMethods returns the primary method followed by the extra ones.
+func (s *Snapshot) AvailableMethods() []Method {
+	out := []Method{s.PrimaryMethod()}
+	for m := range s.Methods {
+		if m != s.PrimaryMethod() {
+			out = append(out, m)
+		}
+	}
+	sort.Slice(out[1:], func(i, j int) bool { return out[i+1] < out[j+1] })
+	return out
 }
 
+// Store holds the current snapshot of every registered period. Snapshots
+// are swapped atomically so readers never see a partially built period.
 type Store struct {
-	daily  atomic.Value
-	weekly atomic.Value
+	mu      sync.RWMutex
+	periods []Period
+	snaps   map[Period]*atomic.Value
 }
 
-func NewStore() *Store {
-	s := &Store{}
-	s.daily.Store((*Snapshot)(nil))
-	s.weekly.Store((*Snapshot)(nil))
+// NewStore creates a store with the given periods registered, or
+// DefaultPeriods if none are given.
+func NewStore(periods ...Period) *Store {
+	if len(periods) == 0 {
+		periods = DefaultPeriods
+	}
+	s := &Store{snaps: make(map[Period]*atomic.Value)}
+	for _, p := range periods {
+		s.Register(p)
+	}
 	return s
 }
 
+// Register adds a period to the store. Registering a period twice is a no-op.
+func (s *Store) Register(period Period) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.snaps[period]; ok {
+		return
+	}
+	v := &atomic.Value{}
+	v.Store((*Snapshot)(nil))
+	s.snaps[period] = v
+	s.periods = append(s.periods, period)
+}
+
+// Periods returns the registered periods in registration order.
+func (s *Store) Periods() []Period {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]Period, len(s.periods))
+	copy(out, s.periods)
+	return out
+}
+
+func (s *Store) slot(period Period) (*atomic.Value, bool) {
+	s.mu.RLock()
+	v, ok := s.snaps[period]
+	s.mu.RUnlock()
+	return v, ok
+}
+
 func (s *Store) Snapshot(period Period) (*Snapshot, error) {
-	switch period {
-	case PeriodDaily:
-		snap, _ := s.daily.Load().(*Snapshot)
-		return snap, nil
-	case PeriodWeekly:
-		snap, _ := s.weekly.Load().(*Snapshot)
-		return snap, nil
-	default:
+	v, ok := s.slot(period)
+	if !ok {
 		return nil, errors.New("unknown period")
 	}
+	snap, _ := v.Load().(*Snapshot)
+	return snap, nil
 }
 
 func (s *Store) Swap(period Period, snap *Snapshot) error {
 	if snap == nil {
 		return errors.New("nil snapshot")
 	}
-	switch period {
-	case PeriodDaily:
-		s.daily.Store(snap)
-		return nil
-	case PeriodWeekly:
-		s.weekly.Store(snap)
-		return nil
-	default:
+	v, ok := s.slot(period)
+	if !ok {
 		return errors.New("unknown period")
 	}
+	v.Store(snap)
+	return nil
 }
 
 func (s *Store) GetLevels(period Period, symbol string) (Levels, bool) {
@@ -68,3 +131,12 @@ func (s *Store) GetLevels(period Period, symbol string) (Levels, bool) {
 	lv, ok := snap.Symbols[symbol]
 	return lv, ok
 }
+
+// GetMethodLevels returns the levels of symbol for a specific method.
+func (s *Store) GetMethodLevels(period Period, method Method, symbol string) (Levels, bool) {
+	snap, err := s.Snapshot(period)
+	if err != nil || snap == nil {
+		return Levels{}, false
+	}
+	return snap.Levels(method, symbol)
+}
@@ -0,0 +1,200 @@
+// Package recorder writes decoded mark price frames to hourly gzip JSONL
+// files and reads them back for offline replay.
+package recorder
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"example.com/binance-pivot-monitor/internal/binance"
+)
+
+const (
+	filePrefix = "markprice-"
+	fileSuffix = ".jsonl.gz"
+	hourLayout = "20060102-15"
+)
+
+// Frame is one decoded !markPrice@arr@1s message.
+type Frame struct {
+	Time   time.Time                `json:"t"`
+	Events []binance.MarkPriceEvent `json:"e"`
+}
+
+// Recorder appends frames to <dir>/markprice-YYYYMMDD-HH.jsonl.gz, one file
+// per UTC hour. Each frame is flushed so a crash loses at most the frame
+// being written.
+type Recorder struct {
+	dir string
+
+	mu     sync.Mutex
+	hour   time.Time
+	f      *os.File
+	gz     *gzip.Writer
+	bw     *bufio.Writer
+	frames int64
+}
+
+// New creates a recorder writing into dir.
+func New(dir string) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Recorder{dir: dir}, nil
+}
+
+// FileName returns the file name holding frames of the given hour.
+func FileName(t time.Time) string {
+	return filePrefix + t.UTC().Format(hourLayout) + fileSuffix
+}
+
+// Record writes one frame.
+func (r *Recorder) Record(ts time.Time, events []binance.MarkPriceEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	b, err := json.Marshal(Frame{Time: ts.UTC(), Events: events})
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	hour := ts.UTC().Truncate(time.Hour)
+	if r.gz == nil || !hour.Equal(r.hour) {
+		if err := r.closeLocked(); err != nil {
+			return err
+		}
+		if err := r.openLocked(hour); err != nil {
+			return err
+		}
+	}
+
+	if _, err := r.bw.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	if err := r.bw.Flush(); err != nil {
+		return err
+	}
+	if err := r.gz.Flush(); err != nil {
+		return err
+	}
+	r.frames++
+	return nil
+}
+
+// Frames returns the number of frames written since the recorder was created.
+func (r *Recorder) Frames() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.frames
+}
+
+// Close finishes the current file.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.closeLocked()
+}
+
+func (r *Recorder) openLocked(hour time.Time) error {
+	// 同一小时重启时追加为新的 gzip member，读取时按多 member 流处理
+	f, err := os.OpenFile(filepath.Join(r.dir, FileName(hour)), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	r.f = f
+	r.gz = gzip.NewWriter(f)
+	r.bw = bufio.NewWriter(r.gz)
+	r.hour = hour
+	return nil
+}
+
+func (r *Recorder) closeLocked() error {
+	if r.gz == nil {
+		return nil
+	}
+	var errs []error
+	errs = append(errs, r.bw.Flush(), r.gz.Close(), r.f.Close())
+	r.f, r.gz, r.bw = nil, nil, nil
+	return errors.Join(errs...)
+}
+
+// Files returns the recording files in dir covering [from, to), oldest
+// first. A zero from or to leaves that side open.
+func Files(dir string, from, to time.Time) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+		hour, err := time.Parse(hourLayout, strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileSuffix))
+		if err != nil {
+			continue
+		}
+		if !from.IsZero() && !hour.Add(time.Hour).After(from) {
+			continue
+		}
+		if !to.IsZero() && !hour.Before(to) {
+			continue
+		}
+		out = append(out, filepath.Join(dir, name))
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// ReadFile calls fn for every frame in path. A truncated tail, as left by a
+// crash, ends the file without error.
+func ReadFile(path string, fn func(Frame) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	defer gz.Close()
+
+	sc := bufio.NewScanner(gz)
+	sc.Buffer(make([]byte, 0, 1<<20), 16<<20)
+	for sc.Scan() {
+		line := sc.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var fr Frame
+		if err := json.Unmarshal(line, &fr); err != nil {
+			// 最后一行可能在崩溃时只写了一半
+			continue
+		}
+		if err := fn(fr); err != nil {
+			return err
+		}
+	}
+	if err := sc.Err(); err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package recorder
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"example.com/binance-pivot-monitor/internal/binance"
+)
+
+func readAll(t *testing.T, files []string) []Frame {
+	t.Helper()
+	var out []Frame
+	for _, f := range files {
+		if err := ReadFile(f, func(fr Frame) error {
+			out = append(out, fr)
+			return nil
+		}); err != nil {
+			t.Fatalf("ReadFile(%s): %v", f, err)
+		}
+	}
+	return out
+}
+
+func TestRecorder_RoundTripAndRotation(t *testing.T) {
+	dir := t.TempDir()
+	rec, err := New(dir)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	base := time.Date(2025, 1, 6, 9, 59, 58, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		ts := base.Add(time.Duration(i) * time.Second)
+		ev := []binance.MarkPriceEvent{{EventTime: ts.UnixMilli(), Symbol: "BTCUSDT", MarkPrice: "100.5"}}
+		if err := rec.Record(ts, ev); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	files, err := Files(dir, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Files: %v", err)
+	}
+	if len(files) != 2 || filepath.Base(files[0]) != "markprice-20250106-09.jsonl.gz" {
+		t.Fatalf("files = %v, want 09 and 10 hour files", files)
+	}
+
+	frames := readAll(t, files)
+	if len(frames) != 4 {
+		t.Fatalf("frames = %d, want 4", len(frames))
+	}
+	for i, fr := range frames {
+		if !fr.Time.Equal(base.Add(time.Duration(i) * time.Second)) {
+			t.Errorf("frame %d time = %s", i, fr.Time)
+		}
+		if len(fr.Events) != 1 || fr.Events[0].Symbol != "BTCUSDT" || fr.Events[0].MarkPrice != "100.5" {
+			t.Errorf("frame %d events = %+v", i, fr.Events)
+		}
+	}
+
+	// Only the 10:00 file overlaps [10:00, 11:00).
+	files, _ = Files(dir, base.Add(2*time.Second), base.Add(time.Hour))
+	if len(files) != 1 || filepath.Base(files[0]) != "markprice-20250106-10.jsonl.gz" {
+		t.Errorf("filtered files = %v", files)
+	}
+}
+
+func TestRecorder_ReopenAndTruncatedTail(t *testing.T) {
+	dir := t.TempDir()
+	ts := time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC)
+	ev := []binance.MarkPriceEvent{{Symbol: "ETHUSDT", MarkPrice: "3000"}}
+
+	// Two runs writing into the same hour append gzip members.
+	for run := 0; run < 2; run++ {
+		rec, err := New(dir)
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		_ = rec.Record(ts.Add(time.Duration(run)*time.Second), ev)
+		_ = rec.Close()
+	}
+
+	path := filepath.Join(dir, FileName(ts))
+	if n := len(readAll(t, []string{path})); n != 2 {
+		t.Fatalf("frames after reopen = %d, want 2", n)
+	}
+
+	// A recorder that crashed without Close leaves a stream without trailer.
+	rec, _ := New(dir)
+	_ = rec.Record(ts.Add(2*time.Second), ev)
+	b, _ := os.ReadFile(path)
+	crashed := filepath.Join(t.TempDir(), FileName(ts))
+	if err := os.WriteFile(crashed, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_ = rec.Close()
+
+	if n := len(readAll(t, []string{crashed})); n != 3 {
+		t.Errorf("frames from unterminated file = %d, want 3", n)
+	}
+}
@@ -0,0 +1,223 @@
+package signal
+
+import (
+	"bufio"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultCombinedHistoryMax is the default number of combined signals kept.
+const DefaultCombinedHistoryMax = 5000
+
+// CombinedHistory stores combined pivot+pattern signals in memory and,
+// optionally, in a JSONL file.
+type CombinedHistory struct {
+	mu        sync.RWMutex
+	signals   []CombinedSignal
+	maxSize   int
+	filePath  string
+	file      *os.File
+	fileLines int
+}
+
+// NewCombinedHistory creates a history. An empty filePath keeps signals in
+// memory only.
+func NewCombinedHistory(filePath string, maxSize int) (*CombinedHistory, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultCombinedHistoryMax
+	}
+	h := &CombinedHistory{maxSize: maxSize, filePath: filePath}
+	if filePath == "" {
+		return h, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return nil, err
+	}
+	if err := h.load(); err != nil && !os.IsNotExist(err) {
+		log.Printf("WARN: combined history load failed: %v", err)
+	}
+	f, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	h.file = f
+	return h, nil
+}
+
+func (h *CombinedHistory) load() error {
+	f, err := os.Open(h.filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		h.fileLines++
+		var cs CombinedSignal
+		if err := json.Unmarshal(sc.Bytes(), &cs); err != nil || cs.ID == "" {
+			continue
+		}
+		h.signals = append(h.signals, cs)
+	}
+	if len(h.signals) > h.maxSize {
+		h.signals = h.signals[len(h.signals)-h.maxSize:]
+	}
+	return sc.Err()
+}
+
+// Add stores a combined signal.
+func (h *CombinedHistory) Add(cs CombinedSignal) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.signals = append(h.signals, cs)
+	if len(h.signals) > h.maxSize {
+		h.signals = h.signals[len(h.signals)-h.maxSize:]
+	}
+	if h.file == nil {
+		return nil
+	}
+
+	b, err := json.Marshal(cs)
+	if err != nil {
+		return err
+	}
+	if _, err := h.file.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	h.fileLines++
+	if h.fileLines > h.maxSize*2 {
+		if err := h.compactLocked(); err != nil {
+			log.Printf("WARN: combined history compact failed: %v", err)
+		}
+	}
+	return nil
+}
+
+// CombinedQuery filters combined signals. Empty fields match everything.
+type CombinedQuery struct {
+	Symbol string
+	// Correlations lists accepted strengths; MinCorrelation accepts that
+	// strength and stronger.
+	Correlations   []CorrelationStrength
+	MinCorrelation CorrelationStrength
+	Direction      string // pivot direction, up or down
+	Since          time.Time
+	Limit          int
+}
+
+// Query returns matching signals, newest first.
+func (h *CombinedHistory) Query(q CombinedQuery) []CombinedSignal {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var out []CombinedSignal
+	for i := len(h.signals) - 1; i >= 0; i-- {
+		cs := h.signals[i]
+		if !q.Match(cs) {
+			continue
+		}
+		out = append(out, cs)
+		if q.Limit > 0 && len(out) >= q.Limit {
+			break
+		}
+	}
+	return out
+}
+
+// Match reports whether cs passes the query's filters (Limit is ignored).
+func (q CombinedQuery) Match(cs CombinedSignal) bool {
+	if q.Symbol != "" && cs.Symbol != q.Symbol {
+		return false
+	}
+	if len(q.Correlations) > 0 {
+		ok := false
+		for _, c := range q.Correlations {
+			if c == cs.Correlation {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	if q.MinCorrelation != "" && cs.Correlation.Rank() < q.MinCorrelation.Rank() {
+		return false
+	}
+	if q.Direction != "" && (cs.PivotSignal == nil || cs.PivotSignal.Direction != q.Direction) {
+		return false
+	}
+	if !q.Since.IsZero() && cs.CombinedAt.Before(q.Since) {
+		return false
+	}
+	return true
+}
+
+// Count returns the number of signals in memory.
+func (h *CombinedHistory) Count() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.signals)
+}
+
+// Close closes the history file.
+func (h *CombinedHistory) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.file == nil {
+		return nil
+	}
+	err := h.file.Close()
+	h.file = nil
+	return err
+}
+
+// compactLocked rewrites the file with the signals in memory.
+func (h *CombinedHistory) compactLocked() error {
+	tmp := h.filePath + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(f)
+	enc := json.NewEncoder(bw)
+	for _, cs := range h.signals {
+		if err := enc.Encode(cs); err != nil {
+			f.Close()
+			os.Remove(tmp)
+			return err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, h.filePath); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	// 原文件已被替换，重新打开用于追加
+	h.file.Close()
+	nf, err := os.OpenFile(h.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		h.file = nil
+		return err
+	}
+	h.file = nf
+	h.fileLines = len(h.signals)
+	return nil
+}
@@ -0,0 +1,84 @@
+package signal
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"example.com/binance-pivot-monitor/internal/pattern"
+)
+
+func combined(id, symbol, dir string, corr CorrelationStrength, at time.Time) CombinedSignal {
+	return CombinedSignal{
+		ID:            id,
+		Symbol:        symbol,
+		PivotSignal:   &Signal{ID: id, Symbol: symbol, Direction: dir},
+		PatternSignal: &pattern.Signal{Symbol: symbol},
+		Correlation:   corr,
+		CombinedAt:    at,
+	}
+}
+
+func TestCombinedHistory_QueryAndReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "signals", "combined.jsonl")
+	h, err := NewCombinedHistory(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t0 := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	_ = h.Add(combined("a", "BTCUSDT", "up", CorrelationStrong, t0))
+	_ = h.Add(combined("b", "ETHUSDT", "down", CorrelationWeak, t0.Add(time.Minute)))
+	_ = h.Add(combined("c", "BTCUSDT", "down", CorrelationModerate, t0.Add(2*time.Minute)))
+	_ = h.Add(combined("d", "BTCUSDT", "up", CorrelationStrong, t0.Add(3*time.Minute)))
+
+	if h.Count() != 3 {
+		t.Fatalf("count = %d, want 3 (max size)", h.Count())
+	}
+
+	got := h.Query(CombinedQuery{Symbol: "BTCUSDT", MinCorrelation: CorrelationModerate})
+	if len(got) != 2 || got[0].ID != "d" || got[1].ID != "c" {
+		t.Errorf("min moderate = %+v", got)
+	}
+	got = h.Query(CombinedQuery{Correlations: []CorrelationStrength{CorrelationWeak}})
+	if len(got) != 1 || got[0].ID != "b" {
+		t.Errorf("weak only = %+v", got)
+	}
+	got = h.Query(CombinedQuery{Direction: "up", Limit: 1})
+	if len(got) != 1 || got[0].ID != "d" {
+		t.Errorf("direction up = %+v", got)
+	}
+	_ = h.Close()
+
+	h2, err := NewCombinedHistory(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h2.Close()
+	if got := h2.Query(CombinedQuery{}); len(got) != 3 || got[2].ID != "b" {
+		t.Errorf("reloaded = %+v", got)
+	}
+}
+
+func TestCombiner_CombinedID(t *testing.T) {
+	c := NewCombiner(15 * time.Minute)
+	now := time.Now()
+	pat := pattern.NewSignal("BTCUSDT", pattern.PatternHammer, pattern.DirectionBullish, 75, now)
+	c.AddPatternSignal(pat)
+
+	var got []CombinedSignal
+	c.SetOnCombined(func(cs CombinedSignal) { got = append(got, cs) })
+	c.AddPivotSignal(Signal{ID: "p1", Symbol: "BTCUSDT", Direction: "up", TriggeredAt: now})
+
+	if len(got) != 1 || got[0].ID != "p1+"+pat.ID || got[0].Symbol != "BTCUSDT" {
+		t.Errorf("combined = %+v", got)
+	}
+}
+
+func TestParseCorrelation(t *testing.T) {
+	if c, err := ParseCorrelation(" Strong "); err != nil || c != CorrelationStrong {
+		t.Errorf("ParseCorrelation = %q, %v", c, err)
+	}
+	if _, err := ParseCorrelation("huge"); err == nil {
+		t.Error("expected error")
+	}
+}
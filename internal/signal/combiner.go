@@ -1,6 +1,8 @@
 package signal
 
 import (
+	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,8 +18,35 @@ const (
 	CorrelationWeak     CorrelationStrength = "weak"     // Direction conflict
 )
 
+// Rank orders correlation strengths: strong 3, moderate 2, weak 1, unknown 0.
+func (c CorrelationStrength) Rank() int {
+	switch c {
+	case CorrelationStrong:
+		return 3
+	case CorrelationModerate:
+		return 2
+	case CorrelationWeak:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// ParseCorrelation parses strong, moderate or weak.
+func ParseCorrelation(s string) (CorrelationStrength, error) {
+	c := CorrelationStrength(strings.ToLower(strings.TrimSpace(s)))
+	if c.Rank() == 0 {
+		return "", fmt.Errorf("unknown correlation %q", s)
+	}
+	return c, nil
+}
+
 // CombinedSignal represents a correlated pivot and pattern signal.
 type CombinedSignal struct {
+	// ID is "<pivot id>+<pattern id>", unique per pair.
+	ID            string           `json:"id"`
+	Symbol        string           `json:"symbol"`
+	Kind          string           `json:"kind"` // pivot signal kind: cross or reject
 	PivotSignal   *Signal          `json:"pivot_signal"`
 	PatternSignal *pattern.Signal  `json:"pattern_signal"`
 	Correlation   CorrelationStrength `json:"correlation"`
@@ -50,7 +79,9 @@ func (c *Combiner) SetOnCombined(fn func(CombinedSignal)) {
 	c.onCombined = fn
 }
 
-// AddPivotSignal adds a pivot signal and checks for correlations.
+// AddPivotSignal adds a pivot signal and checks for correlations. Reject
+// signals are correlated too; their direction is the expected move away from
+// the level, and the combined signal carries the kind.
 func (c *Combiner) AddPivotSignal(sig Signal) []CombinedSignal {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -67,6 +98,9 @@ func (c *Combiner) AddPivotSignal(sig Signal) []CombinedSignal {
 		if c.isWithinWindow(sig.TriggeredAt, pat.DetectedAt) {
 			corr := c.checkCorrelation(sig, *pat)
 			cs := CombinedSignal{
+				ID:            sig.ID + "+" + pat.ID,
+				Symbol:        sig.Symbol,
+				Kind:          sig.KindOrCross(),
 				PivotSignal:   &sig,
 				PatternSignal: pat,
 				Correlation:   corr,
@@ -100,6 +134,9 @@ func (c *Combiner) AddPatternSignal(sig pattern.Signal) []CombinedSignal {
 		if c.isWithinWindow(piv.TriggeredAt, sig.DetectedAt) {
 			corr := c.checkCorrelation(*piv, sig)
 			cs := CombinedSignal{
+				ID:            piv.ID + "+" + sig.ID,
+				Symbol:        sig.Symbol,
+				Kind:          piv.KindOrCross(),
 				PivotSignal:   piv,
 				PatternSignal: &sig,
 				Correlation:   corr,
@@ -37,6 +37,18 @@ func TestCombiner_AddPivotSignal(t *testing.T) {
 	if combined[0].Correlation != CorrelationStrong {
 		t.Errorf("Expected strong correlation, got %s", combined[0].Correlation)
 	}
+	if combined[0].Kind != KindCross {
+		t.Errorf("Expected kind %s, got %s", KindCross, combined[0].Kind)
+	}
+
+	// Reject signals are combined and labeled
+	rejSig := pivSig
+	rejSig.ID = "test-2"
+	rejSig.Kind = KindReject
+	combined = c.AddPivotSignal(rejSig)
+	if len(combined) != 1 || combined[0].Kind != KindReject {
+		t.Fatalf("Expected 1 combined reject signal, got %+v", combined)
+	}
 }
 
 func TestCombiner_AddPatternSignal(t *testing.T) {
@@ -14,16 +14,19 @@ import (
 
 // Period constants for bucket keys
 const (
-	PeriodDaily  = "1d"
-	PeriodWeekly = "1w"
-	PeriodOther  = "other"
+	PeriodDaily   = "1d"
+	PeriodWeekly  = "1w"
+	PeriodMonthly = "1M"
+	PeriodOther   = "other"
 )
 
 // Default capacity ratios for period buckets
 const (
-	dailyRatio  = 0.80 // 80% for daily signals
-	weeklyRatio = 0.15 // 15% for weekly signals
-	otherRatio  = 0.05 // 5% for other signals
+	dailyRatio    = 0.80 // 80% for daily signals
+	weeklyRatio   = 0.15 // 15% for weekly signals
+	otherRatio    = 0.05 // 5% for other signals
+	monthlyRatio  = 0.05 // monthly signals are rare
+	intradayRatio = 0.15 // per intraday period (4h, 8h, ...)
 )
 
 // periodBucket holds signals for a specific period with independent capacity.
@@ -47,15 +50,56 @@ func newPeriodBucket(max int) *periodBucket {
 }
 
 // normalizePeriod converts various period formats to standard bucket keys.
+// Every pivot period gets its own key; only unrecognized values share the
+// other bucket.
 func normalizePeriod(period string) string {
-	switch strings.ToLower(strings.TrimSpace(period)) {
+	v := strings.TrimSpace(period)
+	// "1M" is monthly, "1m" is not a pivot period
+	if v == PeriodMonthly {
+		return PeriodMonthly
+	}
+	v = strings.ToLower(v)
+	switch v {
 	case "1d", "d", "daily":
 		return PeriodDaily
 	case "1w", "w", "weekly":
 		return PeriodWeekly
-	default:
-		return PeriodOther
+	case "monthly", "month":
+		return PeriodMonthly
+	}
+	if isIntraday(v) {
+		return v
 	}
+	return PeriodOther
+}
+
+// isIntraday reports whether v is an "Nh" session key.
+func isIntraday(v string) bool {
+	n := strings.TrimSuffix(v, "h")
+	if n == v || n == "" {
+		return false
+	}
+	for _, c := range n {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// periodRatio returns the share of total capacity a bucket is sized from.
+func periodRatio(key string) float64 {
+	switch key {
+	case PeriodDaily:
+		return dailyRatio
+	case PeriodWeekly:
+		return weeklyRatio
+	case PeriodMonthly:
+		return monthlyRatio
+	case PeriodOther:
+		return otherRatio
+	}
+	return intradayRatio
 }
 
 type History struct {
@@ -85,42 +129,57 @@ type History struct {
 }
 
 func NewHistory(max int) *History {
+	return NewHistoryForPeriods(max, []string{PeriodDaily, PeriodWeekly})
+}
+
+// NewHistoryForPeriods creates a history with one bucket per registered
+// period plus the other bucket. Capacity is split by period ratio, scaled so
+// the buckets add up to max; with only 1d and 1w this matches NewHistory.
+func NewHistoryForPeriods(max int, periods []string) *History {
 	if max <= 0 {
 		max = 10000
 	}
 
-	// Calculate default capacity for each period based on ratios
-	dailyMax := int(float64(max) * dailyRatio)
-	weeklyMax := int(float64(max) * weeklyRatio)
-	otherMax := int(float64(max) * otherRatio)
-
-	// Ensure minimum capacity
-	if dailyMax < 100 {
-		dailyMax = 100
-	}
-	if weeklyMax < 100 {
-		weeklyMax = 100
-	}
-	if otherMax < 50 {
-		otherMax = 50
+	keys := []string{PeriodOther}
+	seen := map[string]bool{PeriodOther: true}
+	for _, p := range periods {
+		key := normalizePeriod(p)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
 	}
 
-	periodMax := map[string]int{
-		PeriodDaily:  dailyMax,
-		PeriodWeekly: weeklyMax,
-		PeriodOther:  otherMax,
+	total := 0.0
+	for _, key := range keys {
+		total += periodRatio(key)
+	}
+	scale := 1.0
+	if total > 1 {
+		scale = 1 / total
 	}
 
-	buckets := map[string]*periodBucket{
-		PeriodDaily:  newPeriodBucket(dailyMax),
-		PeriodWeekly: newPeriodBucket(weeklyMax),
-		PeriodOther:  newPeriodBucket(otherMax),
+	periodMax := make(map[string]int, len(keys))
+	buckets := make(map[string]*periodBucket, len(keys))
+	for _, key := range keys {
+		n := int(float64(max) * periodRatio(key) * scale)
+		// Ensure minimum capacity
+		minCap := 50
+		if key == PeriodDaily || key == PeriodWeekly {
+			minCap = 100
+		}
+		if n < minCap {
+			n = minCap
+		}
+		periodMax[key] = n
+		buckets[key] = newPeriodBucket(n)
 	}
 
 	return &History{
 		max:        max,
 		periodMax:  periodMax,
-		defaultMax: otherMax,
+		defaultMax: periodMax[PeriodOther],
 		buckets:    buckets,
 		separated:  true, // Use separated storage by default
 	}
@@ -673,7 +732,7 @@ func (h *History) Query(symbolContains, period, level, direction, source string,
 // queryFromBuckets queries signals from period-separated buckets.
 func (h *History) queryFromBuckets(symbolContains, period, level, direction, source string, limit int) []Signal {
 	symbolContains = strings.TrimSpace(symbolContains)
-	period = strings.ToLower(strings.TrimSpace(period))
+	period = strings.TrimSpace(period)
 	level = strings.TrimSpace(level)
 	direction = strings.ToLower(strings.TrimSpace(direction))
 	source = strings.TrimSpace(source)
@@ -734,6 +793,10 @@ func (h *History) queryFromBuckets(symbolContains, period, level, direction, sou
 			if periodKey != "" && normalizePeriod(s.Period) != periodKey {
 				continue
 			}
+			// The other bucket mixes unrecognized periods, so match those exactly
+			if periodKey == PeriodOther && !strings.EqualFold(s.Period, period) {
+				continue
+			}
 			if level != "" && s.Level != level {
 				continue
 			}
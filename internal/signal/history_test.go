@@ -726,3 +726,44 @@ func TestMigrationFromUnified(t *testing.T) {
 		t.Errorf("Expected 3 weekly signals, got %d", len(weeklyResults))
 	}
 }
+
+// TestHistory_RegisteredPeriodBuckets tests that every registered period gets
+// its own bucket, so frequent intraday signals can't evict monthly ones.
+func TestHistory_RegisteredPeriodBuckets(t *testing.T) {
+	h := NewHistoryForPeriods(2000, []string{"1d", "1w", "1M", "4h"})
+
+	total := 0
+	for _, key := range []string{PeriodDaily, PeriodWeekly, PeriodMonthly, "4h", PeriodOther} {
+		b, ok := h.buckets[key]
+		if !ok {
+			t.Fatalf("missing bucket %s", key)
+		}
+		total += b.max
+	}
+	if total > 2000 {
+		t.Errorf("bucket capacities add up to %d, want <= 2000", total)
+	}
+
+	base := time.Now()
+	h.Add(Signal{ID: "M", Symbol: "BTCUSDT", Period: "1M", Level: "R3", Direction: "up", TriggeredAt: base})
+	for i := 0; i < 1000; i++ {
+		h.Add(Signal{ID: "H", Symbol: "BTCUSDT", Period: "4h", Level: "R3", Direction: "up", TriggeredAt: base.Add(time.Duration(i+1) * time.Second)})
+	}
+
+	if got := h.Query("", "1M", "", "", "", 100); len(got) != 1 || got[0].ID != "M" {
+		t.Fatalf("monthly query = %+v, want the monthly signal", got)
+	}
+	if got := h.Query("", "monthly", "", "", "", 100); len(got) != 1 {
+		t.Errorf("monthly alias query returned %d signals, want 1", len(got))
+	}
+	if got := h.Query("", "4h", "", "", "", 4000); len(got) != h.buckets["4h"].max {
+		t.Errorf("4h query returned %d signals, want bucket capacity %d", len(got), h.buckets["4h"].max)
+	}
+
+	// 1d and 1w alone keep the original split
+	d := NewHistoryForPeriods(1000, []string{"1d", "1w"})
+	if d.buckets[PeriodDaily].max != 800 || d.buckets[PeriodWeekly].max != 150 || d.buckets[PeriodOther].max != 50 {
+		t.Errorf("default split = %d/%d/%d, want 800/150/50",
+			d.buckets[PeriodDaily].max, d.buckets[PeriodWeekly].max, d.buckets[PeriodOther].max)
+	}
+}
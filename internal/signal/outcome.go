@@ -0,0 +1,410 @@
+package signal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultOutcomeHorizons are the offsets after a signal at which the mark
+// price is sampled.
+var DefaultOutcomeHorizons = []time.Duration{5 * time.Minute, 15 * time.Minute, time.Hour, 4 * time.Hour}
+
+// outcomeSampleGrace is how late the first price after a horizon may arrive
+// and still count as that horizon's sample, e.g. after a reconnect.
+const outcomeSampleGrace = 2 * time.Minute
+
+// Outcome is what price did after a signal. Returns, MFE and MAE are in
+// percent of the entry price, signed so that a move in the signal's
+// direction is positive.
+type Outcome struct {
+	SignalID    string             `json:"signal_id"`
+	Symbol      string             `json:"symbol"`
+	Direction   string             `json:"direction"`
+	EntryPrice  float64            `json:"entry_price"`
+	TriggeredAt time.Time          `json:"triggered_at"`
+	Prices      map[string]float64 `json:"prices"`  // horizon -> mark price
+	Returns     map[string]float64 `json:"returns"` // horizon -> return
+	MFE         float64            `json:"mfe"`     // max favorable excursion
+	MAE         float64            `json:"mae"`     // max adverse excursion, <= 0
+	Complete    bool               `json:"complete"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+
+	next  int  // index of the next horizon to sample
+	dirty bool // excursions changed since the outcome was last written
+}
+
+// signedReturn returns the move from entry to price in the signal's direction.
+func (o *Outcome) signedReturn(price float64) float64 {
+	r := (price - o.EntryPrice) / o.EntryPrice * 100
+	if o.Direction == "down" {
+		return -r
+	}
+	return r
+}
+
+func (o *Outcome) clone() Outcome {
+	c := *o
+	c.Prices = make(map[string]float64, len(o.Prices))
+	for k, v := range o.Prices {
+		c.Prices[k] = v
+	}
+	c.Returns = make(map[string]float64, len(o.Returns))
+	for k, v := range o.Returns {
+		c.Returns[k] = v
+	}
+	return c
+}
+
+// OutcomeTracker follows every emitted signal for the longest horizon and
+// records the mark price at each horizon together with the excursions in
+// between. Outcomes are persisted as JSONL; the last line of a signal wins.
+type OutcomeTracker struct {
+	mu       sync.Mutex
+	horizons []time.Duration
+	max      int
+	byID     map[string]*Outcome
+	order    []string              // signal IDs, oldest first
+	open     map[string][]*Outcome // symbol -> outcomes still being tracked
+
+	filePath  string
+	fileLines int
+}
+
+// NewOutcomeTracker keeps up to max outcomes. Horizons default to
+// DefaultOutcomeHorizons and must be ascending.
+func NewOutcomeTracker(max int, horizons ...time.Duration) *OutcomeTracker {
+	if max <= 0 {
+		max = 10000
+	}
+	if len(horizons) == 0 {
+		horizons = DefaultOutcomeHorizons
+	}
+	return &OutcomeTracker{
+		horizons: horizons,
+		max:      max,
+		byID:     make(map[string]*Outcome),
+		open:     make(map[string][]*Outcome),
+	}
+}
+
+// Horizons returns the horizon names in order, e.g. "5m", "1h".
+func (t *OutcomeTracker) Horizons() []string {
+	out := make([]string, len(t.horizons))
+	for i, h := range t.horizons {
+		out[i] = horizonKey(h)
+	}
+	return out
+}
+
+func horizonKey(d time.Duration) string {
+	switch {
+	case d%time.Hour == 0:
+		return strconv.Itoa(int(d/time.Hour)) + "h"
+	case d%time.Minute == 0:
+		return strconv.Itoa(int(d/time.Minute)) + "m"
+	default:
+		return d.String()
+	}
+}
+
+// EnablePersistence loads outcomes from filePath and appends every update
+// to it. Outcomes still open are resumed.
+func (t *OutcomeTracker) EnablePersistence(filePath string) error {
+	filePath = strings.TrimSpace(filePath)
+	if filePath == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		t.filePath = filePath
+		return nil
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lines := 0
+	for scanner.Scan() {
+		lines++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var o Outcome
+		if err := json.Unmarshal([]byte(line), &o); err != nil || o.SignalID == "" {
+			continue
+		}
+		if o.Prices == nil {
+			o.Prices = make(map[string]float64)
+		}
+		if o.Returns == nil {
+			o.Returns = make(map[string]float64)
+		}
+		if old, ok := t.byID[o.SignalID]; ok {
+			*old = o
+			continue
+		}
+		t.insertLocked(&o)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	for _, o := range t.byID {
+		if o.Complete {
+			continue
+		}
+		// 重启后从第一个未采样的周期继续
+		for o.next < len(t.horizons) {
+			if _, ok := o.Prices[horizonKey(t.horizons[o.next])]; !ok {
+				break
+			}
+			o.next++
+		}
+		t.open[o.Symbol] = append(t.open[o.Symbol], o)
+	}
+
+	t.filePath = filePath
+	t.fileLines = lines
+	if t.fileLines > t.max*2 {
+		if err := t.compactLocked(); err == nil {
+			t.fileLines = len(t.order)
+		}
+	}
+	return nil
+}
+
+// Track starts following sig from its trigger price.
+func (t *OutcomeTracker) Track(sig Signal) {
+	if sig.ID == "" || sig.Price <= 0 {
+		return
+	}
+	o := &Outcome{
+		SignalID:    sig.ID,
+		Symbol:      sig.Symbol,
+		Direction:   sig.Direction,
+		EntryPrice:  sig.Price,
+		TriggeredAt: sig.TriggeredAt,
+		Prices:      make(map[string]float64),
+		Returns:     make(map[string]float64),
+		UpdatedAt:   sig.TriggeredAt,
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.byID[sig.ID]; ok {
+		return
+	}
+	o.dirty = true
+	t.insertLocked(o)
+	t.open[o.Symbol] = append(t.open[o.Symbol], o)
+}
+
+func (t *OutcomeTracker) insertLocked(o *Outcome) {
+	t.byID[o.SignalID] = o
+	t.order = append(t.order, o.SignalID)
+	for len(t.order) > t.max {
+		id := t.order[0]
+		t.order = t.order[1:]
+		if old, ok := t.byID[id]; ok {
+			delete(t.byID, id)
+			if !old.Complete {
+				t.removeOpenLocked(old)
+			}
+		}
+	}
+}
+
+func (t *OutcomeTracker) removeOpenLocked(o *Outcome) {
+	list := t.open[o.Symbol]
+	for i, x := range list {
+		if x == o {
+			list = append(list[:i], list[i+1:]...)
+			break
+		}
+	}
+	if len(list) == 0 {
+		delete(t.open, o.Symbol)
+	} else {
+		t.open[o.Symbol] = list
+	}
+}
+
+// Update feeds one mark price. It is cheap for symbols without open outcomes.
+func (t *OutcomeTracker) Update(symbol string, price float64, ts time.Time) {
+	if price <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	list := t.open[symbol]
+	if len(list) == 0 {
+		return
+	}
+
+	kept := list[:0]
+	for _, o := range list {
+		if ts.Before(o.TriggeredAt) {
+			kept = append(kept, o)
+			continue
+		}
+		changed := false
+		for o.next < len(t.horizons) {
+			due := o.TriggeredAt.Add(t.horizons[o.next])
+			if ts.Before(due) {
+				break
+			}
+			if ts.Sub(due) <= outcomeSampleGrace {
+				key := horizonKey(t.horizons[o.next])
+				o.Prices[key] = price
+				o.Returns[key] = o.signedReturn(price)
+				changed = true
+			}
+			o.next++
+		}
+		// 超过最长周期后的价格不计入极值
+		if o.next < len(t.horizons) || changed {
+			r := o.signedReturn(price)
+			if r > o.MFE {
+				o.MFE = r
+				o.dirty = true
+			}
+			if r < o.MAE {
+				o.MAE = r
+				o.dirty = true
+			}
+		}
+		if o.next >= len(t.horizons) {
+			o.Complete = true
+			changed = true
+		}
+		if changed {
+			o.UpdatedAt = ts
+			_ = t.persistLocked(o)
+		}
+		if !o.Complete {
+			kept = append(kept, o)
+		}
+	}
+	if len(kept) == 0 {
+		delete(t.open, symbol)
+	} else {
+		t.open[symbol] = kept
+	}
+}
+
+// Get returns the outcome of a signal.
+func (t *OutcomeTracker) Get(signalID string) (Outcome, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	o, ok := t.byID[signalID]
+	if !ok {
+		return Outcome{}, false
+	}
+	return o.clone(), true
+}
+
+// Open returns the number of outcomes still being tracked.
+func (t *OutcomeTracker) Open() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n := 0
+	for _, list := range t.open {
+		n += len(list)
+	}
+	return n
+}
+
+// Flush writes the open outcomes whose excursions changed since they were
+// last written, so a restart resumes them with the running MFE and MAE.
+// Outcomes are otherwise only written at a horizon sample. It returns the
+// first write error.
+func (t *OutcomeTracker) Flush() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.filePath == "" {
+		return nil
+	}
+	var first error
+	for _, list := range t.open {
+		for _, o := range list {
+			if !o.dirty {
+				continue
+			}
+			if err := t.persistLocked(o); err != nil && first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
+
+func (t *OutcomeTracker) persistLocked(o *Outcome) error {
+	if t.filePath == "" {
+		return nil
+	}
+	f, err := os.OpenFile(t.filePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	err = json.NewEncoder(f).Encode(o)
+	_ = f.Close()
+	if err != nil {
+		return err
+	}
+	o.dirty = false
+	t.fileLines++
+	if t.fileLines > t.max*2 {
+		if err := t.compactLocked(); err == nil {
+			t.fileLines = len(t.order)
+		}
+	}
+	return nil
+}
+
+func (t *OutcomeTracker) compactLocked() error {
+	tmp := t.filePath + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(f)
+	enc := json.NewEncoder(bw)
+	for _, id := range t.order {
+		o, ok := t.byID[id]
+		if !ok {
+			continue
+		}
+		if err := enc.Encode(o); err != nil {
+			_ = bw.Flush()
+			_ = f.Close()
+			return err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, t.filePath)
+}
@@ -0,0 +1,157 @@
+package signal
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestOutcomeTracker_Horizons(t *testing.T) {
+	tr := NewOutcomeTracker(100)
+	t0 := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	tr.Track(Signal{ID: "a", Symbol: "BTCUSDT", Direction: "up", Price: 100, TriggeredAt: t0})
+	tr.Track(Signal{ID: "b", Symbol: "BTCUSDT", Direction: "down", Price: 100, TriggeredAt: t0})
+
+	tr.Update("BTCUSDT", 103, t0.Add(2*time.Minute))
+	tr.Update("BTCUSDT", 98, t0.Add(3*time.Minute))
+	tr.Update("BTCUSDT", 101, t0.Add(5*time.Minute))
+	tr.Update("ETHUSDT", 1, t0.Add(5*time.Minute))
+
+	up, ok := tr.Get("a")
+	if !ok {
+		t.Fatal("outcome a missing")
+	}
+	if !approx(up.Prices["5m"], 101) || !approx(up.Returns["5m"], 1) {
+		t.Errorf("a 5m = %v / %v, want 101 / 1", up.Prices["5m"], up.Returns["5m"])
+	}
+	if !approx(up.MFE, 3) || !approx(up.MAE, -2) {
+		t.Errorf("a mfe/mae = %v/%v, want 3/-2", up.MFE, up.MAE)
+	}
+	if _, ok := up.Prices["15m"]; ok || up.Complete {
+		t.Errorf("a should only have the 5m sample: %+v", up)
+	}
+
+	down, _ := tr.Get("b")
+	if !approx(down.Returns["5m"], -1) || !approx(down.MFE, 2) || !approx(down.MAE, -3) {
+		t.Errorf("b = %+v, want 5m -1, mfe 2, mae -3", down)
+	}
+
+	tr.Update("BTCUSDT", 104, t0.Add(15*time.Minute))
+	tr.Update("BTCUSDT", 105, t0.Add(time.Hour))
+	tr.Update("BTCUSDT", 110, t0.Add(4*time.Hour+time.Second))
+	if tr.Open() != 0 {
+		t.Fatalf("open = %d, want 0", tr.Open())
+	}
+	up, _ = tr.Get("a")
+	if !up.Complete || !approx(up.Returns["4h"], 10) || !approx(up.MFE, 10) {
+		t.Errorf("a = %+v, want complete with 4h return 10", up)
+	}
+
+	// 完成后的价格不再影响结果
+	tr.Update("BTCUSDT", 200, t0.Add(5*time.Hour))
+	up, _ = tr.Get("a")
+	if !approx(up.MFE, 10) {
+		t.Errorf("mfe changed after completion: %v", up.MFE)
+	}
+}
+
+func TestOutcomeTracker_LateSampleSkipped(t *testing.T) {
+	tr := NewOutcomeTracker(100)
+	t0 := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	tr.Track(Signal{ID: "a", Symbol: "BTCUSDT", Direction: "up", Price: 100, TriggeredAt: t0})
+
+	// 断线 20 分钟：5m 和 15m 都超过了采样宽限
+	tr.Update("BTCUSDT", 102, t0.Add(20*time.Minute))
+	o, _ := tr.Get("a")
+	if len(o.Prices) != 0 {
+		t.Errorf("late prices recorded: %v", o.Prices)
+	}
+	tr.Update("BTCUSDT", 103, t0.Add(time.Hour+30*time.Second))
+	o, _ = tr.Get("a")
+	if !approx(o.Prices["1h"], 103) {
+		t.Errorf("1h = %v, want 103", o.Prices["1h"])
+	}
+}
+
+func TestOutcomeTracker_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outcomes.jsonl")
+	t0 := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+
+	tr := NewOutcomeTracker(100)
+	if err := tr.EnablePersistence(path); err != nil {
+		t.Fatal(err)
+	}
+	tr.Track(Signal{ID: "a", Symbol: "BTCUSDT", Direction: "up", Price: 100, TriggeredAt: t0})
+	tr.Update("BTCUSDT", 101, t0.Add(5*time.Minute))
+	tr.Update("BTCUSDT", 102, t0.Add(15*time.Minute))
+
+	tr2 := NewOutcomeTracker(100)
+	if err := tr2.EnablePersistence(path); err != nil {
+		t.Fatal(err)
+	}
+	o, ok := tr2.Get("a")
+	if !ok || !approx(o.Prices["15m"], 102) || !approx(o.MFE, 2) {
+		t.Fatalf("reloaded = %+v, ok=%v", o, ok)
+	}
+	if tr2.Open() != 1 {
+		t.Fatalf("open = %d, want 1", tr2.Open())
+	}
+
+	// 恢复后继续采样剩余周期
+	tr2.Update("BTCUSDT", 99, t0.Add(time.Hour))
+	o, _ = tr2.Get("a")
+	if !approx(o.Prices["1h"], 99) || !approx(o.Prices["5m"], 101) {
+		t.Errorf("resumed = %+v", o)
+	}
+}
+
+func TestOutcomeTracker_FlushOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outcomes.jsonl")
+	t0 := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+
+	tr := NewOutcomeTracker(100)
+	if err := tr.EnablePersistence(path); err != nil {
+		t.Fatal(err)
+	}
+	tr.Track(Signal{ID: "a", Symbol: "BTCUSDT", Direction: "up", Price: 100, TriggeredAt: t0})
+	tr.Track(Signal{ID: "b", Symbol: "ETHUSDT", Direction: "down", Price: 10, TriggeredAt: t0})
+	tr.Update("BTCUSDT", 101, t0.Add(5*time.Minute))
+	// 采样之后的极值只在 Flush 时写入
+	tr.Update("BTCUSDT", 104, t0.Add(7*time.Minute))
+	tr.Update("BTCUSDT", 97, t0.Add(8*time.Minute))
+	if err := tr.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	tr2 := NewOutcomeTracker(100)
+	if err := tr2.EnablePersistence(path); err != nil {
+		t.Fatal(err)
+	}
+	a, ok := tr2.Get("a")
+	if !ok || !approx(a.MFE, 4) || !approx(a.MAE, -3) || !approx(a.Prices["5m"], 101) {
+		t.Errorf("reloaded a = %+v, ok=%v, want mfe 4, mae -3", a, ok)
+	}
+	// 尚未采样的结果也能恢复
+	if _, ok := tr2.Get("b"); !ok || tr2.Open() != 2 {
+		t.Errorf("b reloaded = %v, open = %d, want 2", ok, tr2.Open())
+	}
+}
+
+func TestOutcomeTracker_Capacity(t *testing.T) {
+	tr := NewOutcomeTracker(2)
+	t0 := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	for _, id := range []string{"a", "b", "c"} {
+		tr.Track(Signal{ID: id, Symbol: "BTCUSDT", Direction: "up", Price: 100, TriggeredAt: t0})
+	}
+	if _, ok := tr.Get("a"); ok {
+		t.Error("oldest outcome should be evicted")
+	}
+	if tr.Open() != 2 {
+		t.Errorf("open = %d, want 2", tr.Open())
+	}
+}
@@ -1,14 +1,44 @@
 package signal
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Signal kinds. Signals without a kind are crossings.
+const (
+	KindCross  = "cross"
+	KindReject = "reject"
+)
 
 type Signal struct {
-	ID          string    `json:"id"`
-	Symbol      string    `json:"symbol"`
-	Period      string    `json:"period"`
-	Level       string    `json:"level"`
-	Price       float64   `json:"price"`
-	Direction   string    `json:"direction"`
-	TriggeredAt time.Time `json:"triggered_at"`
-	Source      string    `json:"source"`
+	ID           string    `json:"id"`
+	Symbol       string    `json:"symbol"`
+	Period       string    `json:"period"`
+	Method       string    `json:"method,omitempty"`
+	Kind         string    `json:"kind,omitempty"` // cross or reject
+	Level        string    `json:"level"`
+	Price        float64   `json:"price"`
+	Direction    string    `json:"direction"`
+	Confirmation string    `json:"confirmation,omitempty"` // hold, kline_close, penetration; empty = crossing tick
+	TriggeredAt  time.Time `json:"triggered_at"`
+	Source       string    `json:"source"`
+}
+
+// KindOrCross returns the signal kind, treating an empty kind as a crossing.
+func (s Signal) KindOrCross() string {
+	if s.Kind == "" {
+		return KindCross
+	}
+	return s.Kind
+}
+
+// ParseKind parses cross or reject.
+func ParseKind(s string) (string, error) {
+	switch k := strings.ToLower(strings.TrimSpace(s)); k {
+	case KindCross, KindReject:
+		return k, nil
+	}
+	return "", fmt.Errorf("unknown signal kind %q", s)
 }
@@ -1,25 +1,117 @@
 package sse
 
-import "sync"
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Policy decides what Publish does when a subscriber's buffer is full.
+type Policy int
+
+const (
+	// DropNewest discards the message being published (the default).
+	DropNewest Policy = iota
+	// DropOldest discards the oldest buffered message to make room.
+	DropOldest
+	// Disconnect drops the message and closes the subscriber's channel once
+	// it has dropped MaxDrops messages. Pinned subscribers are never
+	// disconnected.
+	Disconnect
+)
+
+func (p Policy) String() string {
+	switch p {
+	case DropOldest:
+		return "drop-oldest"
+	case Disconnect:
+		return "disconnect"
+	default:
+		return "drop-newest"
+	}
+}
+
+// ParsePolicy parses drop-newest, drop-oldest or disconnect.
+func ParsePolicy(s string) (Policy, error) {
+	switch s {
+	case "", "drop-newest":
+		return DropNewest, nil
+	case "drop-oldest":
+		return DropOldest, nil
+	case "disconnect":
+		return Disconnect, nil
+	default:
+		return DropNewest, fmt.Errorf("unknown policy %q", s)
+	}
+}
+
+// DefaultMaxDrops is the Disconnect threshold when none is set.
+const DefaultMaxDrops = 100
+
+type subscriber struct {
+	label     string
+	since     time.Time
+	pinned    bool
+	delivered atomic.Uint64
+	dropped   atomic.Uint64
+}
 
 type Broker[T any] struct {
-	mu      sync.RWMutex
-	clients map[chan T]struct{}
+	mu       sync.RWMutex
+	clients  map[chan T]*subscriber
+	policy   Policy
+	maxDrops uint64
+
+	published    atomic.Uint64
+	dropped      atomic.Uint64
+	disconnected atomic.Uint64
 }
 
 func NewBroker[T any]() *Broker[T] {
 	return &Broker[T]{
-		clients: make(map[chan T]struct{}),
+		clients:  make(map[chan T]*subscriber),
+		maxDrops: DefaultMaxDrops,
 	}
 }
 
+// SetPolicy sets the slow-consumer policy. maxDrops only applies to
+// Disconnect; <= 0 uses DefaultMaxDrops.
+func (b *Broker[T]) SetPolicy(p Policy, maxDrops int) {
+	if maxDrops <= 0 {
+		maxDrops = DefaultMaxDrops
+	}
+	b.mu.Lock()
+	b.policy = p
+	b.maxDrops = uint64(maxDrops)
+	b.mu.Unlock()
+}
+
 func (b *Broker[T]) Subscribe(buffer int) chan T {
+	return b.SubscribeAs("", buffer)
+}
+
+// SubscribeAs subscribes with a label shown in Stats (e.g. the client
+// address).
+func (b *Broker[T]) SubscribeAs(label string, buffer int) chan T {
+	return b.subscribe(label, buffer, false)
+}
+
+// SubscribePinned subscribes an in-process consumer (the SSE hub, webhooks)
+// that cannot reconnect. The Disconnect policy never closes its channel;
+// messages it falls behind on are dropped and counted instead.
+func (b *Broker[T]) SubscribePinned(label string, buffer int) chan T {
+	return b.subscribe(label, buffer, true)
+}
+
+func (b *Broker[T]) subscribe(label string, buffer int, pinned bool) chan T {
 	if buffer <= 0 {
 		buffer = 16
 	}
 	ch := make(chan T, buffer)
 	b.mu.Lock()
-	b.clients[ch] = struct{}{}
+	b.clients[ch] = &subscriber{label: label, since: time.Now(), pinned: pinned}
 	b.mu.Unlock()
 	return ch
 }
@@ -34,14 +126,55 @@ func (b *Broker[T]) Unsubscribe(ch chan T) {
 }
 
 func (b *Broker[T]) Publish(msg T) {
+	b.published.Add(1)
+
+	var slow []chan T
 	b.mu.RLock()
-	for ch := range b.clients {
-		select {
-		case ch <- msg:
-		default:
+	for ch, sub := range b.clients {
+		if b.send(ch, msg) {
+			sub.delivered.Add(1)
+			continue
+		}
+		b.dropped.Add(1)
+		if sub.dropped.Add(1) >= b.maxDrops && b.policy == Disconnect && !sub.pinned {
+			slow = append(slow, ch)
 		}
 	}
 	b.mu.RUnlock()
+
+	// 超过丢弃上限的订阅者被断开，客户端重连后可通过 Last-Event-ID 补齐
+	for _, ch := range slow {
+		b.mu.Lock()
+		if _, ok := b.clients[ch]; ok {
+			delete(b.clients, ch)
+			close(ch)
+			b.disconnected.Add(1)
+		}
+		b.mu.Unlock()
+	}
+}
+
+// send delivers msg without blocking and reports whether it was queued.
+// Under DropOldest the oldest buffered message is dropped instead.
+func (b *Broker[T]) send(ch chan T, msg T) bool {
+	select {
+	case ch <- msg:
+		return true
+	default:
+	}
+	if b.policy != DropOldest {
+		return false
+	}
+	select {
+	case <-ch:
+	default:
+	}
+	select {
+	case ch <- msg:
+	default:
+	}
+	// 无论新消息是否入队，都有一条消息被丢弃
+	return false
 }
 
 // SubscriberCount returns the number of active subscribers.
@@ -50,3 +183,55 @@ func (b *Broker[T]) SubscriberCount() int {
 	defer b.mu.RUnlock()
 	return len(b.clients)
 }
+
+// SubscriberStats describes one subscriber.
+type SubscriberStats struct {
+	Label     string    `json:"label,omitempty"`
+	Since     time.Time `json:"since"`
+	Pinned    bool      `json:"pinned,omitempty"`
+	Buffer    int       `json:"buffer"`
+	Queued    int       `json:"queued"`
+	Delivered uint64    `json:"delivered"`
+	Dropped   uint64    `json:"dropped"`
+}
+
+// Stats is a snapshot of a broker's counters. Dropped includes drops of
+// subscribers that have since left.
+type Stats struct {
+	Policy       string            `json:"policy"`
+	MaxDrops     uint64            `json:"max_drops,omitempty"`
+	Published    uint64            `json:"published"`
+	Dropped      uint64            `json:"dropped"`
+	Disconnected uint64            `json:"disconnected"`
+	Subscribers  []SubscriberStats `json:"subscribers"`
+}
+
+// Stats returns the broker's counters and per-subscriber stats.
+func (b *Broker[T]) Stats() Stats {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	st := Stats{
+		Policy:       b.policy.String(),
+		Published:    b.published.Load(),
+		Dropped:      b.dropped.Load(),
+		Disconnected: b.disconnected.Load(),
+		Subscribers:  make([]SubscriberStats, 0, len(b.clients)),
+	}
+	if b.policy == Disconnect {
+		st.MaxDrops = b.maxDrops
+	}
+	for ch, sub := range b.clients {
+		st.Subscribers = append(st.Subscribers, SubscriberStats{
+			Label:     sub.label,
+			Since:     sub.since,
+			Pinned:    sub.pinned,
+			Buffer:    cap(ch),
+			Queued:    len(ch),
+			Delivered: sub.delivered.Load(),
+			Dropped:   sub.dropped.Load(),
+		})
+	}
+	sort.Slice(st.Subscribers, func(i, j int) bool { return st.Subscribers[i].Since.Before(st.Subscribers[j].Since) })
+	return st
+}
@@ -0,0 +1,157 @@
+package sse
+
+import (
+	"testing"
+)
+
+// drain returns everything queued on ch without blocking.
+func drain(ch chan int) []int {
+	var out []int
+	for {
+		select {
+		case v, ok := <-ch:
+			if !ok {
+				return out
+			}
+			out = append(out, v)
+		default:
+			return out
+		}
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	for in, want := range map[string]Policy{"": DropNewest, "drop-newest": DropNewest, "drop-oldest": DropOldest, "disconnect": Disconnect} {
+		got, err := ParsePolicy(in)
+		if err != nil || got != want {
+			t.Errorf("ParsePolicy(%q) = %v, %v", in, got, err)
+		}
+		if in != "" && got.String() != in {
+			t.Errorf("%v.String() = %q, want %q", got, got.String(), in)
+		}
+	}
+	if _, err := ParsePolicy("block"); err == nil {
+		t.Error("expected error for unknown policy")
+	}
+}
+
+func TestBroker_DropNewest(t *testing.T) {
+	b := NewBroker[int]()
+	ch := b.SubscribeAs("slow", 2)
+	for i := 1; i <= 5; i++ {
+		b.Publish(i)
+	}
+
+	if got := drain(ch); len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Errorf("received %v, want [1 2]", got)
+	}
+	st := b.Stats()
+	if st.Policy != "drop-newest" || st.Published != 5 || st.Dropped != 3 || st.Disconnected != 0 {
+		t.Errorf("stats = %+v", st)
+	}
+	if len(st.Subscribers) != 1 || st.Subscribers[0].Label != "slow" ||
+		st.Subscribers[0].Delivered != 2 || st.Subscribers[0].Dropped != 3 || st.Subscribers[0].Buffer != 2 {
+		t.Errorf("subscriber stats = %+v", st.Subscribers)
+	}
+}
+
+func TestBroker_DropOldest(t *testing.T) {
+	b := NewBroker[int]()
+	b.SetPolicy(DropOldest, 0)
+	ch := b.Subscribe(2)
+	for i := 1; i <= 5; i++ {
+		b.Publish(i)
+	}
+
+	// 缓冲区保留最新的消息
+	if got := drain(ch); len(got) != 2 || got[0] != 4 || got[1] != 5 {
+		t.Errorf("received %v, want [4 5]", got)
+	}
+	st := b.Stats()
+	if st.Policy != "drop-oldest" || st.Dropped != 3 || st.Subscribers[0].Delivered != 2 {
+		t.Errorf("stats = %+v", st)
+	}
+}
+
+func TestBroker_DisconnectAfterMaxDrops(t *testing.T) {
+	b := NewBroker[int]()
+	b.SetPolicy(Disconnect, 3)
+	slow := b.SubscribeAs("slow", 1)
+	fast := b.SubscribeAs("fast", 16)
+
+	b.Publish(1) // fills slow's buffer
+	b.Publish(2)
+	b.Publish(3)
+	if b.SubscriberCount() != 2 {
+		t.Fatalf("disconnected after 2 drops, want 3")
+	}
+	b.Publish(4) // third drop
+
+	if b.SubscriberCount() != 1 {
+		t.Fatalf("subscribers = %d, want slow consumer disconnected", b.SubscriberCount())
+	}
+	if got := drain(slow); len(got) != 1 || got[0] != 1 {
+		t.Errorf("slow received %v, want [1]", got)
+	}
+	if _, ok := <-slow; ok {
+		t.Error("slow channel still open after disconnect")
+	}
+	if got := drain(fast); len(got) != 4 {
+		t.Errorf("fast received %v, want all 4", got)
+	}
+
+	st := b.Stats()
+	if st.Policy != "disconnect" || st.MaxDrops != 3 || st.Dropped != 3 || st.Disconnected != 1 {
+		t.Errorf("stats = %+v", st)
+	}
+
+	// Unsubscribing a disconnected channel must not close it twice.
+	b.Unsubscribe(slow)
+	b.Publish(5)
+	if st := b.Stats(); st.Disconnected != 1 || st.Dropped != 3 {
+		t.Errorf("stats after unsubscribe = %+v", st)
+	}
+}
+
+func TestBroker_UnsubscribeClosesChannel(t *testing.T) {
+	b := NewBroker[int]()
+	ch := b.Subscribe(4)
+	b.Publish(1)
+	b.Unsubscribe(ch)
+
+	if v, ok := <-ch; !ok || v != 1 {
+		t.Errorf("buffered message lost on unsubscribe: %v, %v", v, ok)
+	}
+	if _, ok := <-ch; ok {
+		t.Error("channel not closed after unsubscribe")
+	}
+	b.Publish(2)
+	if b.SubscriberCount() != 0 || b.Stats().Dropped != 0 {
+		t.Errorf("stats after unsubscribe = %+v", b.Stats())
+	}
+	b.Unsubscribe(ch) // no-op
+}
+
+func TestBroker_PinnedNotDisconnected(t *testing.T) {
+	b := NewBroker[int]()
+	b.SetPolicy(Disconnect, 2)
+	pinned := b.SubscribePinned("hub", 1)
+	for i := 1; i <= 5; i++ {
+		b.Publish(i)
+	}
+
+	if b.SubscriberCount() != 1 {
+		t.Fatal("pinned subscriber was disconnected")
+	}
+	if got := drain(pinned); len(got) != 1 || got[0] != 1 {
+		t.Errorf("pinned received %v, want [1]", got)
+	}
+	b.Publish(6)
+	if got := drain(pinned); len(got) != 1 || got[0] != 6 {
+		t.Errorf("pinned received %v after catching up, want [6]", got)
+	}
+	st := b.Stats()
+	if st.Disconnected != 0 || st.Dropped != 4 || !st.Subscribers[0].Pinned {
+		t.Errorf("stats = %+v", st)
+	}
+}
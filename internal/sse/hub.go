@@ -0,0 +1,130 @@
+package sse
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// DefaultReplaySize is the number of replayable events a Hub keeps.
+const DefaultReplaySize = 1024
+
+// Event is a stream event with a hub-wide ID. Data is the JSON payload,
+// Value the original message (for filtering).
+type Event struct {
+	ID    uint64
+	Name  string
+	Data  []byte
+	Value any
+}
+
+// Hub assigns monotonic IDs to events from all streams, fans them out to
+// subscribers and keeps the last replayable events in a ring buffer so
+// reconnecting clients can catch up from their Last-Event-ID.
+//
+// IDs start at the hub's creation time in microseconds, so they keep
+// increasing across restarts and an ID from a previous process is always
+// older than the ring.
+type Hub struct {
+	broker *Broker[Event]
+
+	mu      sync.Mutex
+	next    uint64
+	ring    []Event
+	head    int // index of the oldest event
+	count   int
+	evicted uint64 // highest ID no longer replayable
+}
+
+// NewHub creates a hub that keeps up to size replayable events.
+func NewHub(size int) *Hub {
+	if size <= 0 {
+		size = DefaultReplaySize
+	}
+	start := uint64(time.Now().UnixMicro())
+	return &Hub{
+		broker:  NewBroker[Event](),
+		next:    start,
+		ring:    make([]Event, size),
+		evicted: start - 1,
+	}
+}
+
+// Publish assigns an ID to v and broadcasts it. Replayable events are kept
+// for Since.
+func (h *Hub) Publish(name string, v any, replay bool) (Event, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return Event{}, err
+	}
+
+	h.mu.Lock()
+	ev := Event{ID: h.next, Name: name, Data: b, Value: v}
+	h.next++
+	if replay {
+		if h.count == len(h.ring) {
+			h.evicted = h.ring[h.head].ID
+			h.ring[h.head] = ev
+			h.head = (h.head + 1) % len(h.ring)
+		} else {
+			h.ring[(h.head+h.count)%len(h.ring)] = ev
+			h.count++
+		}
+	}
+	// 在锁内广播，保证订阅者收到的 ID 单调递增
+	h.broker.Publish(ev)
+	h.mu.Unlock()
+	return ev, nil
+}
+
+// Since returns the replayable events after id. complete is false when
+// events after id have already left the ring.
+func (h *Hub) Since(id uint64) (events []Event, complete bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i := 0; i < h.count; i++ {
+		ev := h.ring[(h.head+i)%len(h.ring)]
+		if ev.ID > id {
+			events = append(events, ev)
+		}
+	}
+	return events, id >= h.evicted
+}
+
+// LastID returns the ID of the last published event. Before the first
+// event it is just below the first ID the hub will assign.
+func (h *Hub) LastID() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.next - 1
+}
+
+// Subscribe returns a channel of live events.
+func (h *Hub) Subscribe(buffer int) chan Event {
+	return h.broker.Subscribe(buffer)
+}
+
+// SubscribeAs subscribes with a label shown in Stats.
+func (h *Hub) SubscribeAs(label string, buffer int) chan Event {
+	return h.broker.SubscribeAs(label, buffer)
+}
+
+// SetPolicy sets the slow-consumer policy for subscribers.
+func (h *Hub) SetPolicy(p Policy, maxDrops int) {
+	h.broker.SetPolicy(p, maxDrops)
+}
+
+// Stats returns the subscriber stats of the hub.
+func (h *Hub) Stats() Stats {
+	return h.broker.Stats()
+}
+
+// Unsubscribe removes a subscriber.
+func (h *Hub) Unsubscribe(ch chan Event) {
+	h.broker.Unsubscribe(ch)
+}
+
+// SubscriberCount returns the number of active subscribers.
+func (h *Hub) SubscriberCount() int {
+	return h.broker.SubscriberCount()
+}
@@ -0,0 +1,129 @@
+package sse
+
+import (
+	"testing"
+)
+
+func publish(t *testing.T, h *Hub, name string, v any, replay bool) Event {
+	t.Helper()
+	ev, err := h.Publish(name, v, replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ev
+}
+
+func ids(events []Event) []uint64 {
+	out := make([]uint64, len(events))
+	for i, ev := range events {
+		out[i] = ev.ID
+	}
+	return out
+}
+
+func TestHub_IDsIncrease(t *testing.T) {
+	h := NewHub(4)
+	a := publish(t, h, "signal", 1, true)
+	b := publish(t, h, "ticker", 2, false)
+	c := publish(t, h, "signal", 3, true)
+	if !(a.ID < b.ID && b.ID < c.ID) {
+		t.Errorf("ids = %d, %d, %d, want increasing", a.ID, b.ID, c.ID)
+	}
+	if string(c.Data) != "3" || c.Value != 3 {
+		t.Errorf("event = %+v", c)
+	}
+}
+
+func TestHub_SinceAfterWraparound(t *testing.T) {
+	h := NewHub(3)
+	var evs []Event
+	for i := 0; i < 5; i++ {
+		evs = append(evs, publish(t, h, "signal", i, true))
+	}
+
+	// The ring holds the last three events; a client that saw evs[1] has
+	// missed nothing that was evicted.
+	got, complete := h.Since(evs[1].ID)
+	if !complete {
+		t.Error("since evs[1]: complete = false, want true")
+	}
+	if want := ids(evs[2:]); len(got) != 3 || got[0].ID != want[0] || got[2].ID != want[2] {
+		t.Errorf("since evs[1] = %v, want %v", ids(got), want)
+	}
+
+	got, complete = h.Since(evs[4].ID)
+	if !complete || len(got) != 0 {
+		t.Errorf("since newest = %v (complete %v), want nothing missed", ids(got), complete)
+	}
+
+	// 环形缓冲区再绕一圈后仍按 ID 顺序返回
+	for i := 5; i < 8; i++ {
+		evs = append(evs, publish(t, h, "signal", i, true))
+	}
+	got, complete = h.Since(evs[5].ID)
+	if !complete || len(got) != 2 || got[0].ID != evs[6].ID || got[1].ID != evs[7].ID {
+		t.Errorf("since evs[5] = %v (complete %v), want %v", ids(got), complete, ids(evs[6:]))
+	}
+}
+
+func TestHub_SinceGap(t *testing.T) {
+	h := NewHub(3)
+	var evs []Event
+	for i := 0; i < 5; i++ {
+		evs = append(evs, publish(t, h, "signal", i, true))
+	}
+
+	// evs[1] has left the ring, so a client that last saw evs[0] missed it.
+	got, complete := h.Since(evs[0].ID)
+	if complete {
+		t.Error("since evs[0]: complete = true, want false")
+	}
+	if len(got) != 3 || got[0].ID != evs[2].ID {
+		t.Errorf("since evs[0] = %v, want the buffered %v", ids(got), ids(evs[2:]))
+	}
+
+	// IDs from before the hub was created (e.g. a previous process) are
+	// always older than the ring.
+	if _, complete := h.Since(1); complete {
+		t.Error("since 1: complete = true, want false")
+	}
+}
+
+func TestHub_TickerNotReplayable(t *testing.T) {
+	h := NewHub(2)
+	ch := h.Subscribe(16)
+	defer h.Unsubscribe(ch)
+
+	s1 := publish(t, h, "signal", "a", true)
+	publish(t, h, "ticker", "t1", false)
+	s2 := publish(t, h, "pattern", "b", true)
+	publish(t, h, "ticker", "t2", false)
+	publish(t, h, "ticker", "t3", false)
+
+	got, complete := h.Since(s1.ID - 1)
+	if !complete {
+		t.Error("tickers must not evict replayable events")
+	}
+	if len(got) != 2 || got[0].ID != s1.ID || got[1].ID != s2.ID {
+		t.Errorf("since = %v, want only %d and %d", ids(got), s1.ID, s2.ID)
+	}
+	for _, ev := range got {
+		if ev.Name == "ticker" {
+			t.Errorf("ticker event %d replayed", ev.ID)
+		}
+	}
+
+	// Live subscribers still receive every event.
+	if n := len(ch); n != 5 {
+		t.Errorf("subscriber received %d events, want 5", n)
+	}
+}
+
+func TestHub_LastID(t *testing.T) {
+	h := NewHub(2)
+	first := h.LastID() + 1
+	ev := publish(t, h, "ticker", 1, false)
+	if ev.ID != first || h.LastID() != ev.ID {
+		t.Errorf("LastID = %d, event %d, want first id %d", h.LastID(), ev.ID, first)
+	}
+}
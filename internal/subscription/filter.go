@@ -0,0 +1,319 @@
+// Package subscription filters the live streams per client, either from
+// query parameters or from a saved profile.
+package subscription
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"example.com/binance-pivot-monitor/internal/confluence"
+	"example.com/binance-pivot-monitor/internal/pattern"
+	"example.com/binance-pivot-monitor/internal/pivot"
+	signalpkg "example.com/binance-pivot-monitor/internal/signal"
+	"example.com/binance-pivot-monitor/internal/ticker"
+)
+
+// Event names accepted in Filter.Events.
+const (
+	EventSignal     = "signal"
+	EventPattern    = "pattern"
+	EventTicker     = "ticker"
+	EventCombined   = "combined"
+	EventConfluence = "confluence"
+	EventListing    = "listing"
+)
+
+// Filter selects which stream events a client receives. Empty fields match
+// everything.
+type Filter struct {
+	Events     []string `json:"events,omitempty"`
+	Symbols    []string `json:"symbols,omitempty"`
+	Periods    []string `json:"periods,omitempty"`
+	Levels     []string `json:"levels,omitempty"`
+	Directions []string `json:"directions,omitempty"` // up/down, bullish/bearish
+	Kinds      []string `json:"kinds,omitempty"`      // cross/reject, pivot and combined signals
+	Patterns   []string `json:"patterns,omitempty"`
+	// Correlations applies to combined signals: strong, moderate, weak.
+	Correlations []string `json:"correlations,omitempty"`
+	// MaxVolumeRank keeps only symbols ranked in the top N by 24h quote volume.
+	MaxVolumeRank int `json:"max_volume_rank,omitempty"`
+	MinConfidence int `json:"min_confidence,omitempty"`
+}
+
+// ParseQuery reads a filter from query parameters. List parameters are
+// comma-separated: ?symbols=BTCUSDT,ETHUSDT&levels=R4,S4&max_volume_rank=50
+func ParseQuery(q url.Values) (Filter, error) {
+	f := Filter{
+		Events:       splitList(q.Get("events")),
+		Symbols:      splitList(q.Get("symbols")),
+		Periods:      splitList(q.Get("periods")),
+		Levels:       splitList(q.Get("levels")),
+		Directions:   splitList(q.Get("directions")),
+		Kinds:        splitList(q.Get("kinds")),
+		Patterns:     splitList(q.Get("patterns")),
+		Correlations: splitList(q.Get("correlations")),
+	}
+	var err error
+	if f.MaxVolumeRank, err = parseInt(q, "max_volume_rank"); err != nil {
+		return Filter{}, err
+	}
+	if f.MinConfidence, err = parseInt(q, "min_confidence"); err != nil {
+		return Filter{}, err
+	}
+	return f, f.Validate()
+}
+
+func splitList(v string) []string {
+	if v == "" {
+		return nil
+	}
+	var out []string
+	for _, p := range strings.Split(v, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func parseInt(q url.Values, key string) (int, error) {
+	v := q.Get(key)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s", key)
+	}
+	return n, nil
+}
+
+// Validate checks event names and numeric bounds.
+func (f Filter) Validate() error {
+	for _, e := range f.Events {
+		switch strings.ToLower(e) {
+		case EventSignal, EventPattern, EventTicker, EventCombined, EventConfluence, EventListing:
+		default:
+			return fmt.Errorf("unknown event %q", e)
+		}
+	}
+	for _, k := range f.Kinds {
+		if _, err := signalpkg.ParseKind(k); err != nil {
+			return err
+		}
+	}
+	for _, c := range f.Correlations {
+		if _, err := signalpkg.ParseCorrelation(c); err != nil {
+			return err
+		}
+	}
+	if f.MaxVolumeRank < 0 {
+		return fmt.Errorf("max_volume_rank must be >= 0")
+	}
+	if f.MinConfidence < 0 || f.MinConfidence > 100 {
+		return fmt.Errorf("min_confidence must be between 0 and 100")
+	}
+	return nil
+}
+
+// IsZero reports whether the filter matches everything.
+func (f Filter) IsZero() bool {
+	return len(f.Events) == 0 && len(f.Symbols) == 0 && len(f.Periods) == 0 &&
+		len(f.Levels) == 0 && len(f.Directions) == 0 && len(f.Patterns) == 0 &&
+		len(f.Kinds) == 0 && len(f.Correlations) == 0 &&
+		f.MaxVolumeRank == 0 && f.MinConfidence == 0
+}
+
+// Override returns f with every non-empty field of o replacing f's.
+func (f Filter) Override(o Filter) Filter {
+	if len(o.Events) > 0 {
+		f.Events = o.Events
+	}
+	if len(o.Symbols) > 0 {
+		f.Symbols = o.Symbols
+	}
+	if len(o.Periods) > 0 {
+		f.Periods = o.Periods
+	}
+	if len(o.Levels) > 0 {
+		f.Levels = o.Levels
+	}
+	if len(o.Directions) > 0 {
+		f.Directions = o.Directions
+	}
+	if len(o.Patterns) > 0 {
+		f.Patterns = o.Patterns
+	}
+	if len(o.Kinds) > 0 {
+		f.Kinds = o.Kinds
+	}
+	if len(o.Correlations) > 0 {
+		f.Correlations = o.Correlations
+	}
+	if o.MaxVolumeRank > 0 {
+		f.MaxVolumeRank = o.MaxVolumeRank
+	}
+	if o.MinConfidence > 0 {
+		f.MinConfidence = o.MinConfidence
+	}
+	return f
+}
+
+// RankFunc returns a symbol's 24h quote volume rank (1 = highest).
+type RankFunc func(symbol string) (int, bool)
+
+// Matcher is a compiled Filter.
+type Matcher struct {
+	events     map[string]bool
+	symbols    map[string]bool
+	periods    map[string]bool
+	levels     map[string]bool
+	directions map[string]bool
+	kinds      map[string]bool
+	patterns   map[string]bool
+	corrs      map[string]bool
+	maxRank    int
+	minConf    int
+	rank       RankFunc
+}
+
+// Compile builds a matcher. rank may be nil, in which case MaxVolumeRank is
+// ignored.
+func (f Filter) Compile(rank RankFunc) *Matcher {
+	m := &Matcher{
+		events:     set(f.Events, strings.ToLower),
+		symbols:    set(f.Symbols, strings.ToUpper),
+		periods:    set(f.Periods, func(s string) string { return s }),
+		levels:     set(f.Levels, strings.ToUpper),
+		directions: set(f.Directions, normalizeDirection),
+		kinds:      set(f.Kinds, strings.ToLower),
+		patterns:   set(f.Patterns, strings.ToLower),
+		corrs:      set(f.Correlations, strings.ToLower),
+		minConf:    f.MinConfidence,
+		rank:       rank,
+	}
+	if rank != nil {
+		m.maxRank = f.MaxVolumeRank
+	}
+	return m
+}
+
+func set(values []string, norm func(string) string) map[string]bool {
+	if len(values) == 0 {
+		return nil
+	}
+	m := make(map[string]bool, len(values))
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			m[norm(v)] = true
+		}
+	}
+	return m
+}
+
+func normalizeDirection(s string) string {
+	switch strings.ToLower(s) {
+	case "up", "bullish":
+		return "up"
+	case "down", "bearish":
+		return "down"
+	default:
+		return strings.ToLower(s)
+	}
+}
+
+// match reports whether a set filter accepts v. A nil set accepts all.
+func match(m map[string]bool, v string) bool {
+	return m == nil || m[v]
+}
+
+// Wants reports whether the client receives the event type at all.
+func (m *Matcher) Wants(event string) bool {
+	return match(m.events, event)
+}
+
+// symbol checks the symbol list and volume rank. Symbols without a rank yet
+// are dropped when a rank limit is set.
+func (m *Matcher) symbol(sym string) bool {
+	sym = strings.ToUpper(sym)
+	if !match(m.symbols, sym) {
+		return false
+	}
+	if m.maxRank > 0 {
+		r, ok := m.rank(sym)
+		return ok && r <= m.maxRank
+	}
+	return true
+}
+
+// Signal reports whether a pivot signal passes the filter.
+func (m *Matcher) Signal(sig signalpkg.Signal) bool {
+	return m.Wants(EventSignal) &&
+		match(m.periods, sig.Period) &&
+		match(m.levels, strings.ToUpper(sig.Level)) &&
+		match(m.directions, normalizeDirection(sig.Direction)) &&
+		match(m.kinds, sig.KindOrCross()) &&
+		m.symbol(sig.Symbol)
+}
+
+// Pattern reports whether a pattern signal passes the filter. Periods and
+// levels do not apply to patterns.
+func (m *Matcher) Pattern(sig pattern.Signal) bool {
+	return m.Wants(EventPattern) &&
+		match(m.patterns, strings.ToLower(string(sig.Pattern))) &&
+		match(m.directions, normalizeDirection(string(sig.Direction))) &&
+		sig.Confidence >= m.minConf &&
+		m.symbol(sig.Symbol)
+}
+
+// Combined reports whether a combined signal passes the filter. Symbol,
+// period, level, direction and kind apply to its pivot signal.
+func (m *Matcher) Combined(cs signalpkg.CombinedSignal) bool {
+	if !m.Wants(EventCombined) || !match(m.corrs, string(cs.Correlation)) {
+		return false
+	}
+	if cs.PivotSignal != nil {
+		sig := *cs.PivotSignal
+		if !match(m.periods, sig.Period) ||
+			!match(m.levels, strings.ToUpper(sig.Level)) ||
+			!match(m.directions, normalizeDirection(sig.Direction)) ||
+			!match(m.kinds, sig.KindOrCross()) {
+			return false
+		}
+	}
+	return m.symbol(cs.Symbol)
+}
+
+// Confluence reports whether a confluence alert passes the filter.
+// Directions apply to the alert's bias.
+func (m *Matcher) Confluence(a confluence.Alert) bool {
+	return m.Wants(EventConfluence) &&
+		match(m.directions, normalizeDirection(a.Bias)) &&
+		m.symbol(a.Symbol)
+}
+
+// Listing reports whether a new listing event passes the filter. Only the
+// symbol list and volume rank apply.
+func (m *Matcher) Listing(l pivot.Listing) bool {
+	return m.Wants(EventListing) && m.symbol(l.Symbol)
+}
+
+// Tickers returns the part of a batch that passes the symbol and rank
+// filters, and false if nothing is left.
+func (m *Matcher) Tickers(batch ticker.TickerBatch) (ticker.TickerBatch, bool) {
+	if !m.Wants(EventTicker) {
+		return batch, false
+	}
+	if m.symbols == nil && m.maxRank == 0 {
+		return batch, len(batch.Tickers) > 0
+	}
+	out := ticker.TickerBatch{Tickers: make(map[string]*ticker.Ticker), Timestamp: batch.Timestamp}
+	for sym, t := range batch.Tickers {
+		if m.symbol(sym) {
+			out.Tickers[sym] = t
+		}
+	}
+	return out, len(out.Tickers) > 0
+}
@@ -0,0 +1,154 @@
+package subscription
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MaxProfiles bounds the number of saved profiles.
+const MaxProfiles = 1000
+
+var profileName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// ErrTooManyProfiles is returned by Put when the store is full.
+var ErrTooManyProfiles = errors.New("too many profiles")
+
+// Profile is a named, saved filter.
+type Profile struct {
+	Name      string    `json:"name"`
+	Filter    Filter    `json:"filter"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ProfileStore keeps profiles in memory and, when a path is set, in a JSON
+// file.
+type ProfileStore struct {
+	mu       sync.RWMutex
+	profiles map[string]Profile
+	filePath string
+}
+
+// NewProfileStore creates a store and loads path if it exists. An empty path
+// keeps profiles in memory only.
+func NewProfileStore(path string) (*ProfileStore, error) {
+	s := &ProfileStore{profiles: make(map[string]Profile), filePath: path}
+	if path == "" {
+		return s, nil
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []Profile
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for _, p := range list {
+		s.profiles[p.Name] = p
+	}
+	return s, nil
+}
+
+// ValidName reports whether name can be used as a profile name.
+func ValidName(name string) bool {
+	return profileName.MatchString(name)
+}
+
+// Get returns a profile by name.
+func (s *ProfileStore) Get(name string) (Profile, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.profiles[name]
+	return p, ok
+}
+
+// List returns all profiles sorted by name.
+func (s *ProfileStore) List() []Profile {
+	s.mu.RLock()
+	out := make([]Profile, 0, len(s.profiles))
+	for _, p := range s.profiles {
+		out = append(out, p)
+	}
+	s.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Put creates or replaces a profile.
+func (s *ProfileStore) Put(name string, f Filter) (Profile, error) {
+	if !ValidName(name) {
+		return Profile{}, fmt.Errorf("invalid profile name %q", name)
+	}
+	if err := f.Validate(); err != nil {
+		return Profile{}, err
+	}
+	p := Profile{Name: name, Filter: f, UpdatedAt: time.Now().UTC()}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.profiles[name]; !exists && len(s.profiles) >= MaxProfiles {
+		return Profile{}, ErrTooManyProfiles
+	}
+	prev, existed := s.profiles[name]
+	s.profiles[name] = p
+	if err := s.saveLocked(); err != nil {
+		// 保存失败时回滚，保持内存与文件一致
+		if existed {
+			s.profiles[name] = prev
+		} else {
+			delete(s.profiles, name)
+		}
+		return Profile{}, err
+	}
+	return p, nil
+}
+
+// Delete removes a profile and reports whether it existed.
+func (s *ProfileStore) Delete(name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev, ok := s.profiles[name]
+	if !ok {
+		return false, nil
+	}
+	delete(s.profiles, name)
+	if err := s.saveLocked(); err != nil {
+		s.profiles[name] = prev
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *ProfileStore) saveLocked() error {
+	if s.filePath == "" {
+		return nil
+	}
+	list := make([]Profile, 0, len(s.profiles))
+	for _, p := range s.profiles {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	if err := os.MkdirAll(filepath.Dir(s.filePath), 0o755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.filePath + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.filePath)
+}
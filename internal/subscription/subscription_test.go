@@ -0,0 +1,213 @@
+package subscription
+
+import (
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"example.com/binance-pivot-monitor/internal/confluence"
+	"example.com/binance-pivot-monitor/internal/pattern"
+	"example.com/binance-pivot-monitor/internal/pivot"
+	signalpkg "example.com/binance-pivot-monitor/internal/signal"
+	"example.com/binance-pivot-monitor/internal/ticker"
+)
+
+func ranks(m map[string]int) RankFunc {
+	return func(sym string) (int, bool) {
+		r, ok := m[sym]
+		return r, ok
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	q, _ := url.ParseQuery("symbols=btcusdt,+ETHUSDT&levels=R4,S4&directions=up&max_volume_rank=50&min_confidence=70&events=signal,ticker")
+	f, err := ParseQuery(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Symbols) != 2 || f.Symbols[1] != "ETHUSDT" || f.MaxVolumeRank != 50 || f.MinConfidence != 70 || len(f.Events) != 2 {
+		t.Errorf("filter = %+v", f)
+	}
+
+	for _, bad := range []string{"max_volume_rank=x", "min_confidence=101", "events=klines"} {
+		q, _ := url.ParseQuery(bad)
+		if _, err := ParseQuery(q); err == nil {
+			t.Errorf("%s: expected error", bad)
+		}
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	f := Filter{
+		Symbols:       []string{"btcusdt", "ETHUSDT", "SOLUSDT"},
+		Periods:       []string{"1d"},
+		Levels:        []string{"r4"},
+		Directions:    []string{"bullish"},
+		MaxVolumeRank: 2,
+		MinConfidence: 70,
+	}
+	m := f.Compile(ranks(map[string]int{"BTCUSDT": 1, "ETHUSDT": 2, "SOLUSDT": 3}))
+
+	cases := []struct {
+		sig  signalpkg.Signal
+		want bool
+	}{
+		{signalpkg.Signal{Symbol: "BTCUSDT", Period: "1d", Level: "R4", Direction: "up"}, true},
+		{signalpkg.Signal{Symbol: "BTCUSDT", Period: "1w", Level: "R4", Direction: "up"}, false},
+		{signalpkg.Signal{Symbol: "BTCUSDT", Period: "1d", Level: "S4", Direction: "up"}, false},
+		{signalpkg.Signal{Symbol: "BTCUSDT", Period: "1d", Level: "R4", Direction: "down"}, false},
+		{signalpkg.Signal{Symbol: "SOLUSDT", Period: "1d", Level: "R4", Direction: "up"}, false}, // rank 3
+		{signalpkg.Signal{Symbol: "DOGEUSDT", Period: "1d", Level: "R4", Direction: "up"}, false},
+	}
+	for i, c := range cases {
+		if got := m.Signal(c.sig); got != c.want {
+			t.Errorf("case %d: Signal = %v, want %v", i, got, c.want)
+		}
+	}
+
+	if !m.Pattern(pattern.Signal{Symbol: "ETHUSDT", Direction: pattern.DirectionBullish, Confidence: 80}) {
+		t.Error("bullish ETHUSDT pattern should match")
+	}
+	if m.Pattern(pattern.Signal{Symbol: "ETHUSDT", Direction: pattern.DirectionBullish, Confidence: 60}) {
+		t.Error("pattern below min confidence should not match")
+	}
+
+	batch := ticker.TickerBatch{Tickers: map[string]*ticker.Ticker{
+		"BTCUSDT": {Symbol: "BTCUSDT"}, "SOLUSDT": {Symbol: "SOLUSDT"}, "XRPUSDT": {Symbol: "XRPUSDT"},
+	}}
+	out, ok := m.Tickers(batch)
+	if !ok || len(out.Tickers) != 1 || out.Tickers["BTCUSDT"] == nil {
+		t.Errorf("tickers = %v", out.Tickers)
+	}
+
+	onlySignals := Filter{Events: []string{"signal"}}.Compile(nil)
+	if _, ok := onlySignals.Tickers(batch); ok {
+		t.Error("signal-only filter passed tickers")
+	}
+	if (Filter{}).Compile(nil).Signal(cases[5].sig) != true {
+		t.Error("zero filter should match everything")
+	}
+}
+
+func TestMatcher_Combined(t *testing.T) {
+	m := Filter{Correlations: []string{"strong"}, Levels: []string{"R4"}}.Compile(nil)
+	cs := signalpkg.CombinedSignal{
+		Symbol:      "BTCUSDT",
+		PivotSignal: &signalpkg.Signal{Symbol: "BTCUSDT", Level: "R4", Direction: "up"},
+		Correlation: signalpkg.CorrelationStrong,
+	}
+	if !m.Combined(cs) {
+		t.Error("strong R4 combined signal should match")
+	}
+	cs.Correlation = signalpkg.CorrelationWeak
+	if m.Combined(cs) {
+		t.Error("weak combined signal should not match")
+	}
+	if (Filter{Events: []string{"signal"}}).Compile(nil).Combined(cs) {
+		t.Error("signal-only filter passed a combined signal")
+	}
+
+	q, _ := url.ParseQuery("correlations=huge")
+	if _, err := ParseQuery(q); err == nil {
+		t.Error("expected error for unknown correlation")
+	}
+}
+
+func TestMatcher_Kinds(t *testing.T) {
+	m := Filter{Kinds: []string{"Reject"}}.Compile(nil)
+	reject := signalpkg.Signal{Symbol: "BTCUSDT", Level: "R4", Direction: "down", Kind: signalpkg.KindReject}
+	if !m.Signal(reject) {
+		t.Error("reject signal should match kinds=reject")
+	}
+	// 没有 kind 的旧信号视为穿越
+	if m.Signal(signalpkg.Signal{Symbol: "BTCUSDT", Level: "R4", Direction: "up"}) {
+		t.Error("crossing without kind should not match kinds=reject")
+	}
+	if !m.Combined(signalpkg.CombinedSignal{Symbol: "BTCUSDT", PivotSignal: &reject, Correlation: signalpkg.CorrelationStrong}) {
+		t.Error("combined reject should match kinds=reject")
+	}
+	if (Filter{Kinds: []string{"cross"}}).Compile(nil).Signal(reject) {
+		t.Error("reject signal should not match kinds=cross")
+	}
+
+	q, _ := url.ParseQuery("kinds=bounce")
+	if _, err := ParseQuery(q); err == nil {
+		t.Error("expected error for unknown kind")
+	}
+}
+
+func TestMatcher_Confluence(t *testing.T) {
+	m := Filter{Symbols: []string{"BTCUSDT"}, Directions: []string{"down"}}.Compile(nil)
+	a := confluence.Alert{Score: confluence.Score{Symbol: "BTCUSDT", Score: -60, Bias: confluence.BiasBearish}}
+	if !m.Confluence(a) {
+		t.Error("bearish BTCUSDT alert should match direction down")
+	}
+	a.Bias = confluence.BiasBullish
+	if m.Confluence(a) {
+		t.Error("bullish alert should not match direction down")
+	}
+	if (Filter{Events: []string{"combined"}}).Compile(nil).Confluence(a) {
+		t.Error("combined-only filter passed a confluence alert")
+	}
+}
+
+func TestMatcher_Listing(t *testing.T) {
+	l := pivot.Listing{Symbol: "NEWUSDT"}
+	if !(Filter{}).Compile(nil).Listing(l) {
+		t.Error("empty filter should pass a listing")
+	}
+	if (Filter{Symbols: []string{"BTCUSDT"}}).Compile(nil).Listing(l) {
+		t.Error("symbol filter passed another symbol's listing")
+	}
+	if (Filter{Events: []string{"signal"}}).Compile(nil).Listing(l) {
+		t.Error("signal-only filter passed a listing")
+	}
+}
+
+func TestOverride(t *testing.T) {
+	base := Filter{Symbols: []string{"BTCUSDT"}, Levels: []string{"R4"}, MinConfidence: 60}
+	got := base.Override(Filter{Symbols: []string{"ETHUSDT"}, MinConfidence: 80})
+	if got.Symbols[0] != "ETHUSDT" || got.Levels[0] != "R4" || got.MinConfidence != 80 {
+		t.Errorf("override = %+v", got)
+	}
+}
+
+func TestProfileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "subscriptions", "profiles.json")
+	s, err := NewProfileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Put("bad name", Filter{}); err == nil {
+		t.Error("expected error for invalid name")
+	}
+	if _, err := s.Put("phone", Filter{Events: []string{"klines"}}); err == nil {
+		t.Error("expected error for invalid filter")
+	}
+	if _, err := s.Put("phone", Filter{Symbols: []string{"BTCUSDT"}, MaxVolumeRank: 20}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Put("desk", Filter{}); err != nil {
+		t.Fatal(err)
+	}
+
+	s2, err := NewProfileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := s2.List()
+	if len(list) != 2 || list[0].Name != "desk" || list[1].Filter.MaxVolumeRank != 20 {
+		t.Fatalf("reloaded = %+v", list)
+	}
+
+	if ok, err := s2.Delete("phone"); !ok || err != nil {
+		t.Fatalf("delete = %v, %v", ok, err)
+	}
+	if ok, _ := s2.Delete("phone"); ok {
+		t.Error("second delete reported existing profile")
+	}
+	s3, _ := NewProfileStore(path)
+	if _, ok := s3.Get("phone"); ok {
+		t.Error("deleted profile came back after reload")
+	}
+}
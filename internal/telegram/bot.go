@@ -0,0 +1,484 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"example.com/binance-pivot-monitor/internal/httpapi"
+	"example.com/binance-pivot-monitor/internal/pattern"
+	"example.com/binance-pivot-monitor/internal/pivot"
+	signalpkg "example.com/binance-pivot-monitor/internal/signal"
+	"example.com/binance-pivot-monitor/internal/sse"
+	"example.com/binance-pivot-monitor/internal/ticker"
+)
+
+const (
+	sendQueueSize   = 256
+	sendInterval    = 50 * time.Millisecond
+	defaultPoll     = 50 * time.Second
+	historyDefault  = 10
+	historyMaxLimit = 50
+)
+
+// Queries is the read side of the HTTP API; *httpapi.Server implements it,
+// so commands return exactly what the matching endpoints return.
+type Queries interface {
+	QueryPivots(symbol string, period pivot.Period, method pivot.Method) (httpapi.PivotResponse, bool)
+	QueryHistory(q httpapi.HistoryQuery) []httpapi.EnrichedSignal
+}
+
+// Bot pushes signals to the configured chats and answers commands from them.
+type Bot struct {
+	Client  *Client
+	Chats   []int64
+	Queries Queries
+	Tickers *ticker.Store
+	// Patterns enables pushing pattern signals.
+	Patterns    bool
+	PollTimeout time.Duration
+
+	mu    sync.Mutex
+	mutes map[string]time.Time // symbol -> muted until
+	now   func() time.Time
+	sendQ chan outgoing
+}
+
+type outgoing struct {
+	chatID int64
+	text   string
+}
+
+// New creates a bot.
+func New(client *Client, chats []int64, queries Queries, tickers *ticker.Store) *Bot {
+	return &Bot{
+		Client:   client,
+		Chats:    chats,
+		Queries:  queries,
+		Tickers:  tickers,
+		Patterns: true,
+		mutes:    make(map[string]time.Time),
+		now:      time.Now,
+		sendQ:    make(chan outgoing, sendQueueSize),
+	}
+}
+
+// Run pushes signals from the brokers (either may be nil) and polls for
+// commands until ctx is done.
+func (b *Bot) Run(ctx context.Context, signals *sse.Broker[signalpkg.Signal], patterns *sse.Broker[pattern.Signal]) {
+	var sigCh chan signalpkg.Signal
+	var patCh chan pattern.Signal
+	if signals != nil {
+		sigCh = signals.SubscribePinned("telegram", sendQueueSize)
+		defer signals.Unsubscribe(sigCh)
+	}
+	if patterns != nil && b.Patterns {
+		patCh = patterns.SubscribePinned("telegram", sendQueueSize)
+		defer patterns.Unsubscribe(patCh)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		b.sender(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		b.poll(ctx)
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case sig, ok := <-sigCh:
+			if !ok {
+				log.Printf("telegram: signal subscription closed")
+				sigCh = nil
+				continue
+			}
+			if !b.muted(sig.Symbol) {
+				b.broadcast(b.formatSignal(sig))
+			}
+		case sig, ok := <-patCh:
+			if !ok {
+				log.Printf("telegram: pattern subscription closed")
+				patCh = nil
+				continue
+			}
+			if !b.muted(sig.Symbol) {
+				b.broadcast(b.formatPattern(sig))
+			}
+		}
+	}
+}
+
+func (b *Bot) broadcast(text string) {
+	for _, id := range b.Chats {
+		b.send(id, text)
+	}
+}
+
+func (b *Bot) send(chatID int64, text string) {
+	select {
+	case b.sendQ <- outgoing{chatID: chatID, text: text}:
+	default:
+		log.Printf("telegram: send queue full, dropping message to %d", chatID)
+	}
+}
+
+// sender delivers queued messages one at a time, honoring retry_after.
+func (b *Bot) sender(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case m := <-b.sendQ:
+			err := b.Client.SendMessage(ctx, m.chatID, m.text)
+			var apiErr *APIError
+			if errors.As(err, &apiErr) && apiErr.Code == http.StatusTooManyRequests {
+				if !sleepContext(ctx, apiErr.RetryAfter) {
+					return
+				}
+				err = b.Client.SendMessage(ctx, m.chatID, m.text)
+			}
+			if err != nil && ctx.Err() == nil {
+				log.Printf("telegram: send to %d failed: %v", m.chatID, err)
+			}
+			if !sleepContext(ctx, sendInterval) {
+				return
+			}
+		}
+	}
+}
+
+func (b *Bot) poll(ctx context.Context) {
+	timeout := b.PollTimeout
+	if timeout <= 0 {
+		timeout = defaultPoll
+	}
+	var offset int64
+	backoff := time.Second
+	for ctx.Err() == nil {
+		updates, err := b.Client.GetUpdates(ctx, offset, timeout)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("telegram: getUpdates failed: %v", err)
+			if !sleepContext(ctx, backoff) {
+				return
+			}
+			if backoff < time.Minute {
+				backoff *= 2
+			}
+			continue
+		}
+		backoff = time.Second
+		for _, u := range updates {
+			if u.UpdateID >= offset {
+				offset = u.UpdateID + 1
+			}
+			if u.Message == nil || !b.allowed(u.Message.Chat.ID) {
+				continue
+			}
+			if reply := b.HandleCommand(u.Message.Text); reply != "" {
+				b.send(u.Message.Chat.ID, reply)
+			}
+		}
+	}
+}
+
+func (b *Bot) allowed(chatID int64) bool {
+	for _, id := range b.Chats {
+		if id == chatID {
+			return true
+		}
+	}
+	return false
+}
+
+// HandleCommand returns the reply to a chat message, or "" if it is not a
+// command.
+func (b *Bot) HandleCommand(text string) string {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return ""
+	}
+	// 群组中的命令形如 /pivots@MyBot
+	cmd := strings.ToLower(strings.SplitN(fields[0], "@", 2)[0])
+	args := fields[1:]
+
+	switch cmd {
+	case "/start", "/help":
+		return helpText
+	case "/pivots":
+		return b.cmdPivots(args)
+	case "/history":
+		return b.cmdHistory(args)
+	case "/mute":
+		return b.cmdMute(args)
+	case "/unmute":
+		return b.cmdUnmute(args)
+	case "/mutes":
+		return b.cmdMutes()
+	default:
+		return "Unknown command. " + helpText
+	}
+}
+
+const helpText = `Commands:
+/pivots SYMBOL [period] - pivot levels and 24h stats
+/history SYMBOL [limit] - recent signals
+/mute SYMBOL DURATION - mute alerts, e.g. /mute ETHUSDT 2h
+/unmute SYMBOL
+/mutes - list muted symbols`
+
+func (b *Bot) cmdPivots(args []string) string {
+	if len(args) == 0 {
+		return "Usage: /pivots SYMBOL [period]"
+	}
+	symbol := strings.ToUpper(args[0])
+	var period pivot.Period
+	if len(args) > 1 {
+		p, err := pivot.ParsePeriod(args[1])
+		if err != nil {
+			return "Unknown period " + args[1]
+		}
+		period = p
+	}
+
+	resp, ok := b.Queries.QueryPivots(symbol, period, "")
+	if !ok {
+		return "No pivot data for " + symbol
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s pivots (%s)\n", symbol, resp.Method)
+	periods := make([]pivot.Period, 0, len(resp.Periods))
+	for p := range resp.Periods {
+		periods = append(periods, p)
+	}
+	sort.Slice(periods, func(i, j int) bool { return periods[i].Interval() < periods[j].Interval() })
+	for _, p := range periods {
+		fmt.Fprintf(&sb, "%s: %s\n", p, formatLevels(resp.Periods[p]))
+	}
+	if line := b.tickerLine(symbol); line != "" {
+		sb.WriteString(line)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func (b *Bot) cmdHistory(args []string) string {
+	if len(args) == 0 {
+		return "Usage: /history SYMBOL [limit]"
+	}
+	symbol := strings.ToUpper(args[0])
+	limit := historyDefault
+	if len(args) > 1 {
+		if v, err := strconv.Atoi(args[1]); err == nil && v > 0 {
+			limit = v
+		}
+	}
+	if limit > historyMaxLimit {
+		limit = historyMaxLimit
+	}
+
+	res := b.Queries.QueryHistory(httpapi.HistoryQuery{Symbol: symbol, Limit: limit})
+	if len(res) == 0 {
+		return "No signals for " + symbol
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s last %d signals\n", symbol, len(res))
+	for _, s := range res {
+		fmt.Fprintf(&sb, "%s %s %s %s %s %s",
+			s.TriggeredAt.UTC().Format("01-02 15:04"), s.Symbol, s.Period, s.Level, s.Direction, formatPrice(s.Price))
+		if s.Kind == signalpkg.KindReject {
+			sb.WriteString(" reject")
+		}
+		if s.Outcome != nil {
+			if r, ok := s.Outcome.Returns["1h"]; ok {
+				fmt.Fprintf(&sb, " 1h %+.2f%%", r)
+			}
+		}
+		sb.WriteByte('\n')
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func (b *Bot) cmdMute(args []string) string {
+	if len(args) < 2 {
+		return "Usage: /mute SYMBOL DURATION"
+	}
+	symbol := strings.ToUpper(args[0])
+	d, err := parseDuration(args[1])
+	if err != nil || d <= 0 {
+		return "Invalid duration " + args[1]
+	}
+	until := b.now().Add(d)
+
+	b.mu.Lock()
+	b.mutes[symbol] = until
+	b.mu.Unlock()
+	return fmt.Sprintf("Muted %s until %s UTC", symbol, until.UTC().Format("01-02 15:04"))
+}
+
+func (b *Bot) cmdUnmute(args []string) string {
+	if len(args) == 0 {
+		return "Usage: /unmute SYMBOL"
+	}
+	symbol := strings.ToUpper(args[0])
+	b.mu.Lock()
+	_, ok := b.mutes[symbol]
+	delete(b.mutes, symbol)
+	b.mu.Unlock()
+	if !ok {
+		return symbol + " is not muted"
+	}
+	return "Unmuted " + symbol
+}
+
+func (b *Bot) cmdMutes() string {
+	now := b.now()
+	b.mu.Lock()
+	var lines []string
+	for sym, until := range b.mutes {
+		if !now.Before(until) {
+			delete(b.mutes, sym)
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s until %s UTC", sym, until.UTC().Format("01-02 15:04")))
+	}
+	b.mu.Unlock()
+	if len(lines) == 0 {
+		return "No muted symbols"
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+func (b *Bot) muted(symbol string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	until, ok := b.mutes[strings.ToUpper(symbol)]
+	if !ok {
+		return false
+	}
+	if !b.now().Before(until) {
+		delete(b.mutes, strings.ToUpper(symbol))
+		return false
+	}
+	return true
+}
+
+// parseDuration accepts time.ParseDuration input plus a "d" suffix for days.
+func parseDuration(s string) (time.Duration, error) {
+	if n, ok := strings.CutSuffix(strings.ToLower(s), "d"); ok {
+		days, err := strconv.Atoi(n)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+func (b *Bot) formatSignal(sig signalpkg.Signal) string {
+	arrow := "↑"
+	if sig.Direction == "down" {
+		arrow = "↓"
+	}
+	action := "crossed"
+	if sig.Kind == signalpkg.KindReject {
+		action = "rejected at"
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s %s %s (%s) %s\n", arrow, sig.Symbol, action, sig.Level, sig.Period, sig.Direction)
+	fmt.Fprintf(&sb, "Price: %s", formatPrice(sig.Price))
+	if sig.Confirmation != "" {
+		fmt.Fprintf(&sb, " [%s]", sig.Confirmation)
+	}
+	sb.WriteByte('\n')
+
+	period, err := pivot.ParsePeriod(sig.Period)
+	if err == nil {
+		if resp, ok := b.Queries.QueryPivots(sig.Symbol, period, pivot.Method(sig.Method)); ok {
+			if lv, ok := resp.Periods[period]; ok {
+				fmt.Fprintf(&sb, "%s: %s\n", period, formatLevels(lv))
+			}
+		}
+	}
+	sb.WriteString(b.tickerLine(sig.Symbol))
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func (b *Bot) formatPattern(sig pattern.Signal) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s %s (%s) confidence %d\n", sig.Symbol, sig.Pattern, sig.PatternCN, sig.Direction, sig.Confidence)
+	fmt.Fprintf(&sb, "Up %d%% / Down %d%%, rank %s\n", sig.UpPercent, sig.DownPercent, sig.EfficiencyRank)
+	sb.WriteString(b.tickerLine(sig.Symbol))
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func (b *Bot) tickerLine(symbol string) string {
+	if b.Tickers == nil {
+		return ""
+	}
+	t, ok := b.Tickers.Get(symbol)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("24h: %s %+.2f%% vol %s trades %d\n",
+		formatPrice(t.LastPrice), t.PricePercent, formatVolume(t.QuoteVolume), t.TradeCount)
+}
+
+// formatLevels lists the non-zero levels from R5 down to S5.
+func formatLevels(lv pivot.Levels) string {
+	order := []string{"R5", "R4", "R3", "R2", "R1", "PP", "S1", "S2", "S3", "S4", "S5"}
+	parts := make([]string, 0, len(order))
+	for _, name := range order {
+		if v, ok := lv.Price(name); ok && v > 0 {
+			parts = append(parts, name+" "+formatPrice(v))
+		}
+	}
+	return strings.Join(parts, " | ")
+}
+
+func formatPrice(v float64) string {
+	return strconv.FormatFloat(v, 'g', 8, 64)
+}
+
+func formatVolume(v float64) string {
+	switch {
+	case v >= 1e9:
+		return fmt.Sprintf("%.2fB", v/1e9)
+	case v >= 1e6:
+		return fmt.Sprintf("%.2fM", v/1e6)
+	case v >= 1e3:
+		return fmt.Sprintf("%.2fK", v/1e3)
+	default:
+		return fmt.Sprintf("%.0f", v)
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
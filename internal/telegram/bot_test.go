@@ -0,0 +1,209 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/binance-pivot-monitor/internal/httpapi"
+	"example.com/binance-pivot-monitor/internal/pivot"
+	signalpkg "example.com/binance-pivot-monitor/internal/signal"
+	"example.com/binance-pivot-monitor/internal/sse"
+	"example.com/binance-pivot-monitor/internal/ticker"
+)
+
+type fakeQueries struct {
+	levels  map[string]pivot.Levels
+	history []httpapi.EnrichedSignal
+	lastQ   httpapi.HistoryQuery
+}
+
+func (f *fakeQueries) QueryPivots(symbol string, period pivot.Period, method pivot.Method) (httpapi.PivotResponse, bool) {
+	lv, ok := f.levels[symbol]
+	if !ok {
+		return httpapi.PivotResponse{}, false
+	}
+	return httpapi.PivotResponse{
+		Symbol:  symbol,
+		Method:  pivot.MethodCamarilla,
+		Periods: map[pivot.Period]pivot.Levels{pivot.PeriodDaily: lv},
+	}, true
+}
+
+func (f *fakeQueries) QueryHistory(q httpapi.HistoryQuery) []httpapi.EnrichedSignal {
+	f.lastQ = q
+	return f.history
+}
+
+// stubAPI answers sendMessage and getUpdates like the Bot API.
+type stubAPI struct {
+	mu       sync.Mutex
+	sent     []map[string]any
+	updates  []Update
+	throttle int // number of sendMessage calls to answer with 429
+	got      chan struct{}
+}
+
+func newStubAPI(t *testing.T) (*stubAPI, *httptest.Server) {
+	st := &stubAPI{got: make(chan struct{}, 64)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/bottok/") {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		var params map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&params)
+
+		st.mu.Lock()
+		defer st.mu.Unlock()
+		switch strings.TrimPrefix(r.URL.Path, "/bottok/") {
+		case "sendMessage":
+			if st.throttle > 0 {
+				st.throttle--
+				w.WriteHeader(http.StatusTooManyRequests)
+				_, _ = w.Write([]byte(`{"ok":false,"error_code":429,"description":"Too Many Requests","parameters":{"retry_after":0}}`))
+				return
+			}
+			st.sent = append(st.sent, params)
+			_, _ = w.Write([]byte(`{"ok":true,"result":{}}`))
+			st.got <- struct{}{}
+		case "getUpdates":
+			ups := st.updates
+			st.updates = nil
+			_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": ups})
+		}
+	}))
+	return st, srv
+}
+
+func (st *stubAPI) wait(t *testing.T, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-st.got:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for message %d", i+1)
+		}
+	}
+}
+
+func (st *stubAPI) messages() []map[string]any {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return append([]map[string]any(nil), st.sent...)
+}
+
+func TestHandleCommand(t *testing.T) {
+	q := &fakeQueries{
+		levels: map[string]pivot.Levels{"BTCUSDT": {PP: 100, R1: 101, S1: 99}},
+		history: []httpapi.EnrichedSignal{{
+			Signal:  signalpkg.Signal{Symbol: "SOLUSDT", Period: "1d", Level: "R4", Direction: "up", Price: 150, TriggeredAt: time.Date(2025, 1, 6, 8, 0, 0, 0, time.UTC)},
+			Outcome: &signalpkg.Outcome{Returns: map[string]float64{"1h": 1.25}},
+		}},
+	}
+	tickers := ticker.NewStore()
+	tickers.Update("BTCUSDT", 100.5, 2.5, 1000, 3.2e9)
+
+	b := New(NewClient("http://unused", "tok"), nil, q, tickers)
+
+	out := b.HandleCommand("/pivots@MonitorBot btcusdt")
+	for _, want := range []string{"BTCUSDT pivots", "R1 101 | PP 100 | S1 99", "+2.50%", "3.20B"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("/pivots reply missing %q:\n%s", want, out)
+		}
+	}
+	if out := b.HandleCommand("/pivots DOGEUSDT"); !strings.Contains(out, "No pivot data") {
+		t.Errorf("/pivots unknown = %q", out)
+	}
+
+	out = b.HandleCommand("/history solusdt 500")
+	if q.lastQ.Symbol != "SOLUSDT" || q.lastQ.Limit != historyMaxLimit {
+		t.Errorf("history query = %+v", q.lastQ)
+	}
+	if !strings.Contains(out, "01-06 08:00 SOLUSDT 1d R4 up 150 1h +1.25%") {
+		t.Errorf("/history reply:\n%s", out)
+	}
+
+	if out := b.HandleCommand("hello"); out != "" {
+		t.Errorf("plain text reply = %q, want none", out)
+	}
+	if out := b.HandleCommand("/mute ETHUSDT soon"); !strings.Contains(out, "Invalid duration") {
+		t.Errorf("bad duration reply = %q", out)
+	}
+}
+
+func TestMute(t *testing.T) {
+	now := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	b := New(NewClient("http://unused", "tok"), nil, &fakeQueries{}, nil)
+	b.now = func() time.Time { return now }
+
+	b.HandleCommand("/mute ethusdt 2h")
+	b.HandleCommand("/mute SOLUSDT 1d")
+	if !b.muted("ETHUSDT") || !b.muted("SOLUSDT") || b.muted("BTCUSDT") {
+		t.Fatal("mute state wrong after /mute")
+	}
+	if out := b.HandleCommand("/mutes"); !strings.Contains(out, "ETHUSDT until 01-06 02:00") || !strings.Contains(out, "SOLUSDT until 01-07 00:00") {
+		t.Errorf("/mutes = %q", out)
+	}
+
+	now = now.Add(3 * time.Hour)
+	if b.muted("ETHUSDT") {
+		t.Error("mute should expire after 2h")
+	}
+	b.HandleCommand("/unmute SOLUSDT")
+	if b.muted("SOLUSDT") {
+		t.Error("SOLUSDT still muted after /unmute")
+	}
+}
+
+func TestBot_PushAndCommands(t *testing.T) {
+	st, srv := newStubAPI(t)
+	defer srv.Close()
+	st.throttle = 1
+	st.updates = []Update{
+		{UpdateID: 1, Message: &Message{Chat: Chat{ID: 42}, Text: "/mute ETHUSDT 2h"}},
+		{UpdateID: 2, Message: &Message{Chat: Chat{ID: 7}, Text: "/help"}}, // 未授权的会话
+	}
+
+	q := &fakeQueries{levels: map[string]pivot.Levels{"BTCUSDT": {PP: 100, R4: 110}}}
+	b := New(NewClient(srv.URL, "tok"), []int64{42}, q, nil)
+	b.PollTimeout = 10 * time.Millisecond
+
+	signals := sse.NewBroker[signalpkg.Signal]()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		b.Run(ctx, signals, nil)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// /mute 的回复（首次发送被 429 限流后重试）
+	st.wait(t, 1)
+	if msgs := st.messages(); !strings.Contains(msgs[0]["text"].(string), "Muted ETHUSDT") {
+		t.Fatalf("first message = %v", msgs[0])
+	}
+
+	for signals.SubscriberCount() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	signals.Publish(signalpkg.Signal{Symbol: "ETHUSDT", Period: "1d", Level: "R4", Direction: "up", Price: 3000})
+	signals.Publish(signalpkg.Signal{Symbol: "BTCUSDT", Period: "1d", Level: "R4", Direction: "up", Price: 111})
+	st.wait(t, 1)
+
+	msgs := st.messages()
+	if len(msgs) != 2 {
+		t.Fatalf("messages = %d, want 2", len(msgs))
+	}
+	text := msgs[1]["text"].(string)
+	if msgs[1]["chat_id"].(float64) != 42 || !strings.Contains(text, "BTCUSDT crossed R4") || !strings.Contains(text, "R4 110") {
+		t.Errorf("push = %v", msgs[1])
+	}
+}
@@ -0,0 +1,131 @@
+// Package telegram pushes signals to a Telegram-compatible Bot API and
+// answers chat commands.
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the public Telegram Bot API.
+const DefaultBaseURL = "https://api.telegram.org"
+
+// Client calls Bot API methods at <BaseURL>/bot<Token>/<method>.
+type Client struct {
+	BaseURL string
+	Token   string
+	HTTP    *http.Client
+}
+
+// NewClient creates a client. An empty baseURL uses DefaultBaseURL.
+func NewClient(baseURL, token string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Token:   token,
+		HTTP:    &http.Client{Timeout: 70 * time.Second},
+	}
+}
+
+// Update is one incoming update from getUpdates.
+type Update struct {
+	UpdateID int64    `json:"update_id"`
+	Message  *Message `json:"message,omitempty"`
+}
+
+// Message is a chat message.
+type Message struct {
+	MessageID int64  `json:"message_id"`
+	Chat      Chat   `json:"chat"`
+	Text      string `json:"text"`
+}
+
+// Chat identifies the conversation a message belongs to.
+type Chat struct {
+	ID int64 `json:"id"`
+}
+
+// APIError is a response with "ok": false.
+type APIError struct {
+	Code        int
+	Description string
+	// RetryAfter is set on 429 responses.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("telegram api error %d: %s", e.Code, e.Description)
+}
+
+type apiResponse struct {
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	ErrorCode   int             `json:"error_code"`
+	Description string          `json:"description"`
+	Parameters  *struct {
+		RetryAfter int `json:"retry_after"`
+	} `json:"parameters"`
+}
+
+func (c *Client) call(ctx context.Context, method string, params, out any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	url := c.BaseURL + "/bot" + c.Token + "/" + method
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		// 错误信息中的 URL 含有 token，不直接返回
+		return fmt.Errorf("telegram %s: request failed", method)
+	}
+	defer resp.Body.Close()
+
+	var ar apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&ar); err != nil {
+		return fmt.Errorf("telegram %s: http %d: %w", method, resp.StatusCode, err)
+	}
+	if !ar.OK {
+		apiErr := &APIError{Code: ar.ErrorCode, Description: ar.Description}
+		if ar.Parameters != nil && ar.Parameters.RetryAfter > 0 {
+			apiErr.RetryAfter = time.Duration(ar.Parameters.RetryAfter) * time.Second
+		}
+		return apiErr
+	}
+	if out != nil {
+		return json.Unmarshal(ar.Result, out)
+	}
+	return nil
+}
+
+// SendMessage sends a plain text message.
+func (c *Client) SendMessage(ctx context.Context, chatID int64, text string) error {
+	return c.call(ctx, "sendMessage", map[string]any{
+		"chat_id":                  chatID,
+		"text":                     text,
+		"disable_web_page_preview": true,
+	}, nil)
+}
+
+// GetUpdates long-polls for updates after offset.
+func (c *Client) GetUpdates(ctx context.Context, offset int64, timeout time.Duration) ([]Update, error) {
+	var updates []Update
+	err := c.call(ctx, "getUpdates", map[string]any{
+		"offset":          offset,
+		"timeout":         int(timeout.Seconds()),
+		"allowed_updates": []string{"message"},
+	}, &updates)
+	return updates, err
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"example.com/binance-pivot-monitor/internal/binance"
+	"example.com/binance-pivot-monitor/internal/sse"
 	"github.com/gorilla/websocket"
 )
 
@@ -20,59 +21,41 @@ type TickerBatch struct {
 // Monitor 监控 ticker 数据并广播
 type Monitor struct {
 	Store         *Store
-	BatchInterval time.Duration // 批量推送间隔，默认 500ms
+	BatchInterval time.Duration        // 批量推送间隔，默认 500ms
+	Endpoints     *binance.WSEndpoints // WS 端点列表，nil 时使用默认端点
 
-	mu        sync.RWMutex
-	listeners []chan TickerBatch
-	pending   map[string]*Ticker // 待推送的变化
+	broker  *sse.Broker[TickerBatch]
+	mu      sync.Mutex
+	pending map[string]*Ticker // 待推送的变化
 }
 
 func NewMonitor(store *Store) *Monitor {
 	return &Monitor{
 		Store:         store,
 		BatchInterval: 500 * time.Millisecond,
+		broker:        sse.NewBroker[TickerBatch](),
 		pending:       make(map[string]*Ticker),
 	}
 }
 
 // Subscribe 订阅批量行情更新
 func (m *Monitor) Subscribe(buffer int) chan TickerBatch {
-	if buffer <= 0 {
-		buffer = 16
-	}
-	ch := make(chan TickerBatch, buffer)
-	m.mu.Lock()
-	m.listeners = append(m.listeners, ch)
-	m.mu.Unlock()
-	return ch
+	return m.broker.Subscribe(buffer)
+}
+
+// SubscribeAs 带标签订阅，标签显示在 Stats 中
+func (m *Monitor) SubscribeAs(label string, buffer int) chan TickerBatch {
+	return m.broker.SubscribeAs(label, buffer)
 }
 
 // Unsubscribe 取消订阅
 func (m *Monitor) Unsubscribe(ch chan TickerBatch) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	for i, c := range m.listeners {
-		if c == ch {
-			m.listeners = append(m.listeners[:i], m.listeners[i+1:]...)
-			close(ch)
-			break
-		}
-	}
+	m.broker.Unsubscribe(ch)
 }
 
-// broadcast 广播批量更新
-func (m *Monitor) broadcast(batch TickerBatch) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	for _, ch := range m.listeners {
-		select {
-		case ch <- batch:
-		default:
-			// 丢弃，避免阻塞
-		}
-	}
+// Broker 返回批量行情的广播器（用于统计和配置丢弃策略）
+func (m *Monitor) Broker() *sse.Broker[TickerBatch] {
+	return m.broker
 }
 
 // Run 启动 ticker 监控
@@ -86,9 +69,9 @@ func (m *Monitor) Run(ctx context.Context) {
 			return
 		}
 
-		conn, _, err := binance.DialTickerArr(ctx)
+		conn, base, _, err := binance.DialTickerArr(ctx, m.Endpoints)
 		if err != nil {
-			log.Printf("ticker ws dial failed: %v", err)
+			log.Printf("ticker ws dial %s failed: %v", base, err)
 			if !sleepContext(ctx, backoff) {
 				return
 			}
@@ -96,7 +79,7 @@ func (m *Monitor) Run(ctx context.Context) {
 			continue
 		}
 
-		log.Printf("ticker ws connected")
+		log.Printf("ticker ws connected: %s", base)
 		backoff = 1 * time.Second
 
 		err = m.readLoop(ctx, conn)
@@ -216,7 +199,7 @@ func (m *Monitor) batchPusher(ctx context.Context) {
 			m.pending = make(map[string]*Ticker)
 			m.mu.Unlock()
 
-			m.broadcast(batch)
+			m.broker.Publish(batch)
 		}
 	}
 }
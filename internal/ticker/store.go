@@ -1,6 +1,7 @@
 package ticker
 
 import (
+	"sort"
 	"sync"
 	"time"
 )
@@ -19,8 +20,15 @@ type Ticker struct {
 type Store struct {
 	mu      sync.RWMutex
 	tickers map[string]*Ticker
+
+	// 成交额排名缓存，最多每 rankTTL 重算一次
+	rankMu sync.Mutex
+	ranks  map[string]int
+	rankAt time.Time
 }
 
+const rankTTL = time.Second
+
 func NewStore() *Store {
 	return &Store{
 		tickers: make(map[string]*Ticker),
@@ -88,3 +96,31 @@ func (s *Store) Count() int {
 	defer s.mu.RUnlock()
 	return len(s.tickers)
 }
+
+// VolumeRank 返回交易对按 24h 成交额的排名（1 为最高）
+func (s *Store) VolumeRank(symbol string) (int, bool) {
+	s.rankMu.Lock()
+	defer s.rankMu.Unlock()
+
+	if s.ranks == nil || time.Since(s.rankAt) > rankTTL {
+		all := s.GetAll()
+		syms := make([]string, 0, len(all))
+		for sym := range all {
+			syms = append(syms, sym)
+		}
+		sort.Slice(syms, func(i, j int) bool {
+			if all[syms[i]].QuoteVolume != all[syms[j]].QuoteVolume {
+				return all[syms[i]].QuoteVolume > all[syms[j]].QuoteVolume
+			}
+			return syms[i] < syms[j]
+		})
+		s.ranks = make(map[string]int, len(syms))
+		for i, sym := range syms {
+			s.ranks[sym] = i + 1
+		}
+		s.rankAt = time.Now()
+	}
+
+	r, ok := s.ranks[symbol]
+	return r, ok
+}